@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+
+	"workloadorchestration/workflow"
+)
+
+// Process exit codes, so CI can tell what kind of failure ended a run.
+const (
+	exitOK          = 0
+	exitFailure     = 1   // Anything not covered below
+	exitUsage       = 2   // Invalid flags or configuration
+	exitAuth        = 3   // No credential, or it could not get a token
+	exitCreate      = 4   // Context, schema, solution template or target creation failed
+	exitDeploy      = 5   // Configuration, review, publish, install, update or health check failed
+	exitTransient   = 6   // Throttling, server errors or timeouts; rerunning may succeed
+	exitInterrupted = 130 // Cancelled by SIGINT/SIGTERM, following the shell convention
+)
+
+// stepExitCodes maps workflow steps onto their failure category.
+var stepExitCodes = map[string]int{
+	"Authenticate":                  exitAuth,
+	"ValidateConfiguration":         exitUsage,
+	"ValidateLocation":              exitUsage,
+	"UpdateContext":                 exitCreate,
+	"VerifyContext":                 exitCreate,
+	"CreateSchema":                  exitCreate,
+	"CreateSchemaVersion":           exitCreate,
+	"CreateSolutionTemplate":        exitCreate,
+	"CreateSolutionTemplateVersion": exitCreate,
+	"CreateTarget":                  exitCreate,
+	"SetConfiguration":              exitDeploy,
+	"VerifyConfiguration":           exitDeploy,
+	"ReviewSolutionVersion":         exitDeploy,
+	"PreviewReview":                 exitDeploy,
+	"PublishSolutionVersion":        exitDeploy,
+	"InstallSolution":               exitDeploy,
+	"UpdateDeployment":              exitDeploy,
+	"HealthCheck":                   exitDeploy,
+}
+
+// exitCodeFor picks the exit code for a finished run. A transient failure takes precedence
+// over its step's category, so CI can retry it instead of reporting a regression. When Run
+// returned no error, the non-fatal step failures recorded in result decide.
+func exitCodeFor(result *workflow.WorkflowResult, err error) int {
+	if err != nil {
+		if workflow.IsTransient(err) {
+			return exitTransient
+		}
+		var workflowErr *workflow.WorkflowError
+		if errors.As(err, &workflowErr) {
+			if code, ok := stepExitCodes[workflowErr.Step]; ok {
+				return code
+			}
+		}
+		return exitFailure
+	}
+	if result == nil || result.Succeeded() {
+		return exitOK
+	}
+
+	code := exitOK
+	for _, stepErr := range result.Errors {
+		if stepErr.Transient {
+			return exitTransient
+		}
+		if code == exitOK {
+			code = exitFailure
+			if stepCode, ok := stepExitCodes[stepErr.Step]; ok {
+				code = stepCode
+			}
+		}
+	}
+	return code
+}
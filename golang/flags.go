@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+
+	"workloadorchestration/workflow"
+)
+
+// cliConfig holds the settings that used to be compile-time constants.
+// Precedence: command-line flag, then environment variable, then the config file, then the
+// Azure CLI context (with --az-cli-defaults), then the built-in default.
+type cliConfig struct {
+	ConfigFile           string
+	File                 *workflow.Config // Settings read from ConfigFile; nil without one
+	SubscriptionID       string
+	AzureCLIDefaults     bool // Fill in the subscription, location and resource group from the Azure CLI
+	Location             string
+	Locations            []string // Set by --locations; one run per region instead of a single run in Location
+	ContinueOnError      bool
+	ResourceGroup        string
+	ContextResourceGroup string
+	ContextName          string
+	NamePrefix           string
+	Capability           string // Existing context capability to use instead of adding a generated one
+	RunID                string
+	Tags                 map[string]string
+	DryRun               bool
+	OutputFormat         string
+	OutputFile           string
+	Auth                 string
+	ClientID             string
+	Proxy                string
+	CAFile               string
+	InsecureSkipVerify   bool
+	Cloud                string
+	ExtendedLocation     string
+	ExtendedLocationType string
+	SolutionScope        workflow.SolutionScope
+	HierarchyLevel       string
+	UpdateType           armworkloadorchestration.UpdateType
+	VersionBump          armworkloadorchestration.UpdateType
+	OperationTimeout     time.Duration
+	Timeout              time.Duration
+	HealthCheckTimeout   time.Duration
+	ResumeFile           string
+	CheckpointFile       string
+	Resume               bool
+	Fresh                bool
+	CapabilitiesOutput   string                            // Where the context's merged capabilities are saved
+	CapabilitiesSeedFile string                            // Capability list reconciled into the context; none when empty
+	ConflictPolicy       workflow.CapabilityConflictPolicy // How a capability already in the context with another description is handled
+	Hierarchies          []workflow.Hierarchy              // Levels added to the context; the config file's or the defaults when nil
+	SchemaRulesPath      string                            // Custom schema rules YAML; the config file's or the built-in rules when empty
+	HelmRegistryAuth     *workflow.HelmRegistryAuth        // Private chart registry credentials; anonymous pulls when nil
+	Update               *workflow.DeploymentUpdate        // Set by --update-target; the day-2 update instead of a full run
+	Teardown             bool
+	CleanupRunID         string
+	AuditLogPath         string
+	Debug                bool
+	TeardownOnInterrupt  bool
+	CancelOnInterrupt    bool
+	RollbackOnFailure    bool
+	NoRetry              bool
+	SkipChartCheck       bool
+	ReviewOnly           bool
+	LogFormat            workflow.LogFormat
+	LogLevel             slog.Level
+
+	// builtIn marks the settings still at their built-in default, which the Azure CLI context replaces
+	builtIn struct{ SubscriptionID, Location, ResourceGroup bool }
+}
+
+// parseFlags registers the configuration flags, seeding each default from its
+// environment variable so that an explicit flag always wins.
+func parseFlags(args []string) (cliConfig, error) {
+	var cfg cliConfig
+	// The config file supplies defaults for the flags below, so it is read before they are parsed
+	var file workflow.Config
+	cfg.ConfigFile = configFlagValue(args)
+	if cfg.ConfigFile != "" {
+		loaded, err := workflow.LoadConfig(cfg.ConfigFile)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.File = loaded
+		file = *loaded
+	}
+
+	operationTimeout := 30 * time.Minute
+	if value := os.Getenv("OPERATION_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid OPERATION_TIMEOUT %q: %v", value, err)
+		}
+		operationTimeout = timeout
+	}
+
+	var runTimeout time.Duration
+	if value := os.Getenv("RUN_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid RUN_TIMEOUT %q: %v", value, err)
+		}
+		runTimeout = timeout
+	}
+
+	var healthCheckTimeout time.Duration
+	if value := os.Getenv("HEALTH_CHECK_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HEALTH_CHECK_TIMEOUT %q: %v", value, err)
+		}
+		healthCheckTimeout = timeout
+	}
+
+	fs := flag.NewFlagSet("workloadorchestration", flag.ContinueOnError)
+	fs.String("config", cfg.ConfigFile, "YAML or JSON file with the run's settings; flags and environment variables override it (env CONFIG_FILE)")
+	fs.StringVar(&cfg.SubscriptionID, "subscription-id", envOrDefault("AZURE_SUBSCRIPTION_ID", valueOr(file.SubscriptionID, workflow.SUBSCRIPTION_ID)), "Azure subscription ID (env AZURE_SUBSCRIPTION_ID)")
+	fs.BoolVar(&cfg.AzureCLIDefaults, "az-cli-defaults", os.Getenv("AZURE_CLI_DEFAULTS") == "true", "Take the subscription, location and resource group not set otherwise from the Azure CLI's az account show and az config defaults (env AZURE_CLI_DEFAULTS=true)")
+	fs.StringVar(&cfg.Location, "location", envOrDefault("AZURE_LOCATION", valueOr(file.Location, workflow.LOCATION)), "Azure region for created resources (env AZURE_LOCATION)")
+	locations := fs.String("locations", os.Getenv("AZURE_LOCATIONS"), "Comma-separated regions to run the workflow in one after another, e.g. eastus2euap,westus; overrides --location (env AZURE_LOCATIONS)")
+	fs.BoolVar(&cfg.ContinueOnError, "continue-on-error", os.Getenv("CONTINUE_ON_ERROR") == "true", "With --locations, run the remaining regions after one fails (env CONTINUE_ON_ERROR=true)")
+	fs.StringVar(&cfg.ResourceGroup, "resource-group", envOrDefault("RESOURCE_GROUP", valueOr(file.ResourceGroup, workflow.RESOURCE_GROUP)), "Resource group for created resources (env RESOURCE_GROUP)")
+	fs.StringVar(&cfg.ContextResourceGroup, "context-resource-group", envOrDefault("CONTEXT_RESOURCE_GROUP", valueOr(file.ContextResourceGroup, workflow.CONTEXT_RESOURCE_GROUP)), "Resource group of the existing context (env CONTEXT_RESOURCE_GROUP)")
+	fs.StringVar(&cfg.ContextName, "context-name", envOrDefault("CONTEXT_NAME", valueOr(file.ContextName, workflow.CONTEXT_NAME)), "Name of the existing context (env CONTEXT_NAME)")
+	fs.StringVar(&cfg.NamePrefix, "name-prefix", envOrDefault("NAME_PREFIX", valueOr(file.NamePrefix, workflow.DefaultNamePrefix)), "Prefix of the names of created resources (env NAME_PREFIX)")
+	fs.StringVar(&cfg.Capability, "capability", os.Getenv("CAPABILITY"), "Capability already in the context for the run to use instead of adding a generated one; the context is left unchanged (env CAPABILITY)")
+	fs.StringVar(&cfg.RunID, "run-id", os.Getenv("RUN_ID"), "Run ID used in created resource names; a random one when unset (env RUN_ID)")
+	tags := fs.String("tags", os.Getenv("RESOURCE_TAGS"), "Tags for every created resource as key=value pairs, e.g. costCenter=1234,owner=ops (env RESOURCE_TAGS)")
+	fs.StringVar(&cfg.Auth, "auth", os.Getenv("AZURE_AUTH"), "Credential source: environment, managed-identity, azure-cli or workload-identity; the default chain when unset (env AZURE_AUTH)")
+	fs.StringVar(&cfg.ClientID, "client-id", os.Getenv("IDENTITY_CLIENT_ID"), "Client ID of a user-assigned managed identity or workload identity (env IDENTITY_CLIENT_ID)")
+	fs.StringVar(&cfg.Proxy, "proxy", os.Getenv("PROXY_URL"), "HTTP(S) proxy URL for all Azure traffic, e.g. http://proxy.corp:3128; overrides HTTPS_PROXY and HTTP_PROXY (env PROXY_URL)")
+	fs.StringVar(&cfg.CAFile, "ca-file", os.Getenv("CA_FILE"), "PEM bundle of extra CA certificates to trust for all Azure traffic, e.g. a TLS-inspecting proxy's root (env CA_FILE)")
+	fs.BoolVar(&cfg.InsecureSkipVerify, "insecure-skip-verify", os.Getenv("INSECURE_SKIP_VERIFY") == "true", "Don't verify server certificates; for local testing only (env INSECURE_SKIP_VERIFY=true)")
+	fs.StringVar(&cfg.Cloud, "cloud", envOrDefault("AZURE_CLOUD", "public"), "Azure cloud: public, usgovernment or china (env AZURE_CLOUD)")
+	fs.StringVar(&cfg.ExtendedLocation, "extended-location", envOrDefault("EXTENDED_LOCATION", workflow.DefaultCustomLocationID), "Custom location resource ID (or edge zone name) the target runs in (env EXTENDED_LOCATION)")
+	fs.StringVar(&cfg.ExtendedLocationType, "extended-location-type", envOrDefault("EXTENDED_LOCATION_TYPE", "CustomLocation"), "Extended location type: CustomLocation or EdgeZone (env EXTENDED_LOCATION_TYPE)")
+	solutionScope := fs.String("solution-scope", envOrDefault("SOLUTION_SCOPE", string(workflow.SolutionScopeNew)), "Scope on the target's cluster that solutions deploy into: new or existing (env SOLUTION_SCOPE)")
+	fs.StringVar(&cfg.HierarchyLevel, "hierarchy-level", envOrDefault("HIERARCHY_LEVEL", workflow.DefaultHierarchyLevel), "Context hierarchy level the target sits at; must be one of the context's levels (env HIERARCHY_LEVEL)")
+	updateType := fs.String("update-type", os.Getenv("UPDATE_TYPE"), "Version part a new solution template version bumps: major, minor or patch; the service default when unset (env UPDATE_TYPE)")
+	versionBump := fs.String("version-bump", os.Getenv("VERSION_BUMP"), "Name new schema and solution template versions by bumping the highest existing one: major, minor or patch; random when unset (env VERSION_BUMP)")
+	fs.DurationVar(&cfg.OperationTimeout, "op-timeout", operationTimeout, "Maximum time to wait for each long-running operation, e.g. 15m; 0 waits indefinitely (env OPERATION_TIMEOUT)")
+	fs.DurationVar(&cfg.Timeout, "timeout", runTimeout, "Maximum time for the whole run, e.g. 1h; 0 leaves it unbounded (env RUN_TIMEOUT)")
+	fs.DurationVar(&cfg.HealthCheckTimeout, "health-timeout", healthCheckTimeout, "After install, wait up to this long for HealthCheckEndpoint to answer 200 OK when HealthCheckEnabled is set; 0 skips the check (env HEALTH_CHECK_TIMEOUT)")
+	fs.StringVar(&cfg.ResumeFile, "resume-file", os.Getenv("RESUME_FILE"), "Save long-running operation resume tokens here and resume any found on startup (env RESUME_FILE)")
+	fs.StringVar(&cfg.CheckpointFile, "checkpoint-file", os.Getenv("CHECKPOINT_FILE"), "Record each completed step here so a rerun with the same --run-id skips it (env CHECKPOINT_FILE)")
+	fs.BoolVar(&cfg.Resume, "resume", os.Getenv("RESUME") == "true", "Continue the run recorded in --checkpoint-file, taking its run ID when --run-id is unset (env RESUME=true)")
+	fs.BoolVar(&cfg.Fresh, "fresh", os.Getenv("FRESH") == "true", "Discard the --checkpoint-file and run every step again (env FRESH=true)")
+	fs.StringVar(&cfg.CapabilitiesOutput, "capabilities-output", envOrDefault("CAPABILITIES_OUTPUT", workflow.DefaultCapabilitiesFile), "Save the context's merged capabilities to this file (env CAPABILITIES_OUTPUT)")
+	fs.StringVar(&cfg.CapabilitiesSeedFile, "capabilities-seed", os.Getenv("CAPABILITIES_SEED_FILE"), "JSON capability list, e.g. a saved --capabilities-output file, to reconcile into the context; replaces the config file's capabilities (env CAPABILITIES_SEED_FILE)")
+	conflictPolicy := fs.String("capability-conflict-policy", os.Getenv("CAPABILITY_CONFLICT_POLICY"), "What to do when a capability is already in the context with another description: reject (default), overwriteDescription or error (env CAPABILITY_CONFLICT_POLICY)")
+	hierarchies := fs.String("context-hierarchies", os.Getenv("CONTEXT_HIERARCHIES"), "Comma-separated context levels to add, outermost first, e.g. country,region,factory,line; replaces the config file's (env CONTEXT_HIERARCHIES)")
+	fs.StringVar(&cfg.SchemaRulesPath, "schema-rules", os.Getenv("SCHEMA_RULES_PATH"), "YAML file of schema rules replacing the config file's or the built-in ones (env SCHEMA_RULES_PATH)")
+	helmUsername := fs.String("helm-registry-username", os.Getenv("HELM_REGISTRY_USERNAME"), "Username for pulling the Helm chart from a private registry (env HELM_REGISTRY_USERNAME)")
+	helmPassword := fs.String("helm-registry-password", os.Getenv("HELM_REGISTRY_PASSWORD"), "Password for the Helm chart registry; prefer the environment variable, as command lines are visible to other processes (env HELM_REGISTRY_PASSWORD)")
+	helmToken := fs.String("helm-registry-token", os.Getenv("HELM_REGISTRY_TOKEN"), "Token for the Helm chart registry instead of a password; prefer the environment variable, as command lines are visible to other processes (env HELM_REGISTRY_TOKEN)")
+	updateTarget := fs.String("update-target", os.Getenv("UPDATE_TARGET_NAME"), "Existing target to roll a new solution template version onto instead of running the whole workflow (env UPDATE_TARGET_NAME)")
+	updateTemplate := fs.String("update-solution-template", os.Getenv("UPDATE_SOLUTION_TEMPLATE_NAME"), "With --update-target, the solution template to deploy (env UPDATE_SOLUTION_TEMPLATE_NAME)")
+	updateVersion := fs.String("update-version", os.Getenv("UPDATE_SOLUTION_TEMPLATE_VERSION"), "With --update-target, the solution template version to deploy (env UPDATE_SOLUTION_TEMPLATE_VERSION)")
+	fs.BoolVar(&cfg.Teardown, "teardown", os.Getenv("TEARDOWN") == "true", "Delete everything the run created once it finishes (env TEARDOWN=true)")
+	fs.StringVar(&cfg.CleanupRunID, "cleanup-run-id", os.Getenv("CLEANUP_RUN_ID"), "Delete the resources tagged with this run ID instead of running the workflow (env CLEANUP_RUN_ID)")
+	fs.StringVar(&cfg.AuditLogPath, "audit-log", os.Getenv("AUDIT_LOG_PATH"), "Append a JSON audit record per operation to this file; records are discarded when unset (env AUDIT_LOG_PATH)")
+	fs.BoolVar(&cfg.DryRun, "dry-run", os.Getenv("DRY_RUN") == "true", "Print the operations the run would submit without changing anything (env DRY_RUN=true)")
+	fs.BoolVar(&cfg.TeardownOnInterrupt, "teardown-on-interrupt", os.Getenv("TEARDOWN_ON_INTERRUPT") == "true", "Delete the resources a run created when it is interrupted with Ctrl-C or SIGTERM (env TEARDOWN_ON_INTERRUPT=true)")
+	fs.BoolVar(&cfg.CancelOnInterrupt, "cancel-on-interrupt", os.Getenv("CANCEL_ON_INTERRUPT") == "true", "Check the target operation in flight when the run is interrupted and report whether it keeps running; the service can't cancel it (env CANCEL_ON_INTERRUPT=true)")
+	fs.BoolVar(&cfg.RollbackOnFailure, "rollback-on-failure", os.Getenv("ROLLBACK_ON_FAILURE") == "true", "Reinstall the previously deployed solution version when the install fails (env ROLLBACK_ON_FAILURE=true)")
+	fs.BoolVar(&cfg.SkipChartCheck, "skip-chart-check", os.Getenv("SKIP_CHART_CHECK") == "true", "Don't check that the Helm chart version can be pulled before creating anything, e.g. when only the target can reach the registry (env SKIP_CHART_CHECK=true)")
+	fs.BoolVar(&cfg.ReviewOnly, "review-only", os.Getenv("REVIEW_ONLY") == "true", "Validate the solution against the target and stop, without creating, publishing or installing a solution version (env REVIEW_ONLY=true)")
+	fs.BoolVar(&cfg.NoRetry, "no-retry", os.Getenv("NO_RETRY") == "true", "Run every operation once, without retries, and fail on the first error (env NO_RETRY=true)")
+	fs.BoolVar(&cfg.Debug, "debug", strings.EqualFold(os.Getenv("AZURE_SDK_GO_LOGGING"), "all"), "Log every HTTP request and response to stderr, with credentials redacted (env AZURE_SDK_GO_LOGGING=all)")
+	logFormat := fs.String("log-format", envOrDefault("LOG_FORMAT", "text"), "Progress log format on stderr: text or json (env LOG_FORMAT)")
+	logLevel := fs.String("log-level", envOrDefault("LOG_LEVEL", "info"), "Minimum progress log level: debug, info, warn or error (env LOG_LEVEL)")
+	fs.StringVar(&cfg.OutputFormat, "output", envOrDefault("OUTPUT_FORMAT", "text"), "Run summary format: text or json (env OUTPUT_FORMAT)")
+	fs.StringVar(&cfg.OutputFile, "output-file", os.Getenv("OUTPUT_FILE"), "Write the run summary to this file instead of stdout (env OUTPUT_FILE)")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	cfg.builtIn.SubscriptionID = !set["subscription-id"] && os.Getenv("AZURE_SUBSCRIPTION_ID") == "" && file.SubscriptionID == ""
+	cfg.builtIn.Location = !set["location"] && os.Getenv("AZURE_LOCATION") == "" && file.Location == ""
+	cfg.builtIn.ResourceGroup = !set["resource-group"] && os.Getenv("RESOURCE_GROUP") == "" && file.ResourceGroup == ""
+	parsedTags, err := workflow.ParseTags(*tags)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid --tags: %v", err)
+	}
+	cfg.Tags = parsedTags
+	if *tags == "" {
+		cfg.Tags = file.Tags
+	}
+	if cfg.Locations, err = workflow.ParseLocations(*locations); err != nil {
+		return cfg, fmt.Errorf("invalid --locations: %v", err)
+	}
+	if cfg.SolutionScope, err = workflow.ParseSolutionScope(*solutionScope); err != nil {
+		return cfg, fmt.Errorf("invalid --solution-scope: %v", err)
+	}
+	if cfg.UpdateType, err = workflow.ParseUpdateType(*updateType); err != nil {
+		return cfg, fmt.Errorf("invalid --update-type: %v", err)
+	}
+	if cfg.VersionBump, err = workflow.ParseUpdateType(*versionBump); err != nil {
+		return cfg, fmt.Errorf("invalid --version-bump: %v", err)
+	}
+	if cfg.LogFormat, err = workflow.ParseLogFormat(*logFormat); err != nil {
+		return cfg, fmt.Errorf("invalid --log-format: %v", err)
+	}
+	if cfg.LogLevel, err = workflow.ParseLogLevel(*logLevel); err != nil {
+		return cfg, fmt.Errorf("invalid --log-level: %v", err)
+	}
+	// CAPABILITIES_FILE was renamed so it isn't mistaken for --capabilities-output; fail rather
+	// than silently skip the seeding it used to ask for
+	if os.Getenv("CAPABILITIES_FILE") != "" {
+		return cfg, fmt.Errorf("CAPABILITIES_FILE has been renamed to CAPABILITIES_SEED_FILE (flag --capabilities-seed)")
+	}
+	if cfg.ConflictPolicy, err = workflow.ParseCapabilityConflictPolicy(*conflictPolicy); err != nil {
+		return cfg, fmt.Errorf("invalid --capability-conflict-policy: %v", err)
+	}
+	if cfg.Hierarchies, err = workflow.ParseHierarchies(*hierarchies); err != nil {
+		return cfg, fmt.Errorf("invalid --context-hierarchies: %v", err)
+	}
+	if *helmPassword != "" || *helmToken != "" {
+		cfg.HelmRegistryAuth = &workflow.HelmRegistryAuth{Username: *helmUsername, Password: *helmPassword, Token: *helmToken}
+	}
+	if *updateTarget != "" {
+		cfg.Update = &workflow.DeploymentUpdate{TargetName: *updateTarget, SolutionTemplateName: *updateTemplate, Version: *updateVersion}
+	}
+	if cfg.Capability != "" {
+		if err := workflow.ValidateCapabilityName(cfg.Capability); err != nil {
+			return cfg, fmt.Errorf("invalid --capability: %v", err)
+		}
+	}
+	if cfg.Resume && cfg.Fresh {
+		return cfg, fmt.Errorf("--resume and --fresh are mutually exclusive")
+	}
+	if (cfg.Resume || cfg.Fresh) && cfg.CheckpointFile == "" {
+		return cfg, fmt.Errorf("--resume and --fresh need a --checkpoint-file")
+	}
+	if cfg.OutputFormat != "text" && cfg.OutputFormat != "json" {
+		return cfg, fmt.Errorf("invalid --output %q (valid: text, json)", cfg.OutputFormat)
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	return cfg, nil
+}
+
+// applyAzureCLIDefaults replaces the subscription, location and resource group still at their
+// built-in defaults with the Azure CLI's. Location and resource group keep the built-in
+// default when the CLI has none configured.
+func applyAzureCLIDefaults(ctx context.Context, cfg *cliConfig) error {
+	if !cfg.builtIn.SubscriptionID && !cfg.builtIn.Location && !cfg.builtIn.ResourceGroup {
+		return nil // Everything was set explicitly; don't run az for nothing
+	}
+	defaults, err := workflow.ReadAzureCLIDefaults(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.builtIn.SubscriptionID {
+		cfg.SubscriptionID = defaults.SubscriptionID
+	}
+	if cfg.builtIn.Location {
+		cfg.Location = valueOr(defaults.Location, cfg.Location)
+	}
+	if cfg.builtIn.ResourceGroup {
+		cfg.ResourceGroup = valueOr(defaults.ResourceGroup, cfg.ResourceGroup)
+	}
+	return nil
+}
+
+// configFlagValue finds the config file named by --config in args, or by CONFIG_FILE when the
+// flag isn't given. It runs ahead of flag parsing because the file supplies the flags' defaults.
+func configFlagValue(args []string) string {
+	path := os.Getenv("CONFIG_FILE")
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break // Flag parsing stops here too
+		}
+		// Other flags' values are skipped over too, since none of them starts with -config
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			path = value
+		} else if i+1 < len(args) {
+			path = args[i+1]
+			i++
+		}
+	}
+	return path
+}
+
+// envOrDefault returns the environment variable's value, or def when it is unset or empty.
+func envOrDefault(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// printEffectiveConfig shows the resolved settings so a run can be traced back to its inputs.
+// It writes to w rather than stdout, which carries only the run summary.
+func printEffectiveConfig(w io.Writer, cfg cliConfig) {
+	fmt.Fprintln(w, "Effective configuration:")
+	fmt.Fprintf(w, "  Config File:            %s\n", valueOr(cfg.ConfigFile, "none"))
+	fmt.Fprintf(w, "  Subscription ID:        %s\n", cfg.SubscriptionID)
+	fmt.Fprintf(w, "  Azure CLI Defaults:     %t\n", cfg.AzureCLIDefaults)
+	fmt.Fprintf(w, "  Cloud:                  %s\n", cfg.Cloud)
+	if cfg.Locations != nil {
+		fmt.Fprintf(w, "  Locations:              %s (continue on error: %t)\n", strings.Join(cfg.Locations, ","), cfg.ContinueOnError)
+	} else {
+		fmt.Fprintf(w, "  Location:               %s\n", cfg.Location)
+	}
+	fmt.Fprintf(w, "  Extended Location:      %s (%s)\n", cfg.ExtendedLocation, cfg.ExtendedLocationType)
+	fmt.Fprintf(w, "  Solution Scope:         %s\n", cfg.SolutionScope)
+	fmt.Fprintf(w, "  Hierarchy Level:        %s\n", cfg.HierarchyLevel)
+	fmt.Fprintf(w, "  Update Type:            %s\n", valueOr(string(cfg.UpdateType), "service default"))
+	fmt.Fprintf(w, "  Version Bump:           %s\n", valueOr(string(cfg.VersionBump), "random"))
+	fmt.Fprintf(w, "  Resource Group:         %s\n", cfg.ResourceGroup)
+	fmt.Fprintf(w, "  Context Resource Group: %s\n", cfg.ContextResourceGroup)
+	fmt.Fprintf(w, "  Context Name:           %s\n", cfg.ContextName)
+	fmt.Fprintf(w, "  Name Prefix:            %s\n", cfg.NamePrefix)
+	fmt.Fprintf(w, "  Capability:             %s\n", valueOr(cfg.Capability, "generated"))
+	fmt.Fprintf(w, "  Run ID:                 %s\n", valueOr(cfg.RunID, "generated"))
+	fmt.Fprintf(w, "  Tags:                   %s\n", valueOr(formatTags(cfg.Tags), "none"))
+	fmt.Fprintf(w, "  Auth:                   %s\n", valueOr(cfg.Auth, "default credential chain"))
+	fmt.Fprintf(w, "  Client ID:              %s\n", valueOr(cfg.ClientID, "none"))
+	fmt.Fprintf(w, "  Proxy:                  %s\n", valueOr(cfg.Proxy, "from environment"))
+	fmt.Fprintf(w, "  CA File:                %s\n", valueOr(cfg.CAFile, "system roots only"))
+	fmt.Fprintf(w, "  Skip TLS Verification:  %t\n", cfg.InsecureSkipVerify)
+	fmt.Fprintf(w, "  Operation Timeout:      %s\n", cfg.OperationTimeout)
+	fmt.Fprintf(w, "  Run Timeout:            %s\n", cfg.Timeout)
+	fmt.Fprintf(w, "  Health Check Timeout:   %s\n", cfg.HealthCheckTimeout)
+	fmt.Fprintf(w, "  Resume File:            %s\n", valueOr(cfg.ResumeFile, "disabled"))
+	fmt.Fprintf(w, "  Checkpoint File:        %s\n", valueOr(cfg.CheckpointFile, "disabled"))
+	fmt.Fprintf(w, "  Capabilities Output:    %s\n", cfg.CapabilitiesOutput)
+	fmt.Fprintf(w, "  Capabilities Seed:      %s\n", valueOr(cfg.CapabilitiesSeedFile, "none"))
+	fmt.Fprintf(w, "  Conflict Policy:        %s\n", cfg.ConflictPolicy)
+	fmt.Fprintf(w, "  Context Hierarchies:    %s\n", valueOr(formatHierarchies(cfg.Hierarchies), "from config file or defaults"))
+	fmt.Fprintf(w, "  Schema Rules:           %s\n", valueOr(cfg.SchemaRulesPath, "from config file or built-in"))
+	if cfg.HelmRegistryAuth != nil {
+		fmt.Fprintf(w, "  Helm Registry Auth:     %s\n", cfg.HelmRegistryAuth)
+	} else {
+		fmt.Fprintf(w, "  Helm Registry Auth:     anonymous\n")
+	}
+	if cfg.Update != nil {
+		fmt.Fprintf(w, "  Update:                 %s to %s/%s\n", cfg.Update.TargetName, cfg.Update.SolutionTemplateName, cfg.Update.Version)
+	}
+	fmt.Fprintf(w, "  Teardown:               %t\n", cfg.Teardown)
+	fmt.Fprintf(w, "  Cleanup Run ID:         %s\n", valueOr(cfg.CleanupRunID, "none"))
+	fmt.Fprintf(w, "  Audit Log:              %s\n", valueOr(cfg.AuditLogPath, "disabled"))
+	fmt.Fprintf(w, "  Dry Run:                %t\n", cfg.DryRun)
+	fmt.Fprintf(w, "  Teardown on Interrupt:  %t\n", cfg.TeardownOnInterrupt)
+	fmt.Fprintf(w, "  Cancel on Interrupt:    %t\n", cfg.CancelOnInterrupt)
+	fmt.Fprintf(w, "  Rollback on Failure:    %t\n", cfg.RollbackOnFailure)
+	fmt.Fprintf(w, "  Retries:                %t\n", !cfg.NoRetry)
+	fmt.Fprintf(w, "  Helm Chart Check:       %t\n", !cfg.SkipChartCheck)
+	fmt.Fprintf(w, "  Review Only:            %t\n", cfg.ReviewOnly)
+	fmt.Fprintf(w, "  HTTP Logging:           %t\n", cfg.Debug)
+	fmt.Fprintf(w, "  Log:                    %s, level %s\n", cfg.LogFormat, cfg.LogLevel)
+	fmt.Fprintf(w, "  Output:                 %s\n", cfg.OutputFormat)
+}
+
+// formatTags renders tags as sorted key=value pairs.
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// formatHierarchies renders hierarchies as their comma-separated names, outermost first.
+func formatHierarchies(hierarchies []workflow.Hierarchy) string {
+	names := make([]string, len(hierarchies))
+	for i, hierarchy := range hierarchies {
+		names[i] = hierarchy.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// valueOr returns value, or fallback when value is empty.
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"workloadorchestration/workflow"
+)
+
+// writeResult writes the run summary in the requested format to path, or to stdout when path is empty.
+func writeResult(result *workflow.WorkflowResult, format, path string) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %v", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	printResult(w, result)
+	return nil
+}
+
+// writeRegionResults writes the summary of a multi-region run, one section (or JSON array
+// entry) per region, to path, or to stdout when path is empty.
+func writeRegionResults(results []workflow.RegionResult, format, path string) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %v", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+	for _, region := range results {
+		fmt.Fprintf(w, "\nREGION %s\n", region.Location)
+		if region.Result != nil {
+			printResult(w, region.Result)
+		}
+		if region.Error != "" {
+			fmt.Fprintf(w, "  %-20s %s\n", "Failed:", region.Error)
+		}
+	}
+	return nil
+}
+
+// printResult pretty-prints the outcome of a workflow run.
+func printResult(w io.Writer, result *workflow.WorkflowResult) {
+	fmt.Fprintln(w, "\n"+strings.Repeat("=", 50))
+	fmt.Fprintln(w, "RUN SUMMARY")
+	fmt.Fprintln(w, strings.Repeat("=", 50))
+	printField(w, "Run ID", result.RunID)
+	printField(w, "Capability", result.Capability)
+	printField(w, "Schema", result.SchemaName)
+	printField(w, "Schema Version", result.SchemaVersion)
+	printField(w, "Solution Template", result.SolutionTemplateName)
+	printField(w, "Template Version ID", result.SolutionTemplateVersionID)
+	printField(w, "Target", result.TargetName)
+	printField(w, "Solution Version ID", result.SolutionVersionID)
+	fmt.Fprintf(w, "  %-20s %s\n", "Configuration:", result.ConfigurationStatus)
+	fmt.Fprintf(w, "  %-20s %s\n", "Review:", result.ReviewStatus)
+	fmt.Fprintf(w, "  %-20s %s\n", "Publish:", result.PublishStatus)
+	fmt.Fprintf(w, "  %-20s %s\n", "Install:", result.InstallStatus)
+	fmt.Fprintf(w, "  %-20s %s\n", "Duration:", result.FinishedAt.Sub(result.StartedAt).Round(time.Second))
+	if result.InterruptedStep != "" {
+		printField(w, "Interrupted Step", result.InterruptedStep)
+	}
+
+	if len(result.Errors) > 0 {
+		fmt.Fprintln(w, "  Errors:")
+		for _, stepErr := range result.Errors {
+			fmt.Fprintf(w, "    - %s: %s\n", stepErr.Step, stepErr.Message)
+		}
+	}
+
+	if len(result.ReviewDiagnostics) > 0 {
+		fmt.Fprintln(w, "  Review Diagnostics:")
+		for _, diagnostic := range result.ReviewDiagnostics {
+			fmt.Fprintf(w, "    - %s\n", diagnostic)
+		}
+	}
+	if result.ReviewPreview != nil {
+		if len(result.ReviewPreview.Diagnostics) == 0 {
+			fmt.Fprintf(w, "  %-20s %s\n", "Review Preview:", "clean")
+		} else {
+			fmt.Fprintln(w, "  Review Preview:")
+			for _, diagnostic := range result.ReviewPreview.Diagnostics {
+				fmt.Fprintf(w, "    - %s\n", diagnostic)
+			}
+		}
+	}
+
+	// Only operations that needed more than one attempt are worth a line
+	var retried []workflow.RetryResult
+	for _, retry := range result.Retries {
+		if retry.Attempts > 1 {
+			retried = append(retried, retry)
+		}
+	}
+	if len(retried) > 0 {
+		fmt.Fprintln(w, "  Retries:")
+		for _, retry := range retried {
+			fmt.Fprintf(w, "    - %s took %d attempts over %s\n", retry.Operation, retry.Attempts, retry.Elapsed.Round(time.Second))
+		}
+	}
+}
+
+// printField prints a labelled value, or "-" when the step never produced it.
+func printField(w io.Writer, label, value string) {
+	if value == "" {
+		value = "-"
+	}
+	fmt.Fprintf(w, "  %-20s %s\n", label+":", value)
+}
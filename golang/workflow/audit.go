@@ -0,0 +1,144 @@
+package workflow
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditRecord captures who did what, to which resource, when, and with what outcome.
+// It never contains the access token itself, only the principal extracted from it.
+type AuditRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Principal string    `json:"principal"`
+	Operation string    `json:"operation"`
+	Resource  string    `json:"resource"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuditSink receives audit records. Implementations must be safe for concurrent use.
+type AuditSink interface {
+	Write(record AuditRecord) error
+}
+
+// jsonAuditSink writes each audit record as a single JSON line.
+type jsonAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONAuditSink returns a sink writing each record to w as one line of JSON. Writes are
+// serialized, so steps running concurrently can share it.
+func NewJSONAuditSink(w io.Writer) AuditSink {
+	return &jsonAuditSink{w: w}
+}
+
+func (s *jsonAuditSink) Write(record AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error marshaling audit record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// OpenAuditSink returns a sink appending to the file at path, or a sink that
+// discards everything when path is empty.
+func OpenAuditSink(path string) (AuditSink, func() error, error) {
+	if path == "" {
+		return NewJSONAuditSink(io.Discard), func() error { return nil }, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening audit log: %w", err)
+	}
+	return NewJSONAuditSink(f), f.Close, nil
+}
+
+// Auditor stamps every record with the authenticated principal before handing it to the sink.
+type Auditor struct {
+	Principal string
+	Sink      AuditSink
+	Logger    *slog.Logger // Reports failed writes; the workflow's default logger when nil
+	now       func() time.Time
+}
+
+// NewAuditor returns an Auditor writing to sink on behalf of principal, typically the identity
+// PrincipalFromToken reads from the run's access token.
+func NewAuditor(principal string, sink AuditSink) *Auditor {
+	return &Auditor{Principal: principal, Sink: sink, now: time.Now}
+}
+
+// Record writes one audit record for an operation on a resource. A nil err is a success.
+// Failures to write the record are reported but never interrupt the workflow.
+func (a *Auditor) Record(operation, resource string, err error) {
+	record := AuditRecord{
+		Timestamp: a.now().UTC(),
+		Principal: a.Principal,
+		Operation: operation,
+		Resource:  resource,
+		Outcome:   "Succeeded",
+	}
+	if err != nil {
+		record.Outcome = "Failed"
+		record.Error = err.Error()
+	}
+
+	if writeErr := a.Sink.Write(record); writeErr != nil {
+		logger := a.Logger
+		if logger == nil {
+			logger = defaultLogger
+		}
+		logger.Warn("Failed to write audit record", "operation", operation, logKeyError, writeErr)
+	}
+}
+
+// PrincipalFromToken extracts a human-meaningful identity from a JWT access token's claims.
+// Prefers user principal names, then application IDs, then the object ID.
+// The signature is not verified; the claims are only used for attribution.
+func PrincipalFromToken(token string) string {
+	claims := tokenClaims(token)
+	for _, key := range []string{"upn", "unique_name", "preferred_username", "appid", "azp", "oid", "sub"} {
+		if value, ok := claims[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return "unknown"
+}
+
+// tokenClaim returns one string claim of a JWT access token, e.g. "tid", or "" when it is
+// missing. Like PrincipalFromToken, it doesn't verify the signature.
+func tokenClaim(token, key string) string {
+	value, _ := tokenClaims(token)[key].(string)
+	return value
+}
+
+// tokenClaims decodes the payload of a JWT, returning nil when token isn't one.
+func tokenClaims(token string) map[string]interface{} {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil
+	}
+	return claims
+}
@@ -0,0 +1,76 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testToken is an unsigned JWT carrying claims.
+func testToken(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"none"}`)) + "." + encode(payload) + "." + encode([]byte("signature"))
+}
+
+func TestAuditRecordCarriesPrincipalFromToken(t *testing.T) {
+	token := testToken(t, map[string]any{"upn": "alex@contoso.com", "oid": "00000000-0000-0000-0000-000000000001"})
+	var buf bytes.Buffer
+	auditor := NewAuditor(PrincipalFromToken(token), NewJSONAuditSink(&buf))
+	auditor.now = func() time.Time { return time.Date(2025, 9, 26, 4, 4, 55, 0, time.UTC) }
+
+	auditor.Record("CreateTarget", "target", nil)
+	auditor.Record("ValidateLocation", "westus9", errors.New("unsupported location"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d audit lines, want 2: %q", len(lines), buf.String())
+	}
+	want := []AuditRecord{
+		{Timestamp: time.Date(2025, 9, 26, 4, 4, 55, 0, time.UTC), Principal: "alex@contoso.com", Operation: "CreateTarget", Resource: "target", Outcome: "Succeeded"},
+		{Timestamp: time.Date(2025, 9, 26, 4, 4, 55, 0, time.UTC), Principal: "alex@contoso.com", Operation: "ValidateLocation", Resource: "westus9", Outcome: "Failed", Error: "unsupported location"},
+	}
+	for i, line := range lines {
+		var got AuditRecord
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("audit line %q is not JSON: %v", line, err)
+		}
+		if got != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, got, want[i])
+		}
+		if strings.Contains(line, token) {
+			t.Errorf("record %d contains the access token", i)
+		}
+	}
+}
+
+func TestPrincipalFromToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]any
+		want   string
+	}{
+		{name: "user", claims: map[string]any{"upn": "alex@contoso.com", "appid": "app", "oid": "oid"}, want: "alex@contoso.com"},
+		{name: "application", claims: map[string]any{"appid": "app", "oid": "oid"}, want: "app"},
+		{name: "object ID only", claims: map[string]any{"oid": "oid"}, want: "oid"},
+		{name: "no identity", claims: map[string]any{"tid": "tenant"}, want: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrincipalFromToken(testToken(t, tt.claims)); got != tt.want {
+				t.Errorf("PrincipalFromToken = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := PrincipalFromToken("not-a-jwt"); got != "unknown" {
+		t.Errorf("PrincipalFromToken(not-a-jwt) = %q, want unknown", got)
+	}
+}
@@ -0,0 +1,88 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// AzureCLIDefaults holds the subscription of the active Azure CLI login (`az account show`)
+// and the defaults configured with `az config set defaults.location=... defaults.group=...`.
+// Location and ResourceGroup are empty when the CLI has no default for them.
+type AzureCLIDefaults struct {
+	SubscriptionID string
+	Location       string
+	ResourceGroup  string
+}
+
+// ErrAzureCLINotFound is returned by ReadAzureCLIDefaults when the az command isn't installed.
+var ErrAzureCLINotFound = errors.New("the Azure CLI (az) is not installed or not on PATH")
+
+// azureCLITimeout bounds each az invocation; the CLI can take several seconds to start.
+const azureCLITimeout = 30 * time.Second
+
+// ReadAzureCLIDefaults asks the Azure CLI for its active subscription and configured defaults.
+// It fails when az is missing (ErrAzureCLINotFound) or nobody is logged in; missing defaults
+// are not an error.
+func ReadAzureCLIDefaults(ctx context.Context) (AzureCLIDefaults, error) {
+	if _, err := exec.LookPath("az"); err != nil {
+		return AzureCLIDefaults{}, ErrAzureCLINotFound
+	}
+
+	out, err := runAzureCLI(ctx, "account", "show")
+	if err != nil {
+		return AzureCLIDefaults{}, fmt.Errorf("error reading the Azure CLI subscription (run az login): %w", err)
+	}
+	var account struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(out, &account); err != nil {
+		return AzureCLIDefaults{}, fmt.Errorf("error parsing az account show output: %w", err)
+	}
+	if account.ID == "" {
+		return AzureCLIDefaults{}, fmt.Errorf("the Azure CLI has no active subscription (run az login)")
+	}
+	defaults := AzureCLIDefaults{SubscriptionID: account.ID}
+
+	// Without any defaults configured az fails or prints an empty list; either way there is none
+	if out, err := runAzureCLI(ctx, "config", "get", "defaults"); err == nil {
+		var entries []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		}
+		if json.Unmarshal(out, &entries) == nil {
+			for _, entry := range entries {
+				switch entry.Name {
+				case "location":
+					defaults.Location = entry.Value
+				case "group":
+					defaults.ResourceGroup = entry.Value
+				}
+			}
+		}
+	}
+	return defaults, nil
+}
+
+// runAzureCLI runs az with args and returns its JSON output. A failure carries az's own
+// message, e.g. "Please run 'az login' to setup account."
+func runAzureCLI(ctx context.Context, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, azureCLITimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "az", append(args, "--output", "json")...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("az %s: %s", strings.Join(args, " "), message)
+		}
+		return nil, fmt.Errorf("az %s: %w", strings.Join(args, " "), err)
+	}
+	return out, nil
+}
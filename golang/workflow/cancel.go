@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// ErrCancelNotSupported is returned by CheckOperationCancellable when an operation is still
+// running and the service offers no way to stop it. Abandoning the poller only stops waiting;
+// the operation carries on server-side.
+var ErrCancelNotSupported = errors.New("the service does not support cancelling this operation; it continues server-side")
+
+// cancelTimeout bounds the calls checkInterruptedOperation makes after the run's own context is gone.
+const cancelTimeout = time.Minute
+
+// targetOperationSteps are the steps whose long-running operation acts on a target: creating
+// it, and reviewing, publishing and installing on it.
+var targetOperationSteps = map[string]bool{
+	"CreateTarget":           true,
+	"ReviewSolutionVersion":  true,
+	"PreviewReview":          true,
+	"PublishSolutionVersion": true,
+	"InstallSolution":        true,
+	"UpdateDeployment":       true,
+}
+
+// CheckOperationCancellable reports whether a long-running operation is still in progress on a
+// target, and returns the target's provisioning state. It cancels nothing: workload
+// orchestration has no cancel action for target creation, review, publish or install, so an
+// operation that is still running returns its state together with ErrCancelNotSupported and
+// runs to completion server-side. An operation that has already finished, or a target that
+// doesn't exist (yet), returns no error.
+func CheckOperationCancellable(ctx context.Context, client TargetsAPI, resourceGroupName, targetName string) (armworkloadorchestration.ProvisioningState, error) {
+	res, err := client.Get(ctx, resourceGroupName, targetName, nil)
+	if isNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error getting target %s: %w", targetName, err)
+	}
+	state := armworkloadorchestration.ProvisioningState(provisioningState(&res.Target))
+	switch state {
+	case armworkloadorchestration.ProvisioningStateSucceeded, armworkloadorchestration.ProvisioningStateFailed, armworkloadorchestration.ProvisioningStateCanceled:
+		return state, nil
+	default:
+		return state, ErrCancelNotSupported
+	}
+}
+
+// checkInterruptedOperation runs CheckOperationCancellable for an interrupted target step and
+// logs the outcome, so the user knows whether anything is still running on the service.
+func checkInterruptedOperation(ctx context.Context, client TargetsAPI, resourceGroupName, targetName string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelTimeout)
+	defer cancel()
+	logger := loggerFrom(ctx)
+	state, err := CheckOperationCancellable(ctx, client, resourceGroupName, targetName)
+	switch {
+	case errors.Is(err, ErrCancelNotSupported):
+		logger.Warn("Interrupted operation can't be cancelled and continues server-side", logKeyResource, targetName, logKeyState, state)
+	case err != nil:
+		logger.Warn("Could not check the interrupted operation", logKeyResource, targetName, logKeyError, err)
+	default:
+		logger.Info("Interrupted operation is no longer running", logKeyResource, targetName, logKeyState, valueOrDefault(string(state), "not created"))
+	}
+}
@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+func TestCheckOperationCancellable(t *testing.T) {
+	tests := []struct {
+		name    string
+		get     func(string) (armworkloadorchestration.TargetsClientGetResponse, error)
+		want    armworkloadorchestration.ProvisioningState
+		wantErr error
+	}{
+		{
+			name: "still running",
+			get: func(name string) (armworkloadorchestration.TargetsClientGetResponse, error) {
+				return armworkloadorchestration.TargetsClientGetResponse{Target: targetInState(name, armworkloadorchestration.ProvisioningStateInprogress)}, nil
+			},
+			want:    armworkloadorchestration.ProvisioningStateInprogress,
+			wantErr: ErrCancelNotSupported,
+		},
+		{
+			name: "finished",
+			get: func(name string) (armworkloadorchestration.TargetsClientGetResponse, error) {
+				return armworkloadorchestration.TargetsClientGetResponse{Target: targetInState(name, armworkloadorchestration.ProvisioningStateSucceeded)}, nil
+			},
+			want: armworkloadorchestration.ProvisioningStateSucceeded,
+		},
+		{
+			name: "not created",
+			get: func(string) (armworkloadorchestration.TargetsClientGetResponse, error) {
+				return armworkloadorchestration.TargetsClientGetResponse{}, responseError(http.StatusNotFound, "ResourceNotFound", "target not found")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := CheckOperationCancellable(testContext(), &fakeTargets{get: tt.get}, "rg", "target")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if state != tt.want {
+				t.Errorf("state = %q, want %q", state, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Checkpoint records how far a run got: the steps it completed, in order, and the resources
+// they produced. Run saves one after every completed step when Options.CheckpointPath is set,
+// and a later run with the same run ID skips the steps it lists.
+type Checkpoint struct {
+	RunID                     string    `json:"runId"`
+	CompletedSteps            []string  `json:"completedSteps"`
+	Capability                string    `json:"capability,omitempty"`
+	SchemaID                  string    `json:"schemaId,omitempty"`
+	SchemaVersionID           string    `json:"schemaVersionId,omitempty"`
+	SolutionTemplateID        string    `json:"solutionTemplateId,omitempty"`
+	SolutionTemplateVersionID string    `json:"solutionTemplateVersionId,omitempty"`
+	TargetID                  string    `json:"targetId,omitempty"`
+	SolutionVersionID         string    `json:"solutionVersionId,omitempty"`
+	UpdatedAt                 time.Time `json:"updatedAt"`
+}
+
+// LoadCheckpoint reads the checkpoint saved at path. A missing file returns nil and no error.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint file %s: %w", path, err)
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("error parsing checkpoint file %s: %w", path, err)
+	}
+	return &checkpoint, nil
+}
+
+// checkpointStore keeps a run's checkpoint and rewrites its file as steps complete. A nil
+// store is valid and records nothing, so Run can use one unconditionally.
+type checkpointStore struct {
+	path       string
+	mu         sync.Mutex
+	checkpoint Checkpoint
+	halted     bool
+}
+
+// openCheckpoint picks the checkpoint a run continues from. With fresh set, any saved
+// checkpoint is discarded. Otherwise the saved one is used when it belongs to runID, or, with
+// resume set and runID empty, whatever run it belongs to. A checkpoint for another run is
+// replaced, unless resume was asked for, which is then an error. The returned store's RunID is
+// empty when the run starts from scratch without a run ID.
+func openCheckpoint(ctx context.Context, path, runID string, resume, fresh bool) (*checkpointStore, error) {
+	store := &checkpointStore{path: path, checkpoint: Checkpoint{RunID: runID}}
+	if fresh {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error removing checkpoint file: %w", err)
+		}
+		return store, nil
+	}
+
+	saved, err := LoadCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case saved == nil && resume:
+		return nil, fmt.Errorf("no checkpoint to resume from in %s", path)
+	case saved == nil:
+		return store, nil
+	case saved.RunID == runID || (resume && runID == ""):
+		store.checkpoint = *saved
+		loggerFrom(ctx).Info("Resuming from checkpoint", "file", path, "runId", saved.RunID, "completedSteps", saved.CompletedSteps)
+		return store, nil
+	case resume:
+		return nil, fmt.Errorf("checkpoint file %s is for run %s, not %s", path, saved.RunID, runID)
+	default:
+		loggerFrom(ctx).Warn("Checkpoint file belongs to another run and will be replaced", "file", path, "checkpointRunId", saved.RunID)
+		return store, nil
+	}
+}
+
+// done reports whether an earlier attempt completed step.
+func (s *checkpointStore) done(step string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Contains(s.checkpoint.CompletedSteps, step)
+}
+
+// get returns a copy of the checkpoint.
+func (s *checkpointStore) get() Checkpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkpoint
+}
+
+// complete marks step as done, applies update to record what it produced and saves the file.
+// Nothing is recorded once the store is halted. A failed save is logged rather than returned:
+// it costs the next attempt some repeated work, not this run.
+func (s *checkpointStore) complete(ctx context.Context, step string, update func(*Checkpoint)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.halted {
+		return
+	}
+	if update != nil {
+		update(&s.checkpoint)
+	}
+	if !slices.Contains(s.checkpoint.CompletedSteps, step) {
+		s.checkpoint.CompletedSteps = append(s.checkpoint.CompletedSteps, step)
+	}
+	s.checkpoint.UpdatedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(s.checkpoint, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(s.path, data, 0600)
+	}
+	if err != nil {
+		loggerFrom(ctx).Warn("Could not save checkpoint", logKeyStep, step, "file", s.path, logKeyError, err)
+	}
+}
+
+// halt stops recording steps, so a step that ran after a failed one, e.g. install after a
+// failed review, isn't skipped by the next attempt.
+func (s *checkpointStore) halt() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.halted = true
+}
+
+// remove deletes the checkpoint file once the run has completed every step.
+func (s *checkpointStore) remove(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		loggerFrom(ctx).Warn("Could not remove checkpoint file", "file", s.path, logKeyError, err)
+	}
+}
+
+// stringValue dereferences p, returning "" for nil.
+func stringValue(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// resourceName returns the last segment of a resource ID, i.e. the resource's name.
+func resourceName(id string) string {
+	return path.Base(id)
+}
@@ -0,0 +1,56 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// ClientOption adjusts the options the SDK clients are created with.
+type ClientOption func(*arm.ClientOptions)
+
+// WithClientCloud points the clients at a cloud other than Azure public.
+func WithClientCloud(cloudConfig cloud.Configuration) ClientOption {
+	return func(o *arm.ClientOptions) { o.Cloud = cloudConfig }
+}
+
+// WithClientTransport sends the clients' requests through transport, e.g. an *http.Client
+// configured with a proxy.
+func WithClientTransport(transport policy.Transporter) ClientOption {
+	return func(o *arm.ClientOptions) { o.Transport = transport }
+}
+
+// WithClientRetry replaces the SDK's own retry policy, which sits below the workflow's
+// operation-level retries (see RetryPolicy).
+func WithClientRetry(retry policy.RetryOptions) ClientOption {
+	return func(o *arm.ClientOptions) { o.Retry = retry }
+}
+
+// WithClientTelemetry sets the application ID the clients add to their User-Agent, or
+// disables telemetry.
+func WithClientTelemetry(telemetry policy.TelemetryOptions) ClientOption {
+	return func(o *arm.ClientOptions) { o.Telemetry = telemetry }
+}
+
+// WithClientPolicies adds policies that run once per request, ahead of the SDK's retries.
+func WithClientPolicies(policies ...policy.Policy) ClientOption {
+	return func(o *arm.ClientOptions) { o.PerCallPolicies = append(o.PerCallPolicies, policies...) }
+}
+
+// newClientFactory creates the workload orchestration client factory with options applied
+// in order, later ones winning.
+func newClientFactory(subscriptionID string, credential azcore.TokenCredential, options ...ClientOption) (*armworkloadorchestration.ClientFactory, error) {
+	var clientOptions arm.ClientOptions
+	for _, option := range options {
+		option(&clientOptions)
+	}
+	clientFactory, err := armworkloadorchestration.NewClientFactory(subscriptionID, credential, &clientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client factory: %w", err)
+	}
+	return clientFactory, nil
+}
@@ -0,0 +1,87 @@
+package workflow
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// The interfaces below list the subset of each SDK client the workflow calls.
+// The armworkloadorchestration clients implement them in production;
+// tests can substitute fakes to drive the steps without live Azure.
+
+// SchemasAPI is the subset of *armworkloadorchestration.SchemasClient used by the workflow.
+type SchemasAPI interface {
+	BeginCreateOrUpdate(ctx context.Context, resourceGroupName string, schemaName string, resource armworkloadorchestration.Schema, options *armworkloadorchestration.SchemasClientBeginCreateOrUpdateOptions) (*runtime.Poller[armworkloadorchestration.SchemasClientCreateOrUpdateResponse], error)
+	BeginDelete(ctx context.Context, resourceGroupName string, schemaName string, options *armworkloadorchestration.SchemasClientBeginDeleteOptions) (*runtime.Poller[armworkloadorchestration.SchemasClientDeleteResponse], error)
+	Get(ctx context.Context, resourceGroupName string, schemaName string, options *armworkloadorchestration.SchemasClientGetOptions) (armworkloadorchestration.SchemasClientGetResponse, error)
+	NewListByResourceGroupPager(resourceGroupName string, options *armworkloadorchestration.SchemasClientListByResourceGroupOptions) *runtime.Pager[armworkloadorchestration.SchemasClientListByResourceGroupResponse]
+}
+
+// SchemaVersionsAPI is the subset of *armworkloadorchestration.SchemaVersionsClient used by the workflow.
+type SchemaVersionsAPI interface {
+	BeginCreateOrUpdate(ctx context.Context, resourceGroupName string, schemaName string, schemaVersionName string, resource armworkloadorchestration.SchemaVersion, options *armworkloadorchestration.SchemaVersionsClientBeginCreateOrUpdateOptions) (*runtime.Poller[armworkloadorchestration.SchemaVersionsClientCreateOrUpdateResponse], error)
+	BeginDelete(ctx context.Context, resourceGroupName string, schemaName string, schemaVersionName string, options *armworkloadorchestration.SchemaVersionsClientBeginDeleteOptions) (*runtime.Poller[armworkloadorchestration.SchemaVersionsClientDeleteResponse], error)
+	Get(ctx context.Context, resourceGroupName string, schemaName string, schemaVersionName string, options *armworkloadorchestration.SchemaVersionsClientGetOptions) (armworkloadorchestration.SchemaVersionsClientGetResponse, error)
+	NewListBySchemaPager(resourceGroupName string, schemaName string, options *armworkloadorchestration.SchemaVersionsClientListBySchemaOptions) *runtime.Pager[armworkloadorchestration.SchemaVersionsClientListBySchemaResponse]
+}
+
+// SolutionTemplatesAPI is the subset of *armworkloadorchestration.SolutionTemplatesClient used by the workflow.
+type SolutionTemplatesAPI interface {
+	BeginCreateOrUpdate(ctx context.Context, resourceGroupName string, solutionTemplateName string, resource armworkloadorchestration.SolutionTemplate, options *armworkloadorchestration.SolutionTemplatesClientBeginCreateOrUpdateOptions) (*runtime.Poller[armworkloadorchestration.SolutionTemplatesClientCreateOrUpdateResponse], error)
+	BeginCreateVersion(ctx context.Context, resourceGroupName string, solutionTemplateName string, body armworkloadorchestration.SolutionTemplateVersionWithUpdateType, options *armworkloadorchestration.SolutionTemplatesClientBeginCreateVersionOptions) (*runtime.Poller[armworkloadorchestration.SolutionTemplatesClientCreateVersionResponse], error)
+	BeginDelete(ctx context.Context, resourceGroupName string, solutionTemplateName string, options *armworkloadorchestration.SolutionTemplatesClientBeginDeleteOptions) (*runtime.Poller[armworkloadorchestration.SolutionTemplatesClientDeleteResponse], error)
+	BeginRemoveVersion(ctx context.Context, resourceGroupName string, solutionTemplateName string, body armworkloadorchestration.VersionParameter, options *armworkloadorchestration.SolutionTemplatesClientBeginRemoveVersionOptions) (*runtime.Poller[armworkloadorchestration.SolutionTemplatesClientRemoveVersionResponse], error)
+	Get(ctx context.Context, resourceGroupName string, solutionTemplateName string, options *armworkloadorchestration.SolutionTemplatesClientGetOptions) (armworkloadorchestration.SolutionTemplatesClientGetResponse, error)
+	NewListByResourceGroupPager(resourceGroupName string, options *armworkloadorchestration.SolutionTemplatesClientListByResourceGroupOptions) *runtime.Pager[armworkloadorchestration.SolutionTemplatesClientListByResourceGroupResponse]
+}
+
+// SolutionTemplateVersionsAPI is the subset of *armworkloadorchestration.SolutionTemplateVersionsClient used by the workflow.
+type SolutionTemplateVersionsAPI interface {
+	Get(ctx context.Context, resourceGroupName string, solutionTemplateName string, solutionTemplateVersionName string, options *armworkloadorchestration.SolutionTemplateVersionsClientGetOptions) (armworkloadorchestration.SolutionTemplateVersionsClientGetResponse, error)
+	NewListBySolutionTemplatePager(resourceGroupName string, solutionTemplateName string, options *armworkloadorchestration.SolutionTemplateVersionsClientListBySolutionTemplateOptions) *runtime.Pager[armworkloadorchestration.SolutionTemplateVersionsClientListBySolutionTemplateResponse]
+}
+
+// SolutionsAPI is the subset of *armworkloadorchestration.SolutionsClient used by the workflow.
+type SolutionsAPI interface {
+	Get(ctx context.Context, resourceGroupName string, targetName string, solutionName string, options *armworkloadorchestration.SolutionsClientGetOptions) (armworkloadorchestration.SolutionsClientGetResponse, error)
+	NewListByTargetPager(resourceGroupName string, targetName string, options *armworkloadorchestration.SolutionsClientListByTargetOptions) *runtime.Pager[armworkloadorchestration.SolutionsClientListByTargetResponse]
+}
+
+// SolutionVersionsAPI is the subset of *armworkloadorchestration.SolutionVersionsClient used by the workflow.
+type SolutionVersionsAPI interface {
+	Get(ctx context.Context, resourceGroupName string, targetName string, solutionName string, solutionVersionName string, options *armworkloadorchestration.SolutionVersionsClientGetOptions) (armworkloadorchestration.SolutionVersionsClientGetResponse, error)
+	NewListBySolutionPager(resourceGroupName string, targetName string, solutionName string, options *armworkloadorchestration.SolutionVersionsClientListBySolutionOptions) *runtime.Pager[armworkloadorchestration.SolutionVersionsClientListBySolutionResponse]
+}
+
+// TargetsAPI is the subset of *armworkloadorchestration.TargetsClient used by the workflow.
+type TargetsAPI interface {
+	BeginCreateOrUpdate(ctx context.Context, resourceGroupName string, targetName string, resource armworkloadorchestration.Target, options *armworkloadorchestration.TargetsClientBeginCreateOrUpdateOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse], error)
+	BeginDelete(ctx context.Context, resourceGroupName string, targetName string, options *armworkloadorchestration.TargetsClientBeginDeleteOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientDeleteResponse], error)
+	Get(ctx context.Context, resourceGroupName string, targetName string, options *armworkloadorchestration.TargetsClientGetOptions) (armworkloadorchestration.TargetsClientGetResponse, error)
+	BeginReviewSolutionVersion(ctx context.Context, resourceGroupName string, targetName string, body armworkloadorchestration.SolutionTemplateParameter, options *armworkloadorchestration.TargetsClientBeginReviewSolutionVersionOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientReviewSolutionVersionResponse], error)
+	BeginResolveConfiguration(ctx context.Context, resourceGroupName string, targetName string, body armworkloadorchestration.SolutionTemplateParameter, options *armworkloadorchestration.TargetsClientBeginResolveConfigurationOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientResolveConfigurationResponse], error)
+	BeginPublishSolutionVersion(ctx context.Context, resourceGroupName string, targetName string, body armworkloadorchestration.SolutionVersionParameter, options *armworkloadorchestration.TargetsClientBeginPublishSolutionVersionOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientPublishSolutionVersionResponse], error)
+	BeginInstallSolution(ctx context.Context, resourceGroupName string, targetName string, body armworkloadorchestration.InstallSolutionParameter, options *armworkloadorchestration.TargetsClientBeginInstallSolutionOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientInstallSolutionResponse], error)
+	NewListByResourceGroupPager(resourceGroupName string, options *armworkloadorchestration.TargetsClientListByResourceGroupOptions) *runtime.Pager[armworkloadorchestration.TargetsClientListByResourceGroupResponse]
+}
+
+// ContextsAPI is the subset of *armworkloadorchestration.ContextsClient used by the workflow.
+type ContextsAPI interface {
+	BeginCreateOrUpdate(ctx context.Context, resourceGroupName string, contextName string, resource armworkloadorchestration.Context, options *armworkloadorchestration.ContextsClientBeginCreateOrUpdateOptions) (*runtime.Poller[armworkloadorchestration.ContextsClientCreateOrUpdateResponse], error)
+	Get(ctx context.Context, resourceGroupName string, contextName string, options *armworkloadorchestration.ContextsClientGetOptions) (armworkloadorchestration.ContextsClientGetResponse, error)
+	NewListByResourceGroupPager(resourceGroupName string, options *armworkloadorchestration.ContextsClientListByResourceGroupOptions) *runtime.Pager[armworkloadorchestration.ContextsClientListByResourceGroupResponse]
+}
+
+// The SDK clients are the production implementations
+var (
+	_ SchemasAPI                  = (*armworkloadorchestration.SchemasClient)(nil)
+	_ SchemaVersionsAPI           = (*armworkloadorchestration.SchemaVersionsClient)(nil)
+	_ SolutionTemplatesAPI        = (*armworkloadorchestration.SolutionTemplatesClient)(nil)
+	_ SolutionTemplateVersionsAPI = (*armworkloadorchestration.SolutionTemplateVersionsClient)(nil)
+	_ SolutionsAPI                = (*armworkloadorchestration.SolutionsClient)(nil)
+	_ SolutionVersionsAPI         = (*armworkloadorchestration.SolutionVersionsClient)(nil)
+	_ TargetsAPI                  = (*armworkloadorchestration.TargetsClient)(nil)
+	_ ContextsAPI                 = (*armworkloadorchestration.ContextsClient)(nil)
+)
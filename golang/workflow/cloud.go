@@ -0,0 +1,70 @@
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+// ParseCloud maps a cloud name to its azcore configuration.
+// Accepts "public" (the default when empty), "usgovernment" and "china".
+func ParseCloud(name string) (cloud.Configuration, error) {
+	switch strings.ToLower(name) {
+	case "", "public", "azurepublic":
+		return cloud.AzurePublic, nil
+	case "usgovernment", "usgov", "azuregovernment":
+		return cloud.AzureGovernment, nil
+	case "china", "azurechina":
+		return cloud.AzureChina, nil
+	default:
+		return cloud.Configuration{}, fmt.Errorf("unknown cloud %q (valid: public, usgovernment, china)", name)
+	}
+}
+
+// WithResourceManagerEndpoint returns a copy of cloudConfig whose Resource Manager requests go to
+// endpoint, e.g. a private ARM endpoint or a local test server, keeping the token audience. The
+// Configuration API calls build their URLs on this endpoint. A zero cloudConfig means the public cloud.
+func WithResourceManagerEndpoint(cloudConfig cloud.Configuration, endpoint string) cloud.Configuration {
+	if cloudConfig.Services == nil {
+		cloudConfig = cloud.AzurePublic
+	}
+	// The predefined clouds share their Services map, so never write to it in place
+	cloudConfig.Services = maps.Clone(cloudConfig.Services)
+	service := cloudConfig.Services[cloud.ResourceManager]
+	service.Endpoint = endpoint
+	cloudConfig.Services[cloud.ResourceManager] = service
+	return cloudConfig
+}
+
+// resourceManager returns the Resource Manager endpoint and token audience for cloudConfig.
+// A zero configuration means the public cloud.
+func resourceManager(cloudConfig cloud.Configuration) (cloud.ServiceConfiguration, error) {
+	if cloudConfig.Services == nil {
+		cloudConfig = cloud.AzurePublic
+	}
+	service, ok := cloudConfig.Services[cloud.ResourceManager]
+	if !ok || service.Endpoint == "" || service.Audience == "" {
+		return cloud.ServiceConfiguration{}, fmt.Errorf("cloud configuration has no Resource Manager endpoint")
+	}
+	return service, nil
+}
+
+// ResourceManagerScope returns the token scope for Resource Manager in cloudConfig.
+func ResourceManagerScope(cloudConfig cloud.Configuration) (string, error) {
+	service, err := resourceManager(cloudConfig)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(service.Audience, "/") + "/.default", nil
+}
+
+// resourceManagerEndpoint returns the Resource Manager base URL for cloudConfig, without a trailing slash.
+func resourceManagerEndpoint(cloudConfig cloud.Configuration) (string, error) {
+	service, err := resourceManager(cloudConfig)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(service.Endpoint, "/"), nil
+}
@@ -0,0 +1,146 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Component types the target knows a provider for, see DefaultProviders. Any other type
+// works too, given a target binding for it (see Options.Topologies).
+const (
+	ComponentTypeHelm      = "helm.v3"   // A Helm chart; see NewHelmComponent
+	ComponentTypeKubectl   = "yaml.k8s"  // Kubernetes manifests applied as they are
+	ComponentTypeContainer = "container" // A container image run as a Kubernetes deployment
+	ComponentTypeScript    = "script"    // Scripts run on the target's host
+)
+
+// DefaultProviders maps each well-known component type to the Symphony provider that
+// deploys it, as bound by TopologiesForComponents.
+var DefaultProviders = map[string]string{
+	ComponentTypeHelm:      "providers.target.helm",
+	ComponentTypeKubectl:   "providers.target.kubectl",
+	ComponentTypeContainer: "providers.target.k8s",
+	ComponentTypeScript:    "providers.target.script",
+}
+
+// Component is one deployable unit in a solution specification. Properties are passed to the
+// provider as they are, e.g. {"chart": {...}} for helm.v3 or {"yaml": "https://..."} for yaml.k8s.
+type Component struct {
+	Name       string                 `yaml:"name"`
+	Type       string                 `yaml:"type"` // Provider role, e.g. "helm.v3"; the target needs a binding for it
+	Properties map[string]interface{} `yaml:"properties"`
+}
+
+// NewHelmComponent wraps a Helm chart as a helm.v3 component.
+func NewHelmComponent(name string, chart HelmChart) (Component, error) {
+	chartProperties, err := buildHelmChartProperties(chart)
+	if err != nil {
+		return Component{}, fmt.Errorf("error building helm chart properties for %s: %w", name, err)
+	}
+
+	return Component{
+		Name: name,
+		Type: "helm.v3",
+		Properties: map[string]interface{}{
+			"chart": chartProperties,
+		},
+	}, nil
+}
+
+// DefaultComponents returns the single sample Helm component used when the caller supplies none.
+func DefaultComponents() ([]Component, error) {
+	component, err := NewHelmComponent("helmcomponent", DefaultHelmChart)
+	if err != nil {
+		return nil, err
+	}
+	return []Component{component}, nil
+}
+
+// TopologiesForComponents returns a target topology binding every type in components to its
+// provider in DefaultProviders. Kubernetes providers deploy into the target's own cluster, as
+// DefaultTargetTopologies does for Helm. A type without a default provider is an error; bind
+// it explicitly instead.
+func TopologiesForComponents(components []Component) ([]TargetTopology, error) {
+	var bindings []TargetBinding
+	seen := make(map[string]bool)
+	for _, component := range components {
+		if seen[component.Type] {
+			continue
+		}
+		seen[component.Type] = true
+		provider, ok := DefaultProviders[component.Type]
+		if !ok {
+			return nil, fmt.Errorf("component %s has type %q, which has no default provider; add a target binding for it", component.Name, component.Type)
+		}
+		config := map[string]interface{}{}
+		if component.Type != ComponentTypeScript {
+			config["inCluster"] = "true"
+		}
+		bindings = append(bindings, TargetBinding{Role: component.Type, Provider: provider, Config: config})
+	}
+	if len(bindings) == 0 {
+		return nil, fmt.Errorf("no components to bind")
+	}
+	return []TargetTopology{{Bindings: bindings}}, nil
+}
+
+// ValidateComponentBindings checks that the target's topologies bind a provider to the type of
+// every component, since the service only finds a missing binding at install. All unbound
+// types are reported at once.
+func ValidateComponentBindings(components []Component, topologies []TargetTopology) error {
+	roles := make(map[string]bool)
+	for _, topology := range topologies {
+		for _, binding := range topology.Bindings {
+			roles[binding.Role] = true
+		}
+	}
+	var unbound []string
+	for _, component := range components {
+		if !roles[component.Type] {
+			unbound = append(unbound, fmt.Sprintf("%s (%s)", component.Name, component.Type))
+		}
+	}
+	if len(unbound) > 0 {
+		bound := make([]string, 0, len(roles))
+		for role := range roles {
+			bound = append(bound, role)
+		}
+		sort.Strings(bound)
+		return fmt.Errorf("the target has no binding for components %s (bound roles: %s)", strings.Join(unbound, ", "), strings.Join(bound, ", "))
+	}
+	return nil
+}
+
+// buildSpecification builds the solution specification from components.
+// At least one component is required and component names must be unique.
+func buildSpecification(components []Component) (map[string]interface{}, error) {
+	if len(components) == 0 {
+		return nil, fmt.Errorf("solution specification needs at least one component")
+	}
+
+	seen := make(map[string]bool)
+	items := make([]map[string]interface{}, 0, len(components))
+	for i, component := range components {
+		if component.Name == "" {
+			return nil, fmt.Errorf("component at index %d has no name", i)
+		}
+		if component.Type == "" {
+			return nil, fmt.Errorf("component %s has no type", component.Name)
+		}
+		if seen[component.Name] {
+			return nil, fmt.Errorf("duplicate component name %s", component.Name)
+		}
+		seen[component.Name] = true
+
+		items = append(items, map[string]interface{}{
+			"name":       component.Name,
+			"type":       component.Type,
+			"properties": component.Properties,
+		})
+	}
+
+	return map[string]interface{}{
+		"components": items,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewHelmComponentSpecification(t *testing.T) {
+	tests := []struct {
+		name    string
+		chart   HelmChart
+		want    map[string]interface{} // The component's chart properties
+		wantErr bool
+	}{
+		{
+			name:  "OCI reference",
+			chart: HelmChart{Repo: "ghcr.io/eclipse-symphony/tests/helm/simple-chart", Version: "0.3.0", Wait: true, Timeout: "5m"},
+			want:  map[string]interface{}{"repo": "ghcr.io/eclipse-symphony/tests/helm/simple-chart", "version": "0.3.0", "wait": true, "timeout": "5m"},
+		},
+		{
+			name:  "OCI URL with token",
+			chart: HelmChart{Repo: "oci://contoso.azurecr.io/charts/app", Version: "1.2.0", Auth: &HelmRegistryAuth{Username: "puller", Token: "secret-token"}},
+			want:  map[string]interface{}{"repo": "oci://contoso.azurecr.io/charts/app", "version": "1.2.0", "wait": false, "username": "puller", "password": "secret-token"},
+		},
+		{
+			name:  "HTTP repository",
+			chart: HelmChart{Repo: "https://charts.contoso.com/stable", Name: "app", Version: "2.0.1", Wait: true},
+			want:  map[string]interface{}{"repo": "https://charts.contoso.com/stable", "name": "app", "version": "2.0.1", "wait": true},
+		},
+		{
+			name:  "HTTP repository with password",
+			chart: HelmChart{Repo: "http://localhost:8080/charts", Name: "app", Version: "2.0.1", Auth: &HelmRegistryAuth{Username: "reader", Password: "pw"}},
+			want:  map[string]interface{}{"repo": "http://localhost:8080/charts", "name": "app", "version": "2.0.1", "wait": false, "username": "reader", "password": "pw"},
+		},
+		{name: "HTTP repository without chart name", chart: HelmChart{Repo: "https://charts.contoso.com/stable", Version: "2.0.1"}, wantErr: true},
+		{name: "no version", chart: HelmChart{Repo: "ghcr.io/contoso/app"}, wantErr: true},
+		{name: "unsupported scheme", chart: HelmChart{Repo: "ftp://charts.contoso.com/app", Version: "1.0.0"}, wantErr: true},
+		{name: "password and token", chart: HelmChart{Repo: "ghcr.io/contoso/app", Version: "1.0.0", Auth: &HelmRegistryAuth{Username: "u", Password: "p", Token: "t"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			component, err := NewHelmComponent("helmcomponent", tt.chart)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewHelmComponent = %+v, want an error", component)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewHelmComponent: %v", err)
+			}
+
+			specification, err := buildSpecification([]Component{component})
+			if err != nil {
+				t.Fatalf("buildSpecification: %v", err)
+			}
+			want := map[string]interface{}{
+				"components": []map[string]interface{}{{
+					"name":       "helmcomponent",
+					"type":       "helm.v3",
+					"properties": map[string]interface{}{"chart": tt.want},
+				}},
+			}
+			if !reflect.DeepEqual(specification, want) {
+				t.Errorf("specification = %#v, want %#v", specification, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package workflow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a whole run in one file, as read by LoadConfig. Its keys are the camelCase
+// field names, e.g.:
+//
+//	subscriptionId: 00000000-0000-0000-0000-000000000000
+//	resourceGroup: sdkexamples
+//	location: eastus2euap
+//	contextName: Mehoopany-Context
+//	capabilities:
+//	  - name: sdkexamples-soap
+//	    description: Soap manufacturing
+//	helmChart:
+//	  repo: ghcr.io/eclipse-symphony/tests/helm/simple-chart
+//	  version: 0.3.0
+//	configValues:
+//	  ErrorThreshold: 35.3
+//
+// JSON is accepted too, since it is valid YAML.
+type Config struct {
+	SubscriptionID       string            `yaml:"subscriptionId"`
+	ResourceGroup        string            `yaml:"resourceGroup"`
+	Location             string            `yaml:"location"`
+	ContextResourceGroup string            `yaml:"contextResourceGroup"`
+	ContextName          string            `yaml:"contextName"`
+	NamePrefix           string            `yaml:"namePrefix"`
+	Tags                 map[string]string `yaml:"tags"`
+
+	Capabilities []Capability           `yaml:"capabilities"` // Seeded into the context (see Options.SeedCapabilities)
+	Hierarchies  []Hierarchy            `yaml:"hierarchies"`
+	SchemaRules  []SchemaRule           `yaml:"schemaRules"`
+	HelmChart    *HelmChart             `yaml:"helmChart"`
+	Components   []Component            `yaml:"components"` // Replace helmChart with any component types (see Options.Components)
+	Topologies   []TargetTopology       `yaml:"topologies"` // The new target's bindings (see Options.Topologies)
+	ConfigValues map[string]interface{} `yaml:"configValues"`
+	// ConfigDefaults fill in optional fields missing from configValues (see Options.ConfigDefaults)
+	ConfigDefaults map[string]interface{} `yaml:"configDefaults"`
+}
+
+// LoadConfig reads a Config from a YAML or JSON file. Unknown keys are rejected so a typo
+// doesn't silently fall back to a default, and every missing required field (subscriptionId,
+// resourceGroup, location and contextName) is reported in one error.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// validate checks the required fields and the structured sections, collecting every problem.
+func (c *Config) validate() error {
+	var missing []string
+	for _, field := range []struct{ name, value string }{
+		{"subscriptionId", c.SubscriptionID},
+		{"resourceGroup", c.ResourceGroup},
+		{"location", c.Location},
+		{"contextName", c.ContextName},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			missing = append(missing, field.name)
+		}
+	}
+
+	var errs []error
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("missing required fields: %s", strings.Join(missing, ", ")))
+	}
+	for i, capability := range c.Capabilities {
+		if err := ValidateCapabilityName(capability.Name); err != nil {
+			errs = append(errs, fmt.Errorf("capabilities[%d]: %w", i, err))
+		}
+	}
+	if c.SchemaRules != nil {
+		if _, err := BuildSchemaValue(c.SchemaRules); err != nil {
+			errs = append(errs, fmt.Errorf("schemaRules: %w", err))
+		}
+	}
+	if c.HelmChart != nil {
+		if _, err := buildHelmChartProperties(*c.HelmChart); err != nil {
+			errs = append(errs, fmt.Errorf("helmChart: %w", err))
+		}
+	}
+	if c.HelmChart != nil && c.Components != nil {
+		errs = append(errs, fmt.Errorf("helmChart and components are mutually exclusive; list the chart as a %s component instead", ComponentTypeHelm))
+	}
+	if c.Components != nil {
+		if _, err := buildSpecification(c.Components); err != nil {
+			errs = append(errs, fmt.Errorf("components: %w", err))
+		}
+	}
+	if c.Topologies != nil {
+		if _, err := buildTargetSpecification(c.Topologies); err != nil {
+			errs = append(errs, fmt.Errorf("topologies: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Apply copies the file's settings into the fields of opts that are still unset, so values the
+// caller already chose, e.g. from flags, take precedence over the file.
+func (c *Config) Apply(opts *Options) error {
+	opts.SubscriptionID = valueOrDefault(opts.SubscriptionID, c.SubscriptionID)
+	opts.ResourceGroup = valueOrDefault(opts.ResourceGroup, c.ResourceGroup)
+	opts.Location = valueOrDefault(opts.Location, c.Location)
+	opts.ContextResourceGroup = valueOrDefault(opts.ContextResourceGroup, c.ContextResourceGroup)
+	opts.ContextName = valueOrDefault(opts.ContextName, c.ContextName)
+	opts.NamePrefix = valueOrDefault(opts.NamePrefix, c.NamePrefix)
+	if opts.Tags == nil {
+		opts.Tags = c.Tags
+	}
+	if opts.SeedCapabilities == nil {
+		opts.SeedCapabilities = c.Capabilities
+	}
+	if opts.Hierarchies == nil {
+		opts.Hierarchies = c.Hierarchies
+	}
+	if opts.SchemaRules == nil {
+		opts.SchemaRules = c.SchemaRules
+	}
+	if opts.ConfigValues == nil {
+		opts.ConfigValues = c.ConfigValues
+	}
+	if opts.ConfigDefaults == nil {
+		opts.ConfigDefaults = c.ConfigDefaults
+	}
+	if opts.Components == nil {
+		opts.Components = c.Components
+	}
+	if opts.Topologies == nil {
+		opts.Topologies = c.Topologies
+	}
+	if opts.Components == nil && c.HelmChart != nil {
+		component, err := NewHelmComponent("helmcomponent", *c.HelmChart)
+		if err != nil {
+			return err
+		}
+		opts.Components = []Component{component}
+	}
+	return nil
+}
@@ -0,0 +1,437 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"gopkg.in/yaml.v3"
+)
+
+// Sets dynamic configuration values for a solution using direct REST API calls.
+// This provides configuration data that the deployed solution will use at runtime.
+// Called before reviewing the target to ensure configuration is available.
+// Throttled (429) and 5xx responses are retried. A nil httpClient uses a shared client with a timeout.
+// Requests go to cloudConfig's Resource Manager endpoint; see WithResourceManagerEndpoint to send
+// them elsewhere, e.g. to an httptest.Server.
+// With dryRun set, the request is printed but not sent. Request and response details are
+// only logged when httpClient logs them (see Options.HTTPLog).
+func CreateConfigurationAPICall(ctx context.Context, credential azcore.TokenCredential, cloudConfig cloud.Configuration, httpClient *http.Client, subscriptionID, resourceGroup, configName, solutionName, version string, configValues map[string]interface{}, dryRun bool) error {
+	endpoint, token, err := configurationAPIAuth(ctx, credential, cloudConfig)
+	if err != nil {
+		return err
+	}
+
+	url := configurationVersionURL(endpoint, subscriptionID, resourceGroup, configName, solutionName, version)
+
+	valuesString, err := marshalConfigValues(configValues)
+	if err != nil {
+		return err
+	}
+
+	requestBody := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"values":            valuesString,
+			"provisioningState": "Succeeded",
+		},
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("error marshaling request body: %w", err)
+	}
+
+	if dryRun {
+		logDryRun(ctx, "PUT", "Microsoft.Edge/configurations", configName, map[string]interface{}{
+			"url":  url,
+			"body": string(jsonBody),
+		})
+		return nil
+	}
+
+	loggerFrom(ctx).Info("Setting configuration values", logKeyResource, configName, "solution", solutionName, "version", version)
+
+	resp, err := doWithRetry(ctx, httpClientOrDefault(httpClient), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		loggerFrom(ctx).Info("Configuration values set", logKeyResource, configName, "status", resp.StatusCode)
+		return nil
+	}
+
+	return fmt.Errorf("configuration API call failed: %w", parseARMError(resp.StatusCode, body))
+}
+
+// Retrieves configuration values that were set via the Configuration API.
+// Used to confirm that configuration was properly stored and is available to the solution.
+// Returns the stored properties.values YAML. A non-200 response is returned as an *ARMError.
+// Retries, httpClient and the endpoint behave as in CreateConfigurationAPICall.
+func GetConfigurationAPICall(ctx context.Context, credential azcore.TokenCredential, cloudConfig cloud.Configuration, httpClient *http.Client, subscriptionID, resourceGroup, configName, solutionName, version string) (string, error) {
+	endpoint, token, err := configurationAPIAuth(ctx, credential, cloudConfig)
+	if err != nil {
+		return "", err
+	}
+
+	url := configurationVersionURL(endpoint, subscriptionID, resourceGroup, configName, solutionName, version)
+
+	loggerFrom(ctx).Info("Reading configuration values", logKeyResource, configName, "solution", solutionName, "version", version)
+
+	resp, err := doWithRetry(ctx, httpClientOrDefault(httpClient), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 200 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("error reading response: %w", err)
+		}
+
+		loggerFrom(ctx).Info("Configuration values read", logKeyResource, configName, "status", resp.StatusCode)
+
+		var storedValues string
+		var responseJSON map[string]interface{}
+		if err := json.Unmarshal(body, &responseJSON); err == nil {
+			if properties, ok := responseJSON["properties"].(map[string]interface{}); ok {
+				if values, ok := properties["values"].(string); ok {
+					storedValues = values
+				}
+			}
+		} else {
+			loggerFrom(ctx).Warn("Configuration response is not valid JSON", logKeyResource, configName)
+		}
+
+		return storedValues, nil
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	return "", fmt.Errorf("configuration GET call failed: %w", parseARMError(resp.StatusCode, body))
+}
+
+// Deletes one version of a solution's dynamic configuration, e.g. a stale one left by an earlier
+// run. A version that doesn't exist counts as deleted. Other non-2xx responses are returned as an
+// *ARMError. Retries, httpClient and the endpoint behave as in CreateConfigurationAPICall.
+// With dryRun set, the request is printed but not sent.
+func DeleteConfigurationAPICall(ctx context.Context, credential azcore.TokenCredential, cloudConfig cloud.Configuration, httpClient *http.Client, subscriptionID, resourceGroup, configName, solutionName, version string, dryRun bool) error {
+	endpoint, token, err := configurationAPIAuth(ctx, credential, cloudConfig)
+	if err != nil {
+		return err
+	}
+
+	url := configurationVersionURL(endpoint, subscriptionID, resourceGroup, configName, solutionName, version)
+
+	if dryRun {
+		logDryRun(ctx, "DELETE", "Microsoft.Edge/configurations", configName, map[string]interface{}{
+			"url": url,
+		})
+		return nil
+	}
+
+	loggerFrom(ctx).Info("Deleting configuration version", logKeyResource, configName, "solution", solutionName, "version", version)
+
+	resp, err := doWithRetry(ctx, httpClientOrDefault(httpClient), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		loggerFrom(ctx).Info("Configuration version already deleted", logKeyResource, configName, "version", version)
+		return nil
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		loggerFrom(ctx).Info("Configuration version deleted", logKeyResource, configName, "version", version, "status", resp.StatusCode)
+		return nil
+	}
+
+	return fmt.Errorf("configuration DELETE call failed: %w", parseARMError(resp.StatusCode, body))
+}
+
+// ConfigurationVersion is one version of a solution's dynamic configuration, as listed by
+// ListConfigurationsAPICall.
+type ConfigurationVersion struct {
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	Values            string `json:"values"` // The stored properties.values YAML
+	ProvisioningState string `json:"provisioningState"`
+}
+
+// Lists every version of a solution's dynamic configuration, following nextLink across pages.
+// A nextLink on another scheme or host than the Resource Manager endpoint is an error, so the
+// token is never sent elsewhere.
+// Returns an empty slice when there are none. A non-200 response is returned as an *ARMError.
+// Retries, httpClient and the endpoint behave as in CreateConfigurationAPICall.
+func ListConfigurationsAPICall(ctx context.Context, credential azcore.TokenCredential, cloudConfig cloud.Configuration, httpClient *http.Client, subscriptionID, resourceGroup, configName, solutionName string) ([]ConfigurationVersion, error) {
+	endpoint, token, err := configurationAPIAuth(ctx, credential, cloudConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	loggerFrom(ctx).Info("Listing configuration versions", logKeyResource, configName, "solution", solutionName)
+
+	versions := []ConfigurationVersion{}
+	url := configurationVersionsURL(endpoint, subscriptionID, resourceGroup, configName, solutionName)
+	for url != "" {
+		pageURL := url
+		resp, err := doWithRetry(ctx, httpClientOrDefault(httpClient), func() (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+			if err != nil {
+				return nil, fmt.Errorf("error creating request: %w", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token.Token)
+			req.Header.Set("Content-Type", "application/json")
+			return req, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error making request: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("configuration list call failed: %w", parseARMError(resp.StatusCode, body))
+		}
+
+		var page struct {
+			Value []struct {
+				ID         string `json:"id"`
+				Name       string `json:"name"`
+				Properties struct {
+					Values            string `json:"values"`
+					ProvisioningState string `json:"provisioningState"`
+				} `json:"properties"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("error parsing configuration list response: %w", err)
+		}
+		for _, item := range page.Value {
+			versions = append(versions, ConfigurationVersion{
+				ID:                item.ID,
+				Name:              item.Name,
+				Values:            item.Properties.Values,
+				ProvisioningState: item.Properties.ProvisioningState,
+			})
+		}
+		if page.NextLink != "" {
+			// The token is only meant for the configured endpoint, so never follow a link elsewhere
+			if err := checkSameOrigin(endpoint, page.NextLink); err != nil {
+				return nil, fmt.Errorf("error following configuration list nextLink: %w", err)
+			}
+		}
+		url = page.NextLink
+	}
+
+	loggerFrom(ctx).Info("Configuration versions listed", logKeyResource, configName, "count", len(versions))
+	return versions, nil
+}
+
+// checkSameOrigin fails unless link has the scheme and host of endpoint.
+func checkSameOrigin(endpoint, link string) error {
+	want, err := neturl.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	got, err := neturl.Parse(link)
+	if err != nil {
+		return fmt.Errorf("invalid link %q: %w", link, err)
+	}
+	if !strings.EqualFold(got.Scheme, want.Scheme) || !strings.EqualFold(got.Host, want.Host) {
+		return fmt.Errorf("link %q points outside %s", link, endpoint)
+	}
+	return nil
+}
+
+// configurationAPIAuth returns the Resource Manager endpoint of cloudConfig and a token for it.
+func configurationAPIAuth(ctx context.Context, credential azcore.TokenCredential, cloudConfig cloud.Configuration) (string, azcore.AccessToken, error) {
+	endpoint, err := resourceManagerEndpoint(cloudConfig)
+	if err != nil {
+		return "", azcore.AccessToken{}, err
+	}
+	scope, err := ResourceManagerScope(cloudConfig)
+	if err != nil {
+		return "", azcore.AccessToken{}, err
+	}
+
+	token, err := credential.GetToken(ctx, policy.TokenRequestOptions{
+		Scopes: []string{scope},
+	})
+	if err != nil {
+		return "", azcore.AccessToken{}, fmt.Errorf("error getting token: %w", err)
+	}
+	return endpoint, token, nil
+}
+
+// The Configuration API version every call uses.
+const configurationAPIVersion = "2024-06-01-preview"
+
+// Builds the Configuration API URL for one version of a solution's dynamic configuration.
+func configurationVersionURL(endpoint, subscriptionID, resourceGroup, configName, solutionName, version string) string {
+	return fmt.Sprintf("%s/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Edge/configurations/%s/DynamicConfigurations/%s/versions/%s?api-version=%s",
+		endpoint, neturl.PathEscape(subscriptionID), neturl.PathEscape(resourceGroup), neturl.PathEscape(configName), neturl.PathEscape(solutionName), neturl.PathEscape(version), configurationAPIVersion)
+}
+
+// Builds the Configuration API URL listing every version of a solution's dynamic configuration.
+func configurationVersionsURL(endpoint, subscriptionID, resourceGroup, configName, solutionName string) string {
+	return fmt.Sprintf("%s/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Edge/configurations/%s/DynamicConfigurations/%s/versions?api-version=%s",
+		endpoint, neturl.PathEscape(subscriptionID), neturl.PathEscape(resourceGroup), neturl.PathEscape(configName), neturl.PathEscape(solutionName), configurationAPIVersion)
+}
+
+// Renders configuration values as the YAML document the Configuration API stores.
+// Floats keep full precision, nested maps and lists are preserved, and strings that
+// would otherwise read back as numbers or booleans are quoted.
+func marshalConfigValues(configValues map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(configValues); err != nil {
+		return "", fmt.Errorf("error encoding configuration values: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("error encoding configuration values: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Returns configValues with a value from defaults for each optional field of rules that it
+// doesn't set, along with the names of the fields filled in, in rule order. Required fields and
+// defaults without a rule are skipped, so a missing required field still fails validation
+// (see ValidateConfigAgainstSchema). configValues itself is not modified.
+func ApplyConfigDefaults(rules []SchemaRule, configValues, defaults map[string]interface{}) (map[string]interface{}, []string) {
+	merged := make(map[string]interface{}, len(configValues)+len(defaults))
+	for key, value := range configValues {
+		merged[key] = value
+	}
+
+	var defaulted []string
+	for _, rule := range rules {
+		if rule.Required {
+			continue
+		}
+		if value, ok := merged[rule.Name]; ok && value != nil {
+			continue
+		}
+		if value, ok := defaults[rule.Name]; ok {
+			merged[rule.Name] = value
+			defaulted = append(defaulted, rule.Name)
+		}
+	}
+	return merged, defaulted
+}
+
+// Compares the values YAML read back from the Configuration API against the values that were sent.
+// Every expected key must be present with an equal value; numbers compare by value and
+// trailing whitespace on strings is ignored. Returns all mismatches at once.
+func VerifyConfigurationValues(storedValues string, expected map[string]interface{}) error {
+	var stored map[string]interface{}
+	if err := yaml.Unmarshal([]byte(storedValues), &stored); err != nil {
+		return fmt.Errorf("error parsing stored configuration values: %w", err)
+	}
+
+	keys := make([]string, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var mismatches []string
+	for _, key := range keys {
+		got, ok := stored[key]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s: missing from stored configuration", key))
+			continue
+		}
+		want := expected[key]
+		if !reflect.DeepEqual(normalizeConfigValue(got), normalizeConfigValue(want)) {
+			mismatches = append(mismatches, fmt.Sprintf("%s: sent %v (%T), stored %v (%T)", key, want, want, got, got))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("stored configuration does not match what was sent:\n  %s", strings.Join(mismatches, "\n  "))
+	}
+	return nil
+}
+
+// normalizeConfigValue maps equivalent values onto one representation for comparison:
+// all numbers become float64, strings lose trailing whitespace, and nested maps and lists
+// are normalized element by element.
+func normalizeConfigValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimRight(v, " \t\r\n")
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			normalized[key] = normalizeConfigValue(item)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalizeConfigValue(item)
+		}
+		return normalized
+	default:
+		return v
+	}
+}
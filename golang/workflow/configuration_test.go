@@ -0,0 +1,223 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// fakeCredential hands out a fixed token.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "test-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+const testConfigurationVersionPath = "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Edge/configurations/config/DynamicConfigurations/solution/versions/1.2.3"
+
+// configurationServer serves handler and returns the cloud configuration pointing at it.
+func configurationServer(t *testing.T, handler http.HandlerFunc) cloud.Configuration {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return WithResourceManagerEndpoint(cloud.AzurePublic, server.URL)
+}
+
+// checkConfigurationRequest fails the test unless r is an authorized method request for the
+// test configuration version.
+func checkConfigurationRequest(t *testing.T, r *http.Request, method string) {
+	t.Helper()
+	if r.Method != method {
+		t.Errorf("method = %s, want %s", r.Method, method)
+	}
+	if r.URL.Path != testConfigurationVersionPath {
+		t.Errorf("path = %s, want %s", r.URL.Path, testConfigurationVersionPath)
+	}
+	if got := r.URL.Query().Get("api-version"); got != configurationAPIVersion {
+		t.Errorf("api-version = %q, want %q", got, configurationAPIVersion)
+	}
+	if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+		t.Errorf("Authorization = %q, want the bearer token", got)
+	}
+}
+
+func writeARMError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": code, "message": message}})
+}
+
+func TestCreateConfigurationAPICallSendsValues(t *testing.T) {
+	cloudConfig := configurationServer(t, func(w http.ResponseWriter, r *http.Request) {
+		checkConfigurationRequest(t, r, http.MethodPut)
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+		var body struct {
+			Properties struct {
+				Values            string `json:"values"`
+				ProvisioningState string `json:"provisioningState"`
+			} `json:"properties"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("request body %s is not JSON: %v", data, err)
+		}
+		if err := VerifyConfigurationValues(body.Properties.Values, map[string]interface{}{"ErrorThreshold": 35.3, "HealthCheckEndpoint": "http://localhost:8080/health"}); err != nil {
+			t.Errorf("sent values: %v", err)
+		}
+		if body.Properties.ProvisioningState != "Succeeded" {
+			t.Errorf("provisioningState = %q, want Succeeded", body.Properties.ProvisioningState)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	err := CreateConfigurationAPICall(testContext(), fakeCredential{}, cloudConfig, nil, "sub-id", "rg", "config", "solution", "1.2.3",
+		map[string]interface{}{"ErrorThreshold": 35.3, "HealthCheckEndpoint": "http://localhost:8080/health"}, false)
+	if err != nil {
+		t.Fatalf("CreateConfigurationAPICall: %v", err)
+	}
+}
+
+func TestCreateConfigurationAPICallRetriesThrottledRequests(t *testing.T) {
+	var requests atomic.Int32
+	cloudConfig := configurationServer(t, func(w http.ResponseWriter, r *http.Request) {
+		checkConfigurationRequest(t, r, http.MethodPut)
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			writeARMError(w, http.StatusTooManyRequests, "Throttled", "Too many requests")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err := CreateConfigurationAPICall(testContext(), fakeCredential{}, cloudConfig, nil, "sub-id", "rg", "config", "solution", "1.2.3", map[string]interface{}{"key": "value"}, false)
+	if err != nil {
+		t.Fatalf("CreateConfigurationAPICall: %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestGetConfigurationAPICall(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		code       string
+		wantValues string
+		wantErr    bool
+	}{
+		{name: "stored values", status: http.StatusOK, wantValues: "key: value\n"},
+		{name: "throttled", status: http.StatusTooManyRequests, code: "Throttled", wantErr: true},
+		{name: "not found", status: http.StatusNotFound, code: "ResourceNotFound", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			cloudConfig := configurationServer(t, func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				checkConfigurationRequest(t, r, http.MethodGet)
+				if tt.status != http.StatusOK {
+					writeARMError(w, tt.status, tt.code, "request failed")
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]any{"properties": map[string]any{"values": tt.wantValues}})
+			})
+			// Without retries a throttled response comes straight back
+			ctx := WithRetryPolicy(testContext(), RetryConfigurationAPI, NoRetry)
+
+			values, err := GetConfigurationAPICall(ctx, fakeCredential{}, cloudConfig, nil, "sub-id", "rg", "config", "solution", "1.2.3")
+			if got := requests.Load(); got != 1 {
+				t.Errorf("requests = %d, want 1", got)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("GetConfigurationAPICall: %v", err)
+				}
+				if values != tt.wantValues {
+					t.Errorf("values = %q, want %q", values, tt.wantValues)
+				}
+				return
+			}
+			var armErr *ARMError
+			if !errors.As(err, &armErr) {
+				t.Fatalf("error = %v, want an *ARMError", err)
+			}
+			if armErr.StatusCode != tt.status || armErr.Code != tt.code {
+				t.Errorf("ARMError = %d %s, want %d %s", armErr.StatusCode, armErr.Code, tt.status, tt.code)
+			}
+			if !strings.Contains(armErr.RawBody, tt.code) {
+				t.Errorf("RawBody = %q, want the response body", armErr.RawBody)
+			}
+		})
+	}
+}
+
+func TestListConfigurationsAPICallFollowsNextLink(t *testing.T) {
+	versionsPath := strings.TrimSuffix(testConfigurationVersionPath, "/1.2.3")
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != versionsPath {
+			t.Errorf("path = %s, want %s", r.URL.Path, versionsPath)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q, want the bearer token", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "" {
+			json.NewEncoder(w).Encode(map[string]any{
+				"value":    []any{map[string]any{"name": "1.0.0"}},
+				"nextLink": server.URL + versionsPath + "?api-version=" + configurationAPIVersion + "&page=2",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"value": []any{map[string]any{"name": "2.0.0"}}})
+	}))
+	t.Cleanup(server.Close)
+	cloudConfig := WithResourceManagerEndpoint(cloud.AzurePublic, server.URL)
+
+	versions, err := ListConfigurationsAPICall(testContext(), fakeCredential{}, cloudConfig, nil, "sub-id", "rg", "config", "solution")
+	if err != nil {
+		t.Fatalf("ListConfigurationsAPICall: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Name != "1.0.0" || versions[1].Name != "2.0.0" {
+		t.Errorf("versions = %+v, want 1.0.0 and 2.0.0", versions)
+	}
+}
+
+func TestListConfigurationsAPICallRejectsForeignNextLink(t *testing.T) {
+	var foreignRequests atomic.Int32
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		foreignRequests.Add(1)
+	}))
+	t.Cleanup(foreign.Close)
+
+	cloudConfig := configurationServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"value":    []any{map[string]any{"name": "1.0.0"}},
+			"nextLink": foreign.URL + "/next",
+		})
+	})
+
+	_, err := ListConfigurationsAPICall(testContext(), fakeCredential{}, cloudConfig, nil, "sub-id", "rg", "config", "solution")
+	if err == nil {
+		t.Fatal("ListConfigurationsAPICall followed a nextLink to another host")
+	}
+	if got := foreignRequests.Load(); got != 0 {
+		t.Errorf("foreign host got %d requests, want none", got)
+	}
+}
@@ -0,0 +1,567 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// Capability represents a capability with name and description
+type Capability struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// maxContextUpdateAttempts bounds how often ManageAzureContext re-reads and re-merges a context
+// that another run changed between its read and its write.
+const maxContextUpdateAttempts = 5
+
+// ExistingContext is a context's state as read before merging changes into it.
+type ExistingContext struct {
+	Capabilities []Capability
+	Hierarchies  []Hierarchy
+	Tags         map[string]string
+	Location     string
+	// Exists is false when the context wasn't found, in which case the other fields are empty
+	Exists bool
+	// ETag identifies the version that was read; empty when the context doesn't exist yet
+	// or the service returned none, in which case writes are unconditional.
+	ETag string
+}
+
+// Fetches an existing Azure Context to get current capabilities and hierarchies.
+// Contexts coordinate capabilities across multiple targets in an organization.
+// This allows us to add new capabilities while preserving existing ones.
+// A context that doesn't exist yet is returned with Exists unset; any other failure to read it
+// is returned as an error, since writing without having read the context would replace it.
+func GetExistingContext(ctx context.Context, client ContextsAPI, resourceGroupName, contextName string) (*ExistingContext, error) {
+	loggerFrom(ctx).Debug("Fetching existing context", logKeyResource, contextName)
+
+	// The SDK model has no ETag field, so read it from the raw response header
+	var rawResp *http.Response
+	contextResp, err := client.Get(policy.WithCaptureResponse(ctx, &rawResp), resourceGroupName, contextName, nil)
+	if isNotFound(err) {
+		loggerFrom(ctx).Info("Context not found, a new one will be created", logKeyResource, contextName)
+		return &ExistingContext{Capabilities: []Capability{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting context %s: %w", contextName, err)
+	}
+
+	existing := &ExistingContext{Tags: fromSDKTags(contextResp.Tags), Exists: true}
+	if contextResp.Location != nil {
+		existing.Location = *contextResp.Location
+	}
+	if rawResp != nil {
+		existing.ETag = rawResp.Header.Get("ETag")
+	}
+
+	if contextResp.Properties != nil && contextResp.Properties.Capabilities != nil {
+		for _, cap := range contextResp.Properties.Capabilities {
+			if cap != nil && cap.Name != nil {
+				// Carry the stored description through so re-saving the context doesn't replace it
+				description := ""
+				if cap.Description != nil {
+					description = *cap.Description
+				}
+				if strings.TrimSpace(description) == "" {
+					description = fmt.Sprintf("Existing capability: %s", *cap.Name)
+				}
+				existing.Capabilities = append(existing.Capabilities, Capability{
+					Name:        *cap.Name,
+					Description: description,
+				})
+			}
+		}
+	}
+
+	if contextResp.Properties != nil {
+		for _, hierarchy := range contextResp.Properties.Hierarchies {
+			if hierarchy != nil && hierarchy.Name != nil {
+				existingHierarchy := Hierarchy{Name: *hierarchy.Name}
+				if hierarchy.Description != nil {
+					existingHierarchy.Description = *hierarchy.Description
+				}
+				existing.Hierarchies = append(existing.Hierarchies, existingHierarchy)
+			}
+		}
+	}
+
+	return existing, nil
+}
+
+// Generates a unique manufacturing capability (like "soap-1234" or "shampoo-5678").
+// Each run creates a new capability to demonstrate adding capabilities to contexts.
+// Capabilities represent what a target/facility can manufacture or process.
+// The type and suffix are drawn from source, or from crypto/rand when it is nil.
+func GenerateSingleRandomCapability(source RandomSource) Capability {
+	source = orSecureRandom(source)
+	capabilityTypes := []string{"shampoo", "soap"}
+	capType := capabilityTypes[source.Intn(len(capabilityTypes))]
+	randomSuffix := source.Intn(9000) + 1000
+
+	capability := Capability{
+		Name:        fmt.Sprintf("sdkexamples-%s-%d", capType, randomSuffix),
+		Description: fmt.Sprintf("SDK generated %s manufacturing capability", capType),
+	}
+
+	return capability
+}
+
+// maxCapabilityNameLength is the longest capability name the service accepts.
+const maxCapabilityNameLength = 63
+
+// ValidateCapabilityName checks a capability name against the service's naming rules: 1 to 63
+// ASCII letters, digits, hyphens, underscores and periods, starting and ending with a letter or
+// digit. Capability names end up in resource references, so a bad one is best caught before any
+// request is sent; the error says what to change.
+func ValidateCapabilityName(name string) error {
+	if name == "" {
+		return fmt.Errorf("capability name is empty")
+	}
+	if len(name) > maxCapabilityNameLength {
+		return fmt.Errorf("capability name %q is %d characters long; the limit is %d", name, len(name), maxCapabilityNameLength)
+	}
+	for _, r := range name {
+		if !isASCIIAlphanumeric(r) && r != '-' && r != '_' && r != '.' {
+			return fmt.Errorf("capability name %q contains %q; use only letters, digits, '-', '_' and '.'", name, r)
+		}
+	}
+	if !isASCIIAlphanumeric(rune(name[0])) || !isASCIIAlphanumeric(rune(name[len(name)-1])) {
+		return fmt.Errorf("capability name %q must start and end with a letter or digit", name)
+	}
+	return nil
+}
+
+func isASCIIAlphanumeric(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// CapabilityConflictPolicy controls what happens when a new capability's name is already in the context.
+type CapabilityConflictPolicy string
+
+const (
+	// CapabilityConflictReject keeps the existing capability and drops the new one.
+	CapabilityConflictReject CapabilityConflictPolicy = "reject"
+	// CapabilityConflictOverwriteDescription keeps the existing capability but takes the new description.
+	CapabilityConflictOverwriteDescription CapabilityConflictPolicy = "overwriteDescription"
+	// CapabilityConflictError fails the merge when descriptions differ.
+	CapabilityConflictError CapabilityConflictPolicy = "error"
+)
+
+// ParseCapabilityConflictPolicy validates a policy name, defaulting to reject when empty.
+func ParseCapabilityConflictPolicy(value string) (CapabilityConflictPolicy, error) {
+	switch policy := CapabilityConflictPolicy(value); policy {
+	case "":
+		return CapabilityConflictReject, nil
+	case CapabilityConflictReject, CapabilityConflictOverwriteDescription, CapabilityConflictError:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown capability conflict policy %q (expected %s, %s or %s)",
+			value, CapabilityConflictReject, CapabilityConflictOverwriteDescription, CapabilityConflictError)
+	}
+}
+
+// Safely merges new capabilities with existing ones, avoiding duplicates.
+// Ensures capability names remain unique across the context.
+// Used when updating contexts to add new manufacturing capabilities.
+// Name collisions are resolved according to policy.
+// Every new capability's name is checked with ValidateCapabilityName first; existing ones are
+// already stored by the service and are taken as they are.
+// The result holds the existing capabilities in their order, minus empty names and repeats,
+// followed by each new name not seen before, in the order it first appears. A name repeated
+// within the new set is treated like one already in the context, so no name appears twice.
+func MergeCapabilitiesWithUniqueness(ctx context.Context, existingCapabilities, newCapabilities []Capability, policy CapabilityConflictPolicy) ([]Capability, error) {
+	logger := loggerFrom(ctx)
+	existingNames := make(map[string]int) // name -> index in mergedCapabilities
+	var mergedCapabilities []Capability
+
+	for i, cap := range existingCapabilities {
+		if _, seen := existingNames[cap.Name]; cap.Name != "" && !seen {
+			existingNames[cap.Name] = len(mergedCapabilities)
+			mergedCapabilities = append(mergedCapabilities, cap)
+		} else {
+			logger.Debug("Skipping duplicate or empty existing capability", "index", i, "capability", cap.Name)
+		}
+	}
+
+	for _, cap := range newCapabilities {
+		if err := ValidateCapabilityName(cap.Name); err != nil {
+			return nil, err
+		}
+		index, seen := existingNames[cap.Name]
+		if !seen {
+			existingNames[cap.Name] = len(mergedCapabilities)
+			mergedCapabilities = append(mergedCapabilities, cap)
+			logger.Info("Adding capability to context", "capability", cap.Name)
+			continue
+		}
+
+		existing := &mergedCapabilities[index]
+		if existing.Description == cap.Description {
+			logger.Debug("Capability already in context", "capability", cap.Name)
+			continue
+		}
+
+		switch policy {
+		case CapabilityConflictOverwriteDescription:
+			logger.Info("Updating capability description", "capability", cap.Name, "from", existing.Description, "to", cap.Description)
+			existing.Description = cap.Description
+		case CapabilityConflictError:
+			return nil, fmt.Errorf("capability %s already exists with description %q, refusing to change it to %q",
+				cap.Name, existing.Description, cap.Description)
+		default:
+			logger.Warn("Capability already exists with another description, keeping the existing one", "capability", cap.Name)
+		}
+	}
+
+	logger.Debug("Capabilities merged", "existing", len(existingCapabilities), "new", len(newCapabilities), "merged", len(mergedCapabilities))
+	return mergedCapabilities, nil
+}
+
+// DefaultCapabilitiesFile is where ManageAzureContext saves the merged capabilities unless told otherwise.
+const DefaultCapabilitiesFile = "context-capabilities.json"
+
+// CapabilitiesFile is the layout SaveCapabilitiesToJSON writes: the capabilities together with
+// the context they were saved for and when, so the file describes itself.
+type CapabilitiesFile struct {
+	ContextName  string       `json:"contextName"`
+	SavedAt      time.Time    `json:"savedAt"`
+	Capabilities []Capability `json:"capabilities"`
+}
+
+// SaveCapabilitiesToJSON saves a context's capabilities to a JSON file. The file is replaced
+// atomically, so a crash mid-write never leaves a truncated file behind.
+func SaveCapabilitiesToJSON(contextName string, capabilities []Capability, filename string) error {
+	data, err := json.MarshalIndent(CapabilitiesFile{
+		ContextName:  contextName,
+		SavedAt:      time.Now().UTC(),
+		Capabilities: capabilities,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling capabilities: %w", err)
+	}
+
+	if err := writeFileAtomic(filename, data, 0644); err != nil {
+		return fmt.Errorf("error writing capabilities file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadCapabilitiesFromJSON reads capabilities in the layout SaveCapabilitiesToJSON writes, e.g. a
+// canonical list kept in source control; a bare JSON array of capabilities is accepted too.
+// Every name must pass ValidateCapabilityName, and names must be unique.
+func LoadCapabilitiesFromJSON(filename string) ([]Capability, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading capabilities file: %w", err)
+	}
+
+	var capabilities []Capability
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &capabilities)
+	} else {
+		var file CapabilitiesFile
+		err = json.Unmarshal(data, &file)
+		capabilities = file.Capabilities
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing capabilities file %s: %w", filename, err)
+	}
+
+	seen := make(map[string]bool)
+	for i, cap := range capabilities {
+		if err := ValidateCapabilityName(cap.Name); err != nil {
+			return nil, fmt.Errorf("capability at index %d in %s: %w", i, filename, err)
+		}
+		if seen[cap.Name] {
+			return nil, fmt.Errorf("duplicate capability %s in %s", cap.Name, filename)
+		}
+		seen[cap.Name] = true
+	}
+	return capabilities, nil
+}
+
+// ContextResourceID returns the full ARM resource ID of a context, as referenced by targets.
+func ContextResourceID(subscriptionID, resourceGroupName, contextName string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Edge/contexts/%s", subscriptionID, resourceGroupName, contextName)
+}
+
+// VerifyContextExists checks that a context is there before a target is pointed at it,
+// returning an error that says which context is missing and how to select another.
+// The context is returned so callers can check the target against it, e.g. with ValidateHierarchyLevel.
+func VerifyContextExists(ctx context.Context, client ContextsAPI, resourceGroupName, contextName string) (*armworkloadorchestration.Context, error) {
+	contextResp, err := client.Get(ctx, resourceGroupName, contextName, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("context %s not found in resource group %s; create it first or point the run at an existing context: %w", contextName, resourceGroupName, err)
+		}
+		return nil, fmt.Errorf("error checking context %s: %w", contextName, err)
+	}
+	return &contextResp.Context, nil
+}
+
+// ValidateCapabilities checks that every one of capabilities is listed on the context, so a
+// solution template or target isn't created with a capability the context doesn't know, which
+// the service only rejects steps later. The error lists all the missing capabilities.
+func ValidateCapabilities(contextResource *armworkloadorchestration.Context, capabilities []string) error {
+	known := make(map[string]bool)
+	if contextResource != nil && contextResource.Properties != nil {
+		for _, cap := range contextResource.Properties.Capabilities {
+			if cap != nil && cap.Name != nil {
+				known[*cap.Name] = true
+			}
+		}
+	}
+	var missing []string
+	for _, name := range capabilities {
+		if !known[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	contextName := "context"
+	if contextResource != nil && contextResource.Name != nil {
+		contextName = "context " + *contextResource.Name
+	}
+	return fmt.Errorf("capabilities not found in %s: %s", contextName, strings.Join(missing, ", "))
+}
+
+// VerifyCapabilitiesInContext fetches the context and checks it lists every one of capabilities
+// (see ValidateCapabilities). Call it before CreateSolutionTemplate or CreateTarget to catch a
+// capability the context is missing at the earliest point.
+func VerifyCapabilitiesInContext(ctx context.Context, client ContextsAPI, resourceGroupName, contextName string, capabilities []string) error {
+	contextResource, err := VerifyContextExists(ctx, client, resourceGroupName, contextName)
+	if err != nil {
+		return err
+	}
+	return ValidateCapabilities(contextResource, capabilities)
+}
+
+// Creates or updates an Azure Context with capabilities and organizational hierarchies.
+// Contexts provide centralized coordination of capabilities across multiple targets.
+// Hierarchies define organizational levels (e.g. country -> region -> factory -> line) and are
+// written exactly as given, so callers merge them with the existing ones first.
+// existing is the context as read with GetExistingContext. When the desired state matches it, no
+// write is issued and the stored context is returned with updated set to false. Otherwise its
+// ETag, if any, is sent as If-Match, so the write fails with 412 Precondition Failed if the
+// context changed since it was read; a nil existing always writes, unconditionally.
+// tags replace the context's tags, so callers merge them with the existing ones first.
+// With dryRun set, nothing is submitted and the context that would be written is returned.
+func CreateOrUpdateContextWithHierarchies(ctx context.Context, client ContextsAPI, resourceGroupName, contextName, location string, capabilities []Capability, hierarchies []Hierarchy, tags map[string]string, existing *ExistingContext, dryRun bool) (*armworkloadorchestration.Context, bool, error) {
+	// Create capability objects with name and description; unnamed entries are skipped rather than submitted
+	capabilityObjects := make([]*armworkloadorchestration.Capability, 0, len(capabilities))
+	capabilityNames := make([]string, 0, len(capabilities))
+	for i, cap := range capabilities {
+		if cap.Name == "" {
+			loggerFrom(ctx).Warn("Skipping capability with empty name", "index", i)
+			continue
+		}
+		capabilityNames = append(capabilityNames, cap.Name)
+		capabilityObjects = append(capabilityObjects, &armworkloadorchestration.Capability{
+			Name:        to.Ptr(cap.Name),
+			Description: to.Ptr(cap.Description),
+		})
+	}
+
+	resource := armworkloadorchestration.Context{
+		Location: to.Ptr(location),
+		Tags:     toSDKTags(tags),
+		Properties: &armworkloadorchestration.ContextProperties{
+			Capabilities: capabilityObjects,
+			Hierarchies:  toSDKHierarchies(hierarchies),
+		},
+	}
+
+	// Skip the long-running write, and the ETag change it brings, when there is nothing to change
+	if existing != nil && existing.Exists {
+		changes := contextChanges(existing, capabilities, hierarchies, tags)
+		if len(changes) == 0 {
+			loggerFrom(ctx).Info("Context already up to date", logKeyResource, contextName)
+			if dryRun {
+				resource.ID = to.Ptr(dryRunResourceID(resourceGroupName, "contexts", contextName))
+				resource.Name = to.Ptr(contextName)
+				return &resource, false, nil
+			}
+			contextResp, err := client.Get(ctx, resourceGroupName, contextName, nil)
+			if err != nil {
+				return nil, false, fmt.Errorf("error getting context: %w", err)
+			}
+			return &contextResp.Context, false, nil
+		}
+		loggerFrom(ctx).Debug("Context differs from the desired state", logKeyResource, contextName, "changes", changes)
+	}
+	etag := ""
+	if existing != nil {
+		etag = existing.ETag
+	}
+
+	if dryRun {
+		logDryRun(ctx, "create/update", "Microsoft.Edge/contexts", contextName, map[string]interface{}{
+			"resourceGroup": resourceGroupName,
+			"location":      location,
+			"capabilities":  capabilityNames,
+			"hierarchies":   hierarchyNames(hierarchies),
+			"tags":          tags,
+		})
+		resource.ID = to.Ptr(dryRunResourceID(resourceGroupName, "contexts", contextName))
+		resource.Name = to.Ptr(contextName)
+		return &resource, true, nil
+	}
+
+	contextOperation := func() error {
+		loggerFrom(ctx).Info("Creating or updating context", logKeyResource, contextName)
+		// Only the initial PUT is conditional; the polling and final GET must not carry If-Match
+		writeCtx := ctx
+		if etag != "" {
+			writeCtx = policy.WithHTTPHeader(ctx, http.Header{"If-Match": []string{etag}})
+		}
+		poller, err := client.BeginCreateOrUpdate(writeCtx, resourceGroupName, contextName, resource, nil)
+		if err != nil {
+			return err
+		}
+
+		_, err = pollUntilDone(ctx, poller, "context update", func(ctx context.Context) (*armworkloadorchestration.ProvisioningState, error) {
+			current, err := client.Get(ctx, resourceGroupName, contextName, nil)
+			if err != nil || current.Properties == nil {
+				return nil, err
+			}
+			return current.Properties.ProvisioningState, nil
+		})
+		return err
+	}
+
+	err := retryOperation(ctx, RetryContextUpdate, DefaultRetryPolicy, contextOperation)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating/updating context: %w", err)
+	}
+
+	// Get the created/updated context to return it
+	contextResp, err := client.Get(ctx, resourceGroupName, contextName, nil)
+	if err != nil {
+		return nil, true, fmt.Errorf("error getting created context: %w", err)
+	}
+
+	return &contextResp.Context, true, nil
+}
+
+// contextChanges describes how the desired capabilities, hierarchies and tags differ from the
+// existing context, e.g. "add capability soap-1234"; it is empty when a write would change nothing.
+// Capabilities are compared by name and description regardless of order; hierarchies in order.
+func contextChanges(existing *ExistingContext, capabilities []Capability, hierarchies []Hierarchy, tags map[string]string) []string {
+	var changes []string
+
+	stored := make(map[string]string, len(existing.Capabilities))
+	for _, cap := range existing.Capabilities {
+		stored[cap.Name] = cap.Description
+	}
+	desired := make(map[string]bool, len(capabilities))
+	for _, cap := range capabilities {
+		if cap.Name == "" || desired[cap.Name] {
+			continue
+		}
+		desired[cap.Name] = true
+		description, ok := stored[cap.Name]
+		switch {
+		case !ok:
+			changes = append(changes, "add capability "+cap.Name)
+		case description != cap.Description:
+			changes = append(changes, "change description of capability "+cap.Name)
+		}
+	}
+	for _, cap := range existing.Capabilities {
+		if !desired[cap.Name] {
+			changes = append(changes, "remove capability "+cap.Name)
+		}
+	}
+
+	if !slices.Equal(existing.Hierarchies, hierarchies) {
+		changes = append(changes, fmt.Sprintf("set hierarchies to %s", strings.Join(hierarchyNames(hierarchies), ", ")))
+	}
+	if !maps.Equal(existing.Tags, tags) {
+		changes = append(changes, "update tags")
+	}
+	return changes
+}
+
+// Complete workflow for managing Azure Context capabilities:
+// 1. Generates a new unique capability for this run
+// 2. Fetches existing context and its current capabilities, hierarchies and ETag
+// 3. Merges the seed capabilities and the new capability with existing ones (no duplicates)
+// 4. Saves capability list to capabilitiesFile (DefaultCapabilitiesFile when empty) for reference
+// 5. Updates the context with the merged capability list and hierarchies, unless nothing changed
+// This ensures each run adds a new capability while preserving existing ones.
+// If another run updates the context in between, steps 2-5 are repeated against its new state.
+// The given hierarchies are merged by name into the existing ones rather than replacing them,
+// and tags are added to the context's existing tags.
+// seedCapabilities (e.g. from LoadCapabilitiesFromJSON) are reconciled into the context along with
+// the generated one, so a canonical list can be kept elsewhere; nil adds only the generated one.
+// The name of the capability generated for this run is returned alongside the context.
+// With dryRun set, the existing context is read but neither the JSON file nor the context is written.
+func ManageAzureContext(ctx context.Context, client ContextsAPI, resourceGroupName, contextName, location string, hierarchies []Hierarchy, tags map[string]string, seedCapabilities []Capability, capabilitiesFile string, conflictPolicy CapabilityConflictPolicy, dryRun bool) (*armworkloadorchestration.Context, string, error) {
+	// Step 1: Generate single random capability; it stays the same across re-reads of the context
+	newCapability := GenerateSingleRandomCapability(randomFrom(ctx))
+	loggerFrom(ctx).Info("Generated capability for this run", "capability", newCapability.Name)
+	if len(seedCapabilities) > 0 {
+		loggerFrom(ctx).Info("Reconciling seed capabilities into the context", logKeyResource, contextName, "capabilities", len(seedCapabilities))
+	}
+	newCapabilities := append(append([]Capability{}, seedCapabilities...), newCapability)
+	capabilitiesFile = valueOrDefault(capabilitiesFile, DefaultCapabilitiesFile)
+
+	var contextResult *armworkloadorchestration.Context
+	var updated bool
+	for attempt := 1; ; attempt++ {
+		// Step 2: Fetch existing context
+		// A context that couldn't be read must not be written, or its capabilities would be lost
+		existing, err := GetExistingContext(ctx, client, resourceGroupName, contextName)
+		if err != nil {
+			return nil, "", fmt.Errorf("error fetching existing context: %w", err)
+		}
+
+		// Step 3: Merge capabilities with uniqueness constraints
+		mergedCapabilities, err := MergeCapabilitiesWithUniqueness(ctx, existing.Capabilities, newCapabilities, conflictPolicy)
+		if err != nil {
+			return nil, "", fmt.Errorf("error merging capabilities: %w", err)
+		}
+
+		// Step 4: Save to JSON file; a path that can't be written stops the run before the context is touched
+		if !dryRun {
+			if err := SaveCapabilitiesToJSON(contextName, mergedCapabilities, capabilitiesFile); err != nil {
+				return nil, "", fmt.Errorf("error saving capabilities: %w", err)
+			}
+			loggerFrom(ctx).Debug("Capabilities saved", "file", capabilitiesFile)
+		}
+
+		// Step 5: Create/update context with hierarchies, conditional on the context being unchanged since step 2
+		mergedHierarchies := MergeHierarchies(ctx, existing.Hierarchies, hierarchies)
+		contextResult, updated, err = CreateOrUpdateContextWithHierarchies(ctx, client, resourceGroupName, contextName, location, mergedCapabilities, mergedHierarchies, mergeTags(existing.Tags, tags), existing, dryRun)
+		if isPreconditionFailed(err) && attempt < maxContextUpdateAttempts {
+			loggerFrom(ctx).Info("Context changed since it was read, re-reading and merging again",
+				logKeyResource, contextName, "attempt", attempt+1, "maxAttempts", maxContextUpdateAttempts)
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("error in context management workflow: %w", err)
+		}
+		break
+	}
+
+	if updated {
+		loggerFrom(ctx).Info("Context updated", logKeyResource, *contextResult.Name, logKeyState, provisioningState(contextResult))
+	}
+	return contextResult, newCapability.Name, nil
+}
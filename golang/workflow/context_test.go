@@ -0,0 +1,213 @@
+package workflow
+
+import (
+	"math/rand"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+func TestManageAzureContextKeepsCapabilityDescriptions(t *testing.T) {
+	client := &fakeContexts{t: t, stored: &armworkloadorchestration.Context{
+		Name:     to.Ptr("ctx"),
+		Location: to.Ptr("eastus"),
+		Properties: &armworkloadorchestration.ContextProperties{
+			Capabilities: []*armworkloadorchestration.Capability{
+				{Name: to.Ptr("soap"), Description: to.Ptr("Liquid soap line")},
+				{Name: to.Ptr("shampoo")},
+			},
+		},
+	}}
+
+	_, added, err := ManageAzureContext(testContext(), client, "rg", "ctx", "eastus", nil, nil, nil, filepath.Join(t.TempDir(), "capabilities.json"), CapabilityConflictReject, false)
+	if err != nil {
+		t.Fatalf("ManageAzureContext: %v", err)
+	}
+	if len(client.written) != 1 {
+		t.Fatalf("context written %d times, want 1", len(client.written))
+	}
+
+	descriptions := map[string]string{}
+	for _, capability := range client.written[0].Properties.Capabilities {
+		descriptions[*capability.Name] = *capability.Description
+	}
+	want := map[string]string{
+		"soap":    "Liquid soap line",
+		"shampoo": "Existing capability: shampoo",
+	}
+	for name, description := range want {
+		if descriptions[name] != description {
+			t.Errorf("description of %s = %q, want %q", name, descriptions[name], description)
+		}
+	}
+	if _, ok := descriptions[added]; !ok {
+		t.Errorf("generated capability %s was not written", added)
+	}
+
+	// Reading the written context back returns the same descriptions
+	existing, err := GetExistingContext(testContext(), client, "rg", "ctx")
+	if err != nil {
+		t.Fatalf("GetExistingContext: %v", err)
+	}
+	for _, capability := range existing.Capabilities {
+		if want, ok := want[capability.Name]; ok && capability.Description != want {
+			t.Errorf("read back description of %s = %q, want %q", capability.Name, capability.Description, want)
+		}
+	}
+}
+
+func TestManageAzureContextDoesNotWriteWhenReadFails(t *testing.T) {
+	for _, readErr := range []error{
+		responseError(http.StatusForbidden, "AuthorizationFailed", "no read access"),
+		responseError(http.StatusTooManyRequests, "Throttled", "too many requests"),
+		responseError(http.StatusInternalServerError, "InternalServerError", "try again"),
+	} {
+		client := &fakeContexts{t: t, getErr: readErr}
+		if _, _, err := ManageAzureContext(testContext(), client, "rg", "ctx", "eastus", nil, nil, nil, filepath.Join(t.TempDir(), "capabilities.json"), CapabilityConflictReject, false); err == nil {
+			t.Errorf("ManageAzureContext succeeded after %v, want an error", readErr)
+		}
+		if len(client.written) != 0 {
+			t.Errorf("context written after a failed read (%v)", readErr)
+		}
+	}
+}
+
+func TestGetExistingContextNotFound(t *testing.T) {
+	existing, err := GetExistingContext(testContext(), &fakeContexts{t: t}, "rg", "ctx")
+	if err != nil {
+		t.Fatalf("GetExistingContext: %v", err)
+	}
+	if existing.Exists || len(existing.Capabilities) != 0 {
+		t.Errorf("missing context read as %+v, want an empty context that doesn't exist", existing)
+	}
+}
+
+func TestMergeCapabilitiesWithUniqueness(t *testing.T) {
+	soap := Capability{Name: "soap", Description: "Soap line"}
+	shampoo := Capability{Name: "shampoo", Description: "Shampoo line"}
+	lotion := Capability{Name: "lotion", Description: "Lotion line"}
+
+	tests := []struct {
+		name     string
+		existing []Capability
+		new      []Capability
+		policy   CapabilityConflictPolicy
+		want     []Capability
+		wantErr  bool
+	}{
+		{
+			name: "empty existing",
+			new:  []Capability{soap, shampoo},
+			want: []Capability{soap, shampoo},
+		},
+		{
+			name:     "nothing new",
+			existing: []Capability{soap},
+			want:     []Capability{soap},
+		},
+		{
+			name: "duplicate new names keep the first",
+			new:  []Capability{soap, shampoo, {Name: "soap", Description: "Other soap"}},
+			want: []Capability{soap, shampoo},
+		},
+		{
+			name:     "empty and repeated existing names are dropped",
+			existing: []Capability{{Name: "", Description: "unnamed"}, soap, soap, {Name: ""}},
+			new:      []Capability{shampoo},
+			want:     []Capability{soap, shampoo},
+		},
+		{
+			name:    "empty new name is rejected",
+			new:     []Capability{soap, {Name: "", Description: "unnamed"}},
+			wantErr: true,
+		},
+		{
+			name:     "overlap keeps existing order and appends new names",
+			existing: []Capability{shampoo, soap},
+			new:      []Capability{lotion, soap},
+			want:     []Capability{shampoo, soap, lotion},
+		},
+		{
+			name:     "overlap with another description keeps the existing one",
+			existing: []Capability{soap},
+			new:      []Capability{{Name: "soap", Description: "New soap"}},
+			policy:   CapabilityConflictReject,
+			want:     []Capability{soap},
+		},
+		{
+			name:     "overlap with another description overwrites it",
+			existing: []Capability{soap, shampoo},
+			new:      []Capability{{Name: "soap", Description: "New soap"}},
+			policy:   CapabilityConflictOverwriteDescription,
+			want:     []Capability{{Name: "soap", Description: "New soap"}, shampoo},
+		},
+		{
+			name:     "overlap with another description fails",
+			existing: []Capability{soap},
+			new:      []Capability{{Name: "soap", Description: "New soap"}},
+			policy:   CapabilityConflictError,
+			wantErr:  true,
+		},
+		{
+			name:     "overlap with the same description is not a conflict",
+			existing: []Capability{soap},
+			new:      []Capability{soap},
+			policy:   CapabilityConflictError,
+			want:     []Capability{soap},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeCapabilitiesWithUniqueness(testContext(), tt.existing, tt.new, tt.policy)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("merge = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("merge: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("merge = %v, want %v", got, tt.want)
+			}
+			seen := map[string]bool{}
+			for _, capability := range got {
+				if capability.Name == "" {
+					t.Errorf("merge kept an unnamed capability: %v", got)
+				}
+				if seen[capability.Name] {
+					t.Errorf("merge kept %s twice: %v", capability.Name, got)
+				}
+				seen[capability.Name] = true
+			}
+		})
+	}
+}
+
+func TestGenerateSingleRandomCapabilityFormat(t *testing.T) {
+	pattern := regexp.MustCompile(`^sdkexamples-(soap|shampoo)-(\d{4})$`)
+	source := rand.New(rand.NewSource(42))
+	for i := 0; i < 500; i++ {
+		capability := GenerateSingleRandomCapability(source)
+		match := pattern.FindStringSubmatch(capability.Name)
+		if match == nil {
+			t.Fatalf("capability name %q doesn't match %s", capability.Name, pattern)
+		}
+		if suffix, _ := strconv.Atoi(match[2]); suffix < 1000 || suffix > 9999 {
+			t.Errorf("capability name %q: suffix outside 1000-9999", capability.Name)
+		}
+		if want := "SDK generated " + match[1] + " manufacturing capability"; capability.Description != want {
+			t.Errorf("description = %q, want %q", capability.Description, want)
+		}
+		if err := ValidateCapabilityName(capability.Name); err != nil {
+			t.Errorf("generated name is not valid: %v", err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package workflow
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// CredentialSource selects how the workflow authenticates to Azure.
+type CredentialSource string
+
+const (
+	// CredentialDefault tries the DefaultAzureCredential chain (environment, workload identity, managed identity, CLI, ...).
+	CredentialDefault CredentialSource = ""
+	// CredentialEnvironment reads a service principal from AZURE_CLIENT_ID, AZURE_TENANT_ID and AZURE_CLIENT_SECRET (or a certificate).
+	CredentialEnvironment CredentialSource = "environment"
+	// CredentialManagedIdentity uses the host's managed identity; a client ID selects a user-assigned identity.
+	CredentialManagedIdentity CredentialSource = "managed-identity"
+	// CredentialAzureCLI reuses the account signed in with `az login`.
+	CredentialAzureCLI CredentialSource = "azure-cli"
+	// CredentialWorkloadIdentity exchanges a federated token, as configured by AZURE_FEDERATED_TOKEN_FILE and AZURE_TENANT_ID.
+	CredentialWorkloadIdentity CredentialSource = "workload-identity"
+)
+
+// ParseCredentialSource validates a credential source name; empty selects the default chain.
+func ParseCredentialSource(value string) (CredentialSource, error) {
+	switch source := CredentialSource(value); source {
+	case CredentialDefault, CredentialEnvironment, CredentialManagedIdentity, CredentialAzureCLI, CredentialWorkloadIdentity:
+		return source, nil
+	default:
+		return "", fmt.Errorf("unknown credential source %q (valid: %s, %s, %s, %s)", value,
+			CredentialEnvironment, CredentialManagedIdentity, CredentialAzureCLI, CredentialWorkloadIdentity)
+	}
+}
+
+// NewCredential builds the credential for source, authenticating against cloudConfig's authority.
+// clientID picks a user-assigned managed identity or overrides AZURE_CLIENT_ID for workload identity;
+// it is ignored by the other sources. Token requests go through httpClient, e.g. one built on
+// NewHTTPTransport to use a proxy; the SDK's default client is used when it is nil.
+func NewCredential(source CredentialSource, clientID string, cloudConfig cloud.Configuration, httpClient *http.Client) (azcore.TokenCredential, error) {
+	clientOptions := azcore.ClientOptions{Cloud: cloudConfig}
+	if httpClient != nil {
+		clientOptions.Transport = httpClient
+	}
+	switch source {
+	case CredentialEnvironment:
+		return azidentity.NewEnvironmentCredential(&azidentity.EnvironmentCredentialOptions{ClientOptions: clientOptions})
+	case CredentialManagedIdentity:
+		options := &azidentity.ManagedIdentityCredentialOptions{ClientOptions: clientOptions}
+		if clientID != "" {
+			options.ID = azidentity.ClientID(clientID)
+		}
+		return azidentity.NewManagedIdentityCredential(options)
+	case CredentialAzureCLI:
+		// The CLI authenticates against whichever cloud `az cloud set` selected, through its own
+		// connection, which follows HTTPS_PROXY but not httpClient
+		return azidentity.NewAzureCLICredential(nil)
+	case CredentialWorkloadIdentity:
+		return azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{ClientOptions: clientOptions, ClientID: clientID})
+	case CredentialDefault:
+		return azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{ClientOptions: clientOptions})
+	default:
+		return nil, fmt.Errorf("unknown credential source %q", source)
+	}
+}
@@ -0,0 +1,35 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// dryRunSubscriptionID stands in for the subscription in resource IDs synthesized during a dry run.
+const dryRunSubscriptionID = "00000000-0000-0000-0000-000000000000"
+
+// dryRunResourceID builds a plausible Microsoft.Edge resource ID for a resource a dry run did not create,
+// so later steps that reference it by ID can still be walked.
+// segments alternate resource type and name, e.g. "schemas", name, "versions", version.
+func dryRunResourceID(resourceGroupName string, segments ...string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Edge/%s", dryRunSubscriptionID, resourceGroupName, strings.Join(segments, "/"))
+}
+
+// logDryRun logs the operation a step would have submitted, with its key properties in a stable order.
+func logDryRun(ctx context.Context, action, resourceType, name string, properties map[string]interface{}) {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attrs := make([]any, 0, len(keys))
+	for _, key := range keys {
+		attrs = append(attrs, slog.Any(key, properties[key]))
+	}
+
+	loggerFrom(ctx).Info("Dry run: operation not submitted",
+		"action", action, "resourceType", resourceType, logKeyResource, name, slog.Group("properties", attrs...))
+}
@@ -0,0 +1,128 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// isNotFound reports whether err is an Azure response error with HTTP status 404.
+func isNotFound(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// isPreconditionFailed reports whether err is an Azure response error with HTTP status 412,
+// returned when an If-Match ETag no longer matches the resource.
+func isPreconditionFailed(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// IsTransient reports whether err looks like a passing service condition rather than a defect:
+// throttling (429), a request timeout (408), a server error (5xx) from the SDK or the
+// Configuration API, or an operation that ran past its deadline. Rerunning may succeed.
+func IsTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return isTransientStatus(respErr.StatusCode)
+	}
+	var armErr *ARMError
+	if errors.As(err, &armErr) {
+		return isTransientStatus(armErr.StatusCode)
+	}
+	return false
+}
+
+func isTransientStatus(status int) bool {
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
+
+// WorkflowError identifies the workflow step, and the resource it was acting on, behind a failed run.
+// The underlying cause, typically an *azcore.ResponseError, stays reachable through errors.As.
+type WorkflowError struct {
+	Step     string
+	Resource string
+	Err      error
+}
+
+func (e *WorkflowError) Error() string {
+	if e.Resource == "" {
+		return fmt.Sprintf("step %s: %v", e.Step, e.Err)
+	}
+	return fmt.Sprintf("step %s (%s): %v", e.Step, e.Resource, e.Err)
+}
+
+func (e *WorkflowError) Unwrap() error {
+	return e.Err
+}
+
+// RollbackError reports an install that failed and was rolled back to the solution version
+// deployed before it. RollbackErr is set when reinstalling that version failed too.
+type RollbackError struct {
+	FailedVersion string
+	RolledBackTo  string
+	Err           error
+	RollbackErr   error
+}
+
+func (e *RollbackError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("install of %s failed: %v; rollback to %s also failed: %v", e.FailedVersion, e.Err, e.RolledBackTo, e.RollbackErr)
+	}
+	return fmt.Sprintf("install of %s failed, rolled back to %s: %v", e.FailedVersion, e.RolledBackTo, e.Err)
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// ARMError is the standard Azure Resource Manager error envelope,
+// {"error": {"code": ..., "message": ...}}, returned by the REST calls made outside the SDK.
+type ARMError struct {
+	StatusCode int
+	Code       string // e.g. ResourceNotFound, Throttled
+	Message    string
+	Details    []ARMErrorDetail
+	RawBody    string // The unparsed response body, kept for debugging
+}
+
+// ARMErrorDetail is one entry of an ARM error's details list.
+type ARMErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ARMError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.RawBody)
+	}
+	return fmt.Sprintf("request failed with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// parseARMError builds an ARMError from a failed response. A body that isn't an ARM error
+// envelope still yields an ARMError carrying the status code and raw body.
+func parseARMError(statusCode int, body []byte) *ARMError {
+	armErr := &ARMError{StatusCode: statusCode, RawBody: string(body)}
+
+	var envelope struct {
+		Error struct {
+			Code    string           `json:"code"`
+			Message string           `json:"message"`
+			Details []ARMErrorDetail `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		armErr.Code = envelope.Error.Code
+		armErr.Message = envelope.Error.Message
+		armErr.Details = envelope.Error.Details
+	}
+	return armErr
+}
@@ -0,0 +1,38 @@
+package workflow
+
+import (
+	"context"
+	"time"
+)
+
+// StepEventKind says what happened to a step.
+type StepEventKind string
+
+const (
+	StepEventStarted   StepEventKind = "started"
+	StepEventSucceeded StepEventKind = "succeeded"
+	StepEventFailed    StepEventKind = "failed"
+)
+
+// StepEvent reports progress of one workflow step, e.g. for a UI following a run (see Options.Events).
+type StepEvent struct {
+	Kind     StepEventKind
+	Step     string // e.g. CreateTarget; the same names as WorkflowError.Step
+	Resource string
+	Time     time.Time
+	Duration time.Duration // How long the step took; zero for StepEventStarted
+	Err      error         // Why the step failed; nil unless Kind is StepEventFailed
+}
+
+// publishEvent sends event on events without blocking. When the channel has no room the event
+// is dropped, so a consumer that falls behind never holds up the run. A nil channel is ignored.
+func publishEvent(ctx context.Context, events chan<- StepEvent, event StepEvent) {
+	if events == nil {
+		return
+	}
+	select {
+	case events <- event:
+	default:
+		loggerFrom(ctx).Debug("Dropped step event; the consumer is not keeping up", logKeyStep, event.Step, "kind", event.Kind)
+	}
+}
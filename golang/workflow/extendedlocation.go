@@ -0,0 +1,78 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// DefaultCustomLocationID is the custom location of the sample's Arc-enabled cluster.
+// It only exists in the sample's subscription; point Options.ExtendedLocation at your own.
+const DefaultCustomLocationID = "/subscriptions/973d15c6-6c57-447e-b9c6-6d79b5b784ab/resourceGroups/configmanager-cloudtest-playground-portal/providers/Microsoft.ExtendedLocation/customLocations/den-Location"
+
+const customLocationResourceType = "Microsoft.ExtendedLocation/customLocations"
+
+// ExtendedLocation is where a target's workloads run: a custom location resource ID, or an edge zone name.
+type ExtendedLocation struct {
+	Name string
+	Type armworkloadorchestration.ExtendedLocationType
+}
+
+// DefaultExtendedLocation returns the sample's custom location.
+func DefaultExtendedLocation() ExtendedLocation {
+	return ExtendedLocation{
+		Name: DefaultCustomLocationID,
+		Type: armworkloadorchestration.ExtendedLocationTypeCustomLocation,
+	}
+}
+
+// ParseExtendedLocationType validates a type name (case-insensitive), defaulting to CustomLocation when empty.
+func ParseExtendedLocationType(value string) (armworkloadorchestration.ExtendedLocationType, error) {
+	if value == "" {
+		return armworkloadorchestration.ExtendedLocationTypeCustomLocation, nil
+	}
+	for _, locationType := range armworkloadorchestration.PossibleExtendedLocationTypeValues() {
+		if strings.EqualFold(value, string(locationType)) {
+			return locationType, nil
+		}
+	}
+	return "", fmt.Errorf("unknown extended location type %q (expected %s or %s)", value,
+		armworkloadorchestration.ExtendedLocationTypeCustomLocation, armworkloadorchestration.ExtendedLocationTypeEdgeZone)
+}
+
+// Validate checks the extended location is well formed: a custom location must be the full
+// resource ID of a Microsoft.ExtendedLocation/customLocations resource. It does not check
+// that the custom location exists.
+func (l ExtendedLocation) Validate() error {
+	if l.Name == "" {
+		return fmt.Errorf("extended location name is empty")
+	}
+	switch l.Type {
+	case armworkloadorchestration.ExtendedLocationTypeCustomLocation:
+		id, err := arm.ParseResourceID(l.Name)
+		if err != nil {
+			return fmt.Errorf("custom location %q is not a valid resource ID: %w", l.Name, err)
+		}
+		if !strings.EqualFold(id.ResourceType.String(), customLocationResourceType) {
+			return fmt.Errorf("custom location %q is a %s, expected a %s resource ID", l.Name, id.ResourceType, customLocationResourceType)
+		}
+	case armworkloadorchestration.ExtendedLocationTypeEdgeZone:
+		if strings.Contains(l.Name, "/") {
+			return fmt.Errorf("edge zone %q should be a zone name, not a resource ID", l.Name)
+		}
+	default:
+		return fmt.Errorf("unknown extended location type %q", l.Type)
+	}
+	return nil
+}
+
+// toSDK converts the extended location to the SDK model.
+func (l ExtendedLocation) toSDK() *armworkloadorchestration.ExtendedLocation {
+	return &armworkloadorchestration.ExtendedLocation{
+		Name: to.Ptr(l.Name),
+		Type: to.Ptr(l.Type),
+	}
+}
@@ -0,0 +1,296 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// errNotFaked is returned by fake client methods a test didn't set up.
+var errNotFaked = errors.New("not faked")
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name, or rewrites the file when run with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run with -update to accept it):\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+// testContext returns a context whose workflow logs are discarded.
+func testContext() context.Context {
+	return WithLogger(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// fakeResponse is an HTTP response with a JSON body, as the service would send it.
+func fakeResponse(method string, status int, body any) *http.Response {
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, "https://management.azure.com/fake", nil)
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(data)),
+		Request:    req,
+	}
+}
+
+// donePoller returns a poller for an operation that has already finished with result.
+func donePoller[T any](t *testing.T, result any) *runtime.Poller[T] {
+	t.Helper()
+	pipeline := runtime.NewPipeline("fake", "v0.0.0", runtime.PipelineOptions{}, &policy.ClientOptions{})
+	poller, err := runtime.NewPoller[T](fakeResponse(http.MethodPut, http.StatusOK, result), pipeline, nil)
+	if err != nil {
+		t.Fatalf("creating poller: %v", err)
+	}
+	return poller
+}
+
+// responseError is the *azcore.ResponseError the SDK returns for a failed request.
+func responseError(status int, code, message string) error {
+	return runtime.NewResponseError(fakeResponse(http.MethodPut, status, map[string]any{
+		"error": map[string]any{"code": code, "message": message},
+	}))
+}
+
+// fakeTargets is a TargetsAPI whose methods run the funcs a test sets; the others fail.
+type fakeTargets struct {
+	createOrUpdate func(targetName string, resource armworkloadorchestration.Target) (*runtime.Poller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse], error)
+	get            func(targetName string) (armworkloadorchestration.TargetsClientGetResponse, error)
+	review         func(body armworkloadorchestration.SolutionTemplateParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientReviewSolutionVersionResponse], error)
+	resolve        func(body armworkloadorchestration.SolutionTemplateParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientResolveConfigurationResponse], error)
+	publish        func(body armworkloadorchestration.SolutionVersionParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientPublishSolutionVersionResponse], error)
+	install        func(body armworkloadorchestration.InstallSolutionParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientInstallSolutionResponse], error)
+}
+
+func (f *fakeTargets) BeginCreateOrUpdate(ctx context.Context, resourceGroupName string, targetName string, resource armworkloadorchestration.Target, options *armworkloadorchestration.TargetsClientBeginCreateOrUpdateOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse], error) {
+	if f.createOrUpdate == nil {
+		return nil, errNotFaked
+	}
+	return f.createOrUpdate(targetName, resource)
+}
+
+func (f *fakeTargets) BeginDelete(ctx context.Context, resourceGroupName string, targetName string, options *armworkloadorchestration.TargetsClientBeginDeleteOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientDeleteResponse], error) {
+	return nil, errNotFaked
+}
+
+func (f *fakeTargets) Get(ctx context.Context, resourceGroupName string, targetName string, options *armworkloadorchestration.TargetsClientGetOptions) (armworkloadorchestration.TargetsClientGetResponse, error) {
+	if f.get == nil {
+		return armworkloadorchestration.TargetsClientGetResponse{}, errNotFaked
+	}
+	return f.get(targetName)
+}
+
+func (f *fakeTargets) BeginReviewSolutionVersion(ctx context.Context, resourceGroupName string, targetName string, body armworkloadorchestration.SolutionTemplateParameter, options *armworkloadorchestration.TargetsClientBeginReviewSolutionVersionOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientReviewSolutionVersionResponse], error) {
+	if f.review == nil {
+		return nil, errNotFaked
+	}
+	return f.review(body)
+}
+
+func (f *fakeTargets) BeginResolveConfiguration(ctx context.Context, resourceGroupName string, targetName string, body armworkloadorchestration.SolutionTemplateParameter, options *armworkloadorchestration.TargetsClientBeginResolveConfigurationOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientResolveConfigurationResponse], error) {
+	if f.resolve == nil {
+		return nil, errNotFaked
+	}
+	return f.resolve(body)
+}
+
+func (f *fakeTargets) BeginPublishSolutionVersion(ctx context.Context, resourceGroupName string, targetName string, body armworkloadorchestration.SolutionVersionParameter, options *armworkloadorchestration.TargetsClientBeginPublishSolutionVersionOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientPublishSolutionVersionResponse], error) {
+	if f.publish == nil {
+		return nil, errNotFaked
+	}
+	return f.publish(body)
+}
+
+func (f *fakeTargets) BeginInstallSolution(ctx context.Context, resourceGroupName string, targetName string, body armworkloadorchestration.InstallSolutionParameter, options *armworkloadorchestration.TargetsClientBeginInstallSolutionOptions) (*runtime.Poller[armworkloadorchestration.TargetsClientInstallSolutionResponse], error) {
+	if f.install == nil {
+		return nil, errNotFaked
+	}
+	return f.install(body)
+}
+
+func (f *fakeTargets) NewListByResourceGroupPager(resourceGroupName string, options *armworkloadorchestration.TargetsClientListByResourceGroupOptions) *runtime.Pager[armworkloadorchestration.TargetsClientListByResourceGroupResponse] {
+	return nil
+}
+
+// targetInState is a target named name in provisioning state.
+func targetInState(name string, state armworkloadorchestration.ProvisioningState) armworkloadorchestration.Target {
+	return armworkloadorchestration.Target{
+		Name:       &name,
+		Properties: &armworkloadorchestration.TargetProperties{ProvisioningState: &state},
+	}
+}
+
+// fakeContexts is a ContextsAPI backed by a single stored context; BeginCreateOrUpdate replaces it.
+type fakeContexts struct {
+	t       *testing.T
+	stored  *armworkloadorchestration.Context
+	getErr  error                              // Returned by Get instead of the stored context when set
+	written []armworkloadorchestration.Context // Every context submitted, in order
+}
+
+func (f *fakeContexts) BeginCreateOrUpdate(ctx context.Context, resourceGroupName string, contextName string, resource armworkloadorchestration.Context, options *armworkloadorchestration.ContextsClientBeginCreateOrUpdateOptions) (*runtime.Poller[armworkloadorchestration.ContextsClientCreateOrUpdateResponse], error) {
+	f.written = append(f.written, resource)
+	resource.Name = &contextName
+	f.stored = &resource
+	return donePoller[armworkloadorchestration.ContextsClientCreateOrUpdateResponse](f.t, resource), nil
+}
+
+func (f *fakeContexts) Get(ctx context.Context, resourceGroupName string, contextName string, options *armworkloadorchestration.ContextsClientGetOptions) (armworkloadorchestration.ContextsClientGetResponse, error) {
+	if f.getErr != nil {
+		return armworkloadorchestration.ContextsClientGetResponse{}, f.getErr
+	}
+	if f.stored == nil {
+		return armworkloadorchestration.ContextsClientGetResponse{}, responseError(http.StatusNotFound, "ResourceNotFound", "context not found")
+	}
+	return armworkloadorchestration.ContextsClientGetResponse{Context: *f.stored}, nil
+}
+
+func (f *fakeContexts) NewListByResourceGroupPager(resourceGroupName string, options *armworkloadorchestration.ContextsClientListByResourceGroupOptions) *runtime.Pager[armworkloadorchestration.ContextsClientListByResourceGroupResponse] {
+	return nil
+}
+
+// onePage is a pager that returns page and stops.
+func onePage[T any](page T) *runtime.Pager[T] {
+	return runtime.NewPager(runtime.PagingHandler[T]{
+		More:    func(T) bool { return false },
+		Fetcher: func(context.Context, *T) (T, error) { return page, nil },
+	})
+}
+
+// fakeSolutions is a SolutionsAPI listing the solutions in names.
+type fakeSolutions struct {
+	names []string
+}
+
+func (f *fakeSolutions) Get(ctx context.Context, resourceGroupName string, targetName string, solutionName string, options *armworkloadorchestration.SolutionsClientGetOptions) (armworkloadorchestration.SolutionsClientGetResponse, error) {
+	return armworkloadorchestration.SolutionsClientGetResponse{}, errNotFaked
+}
+
+func (f *fakeSolutions) NewListByTargetPager(resourceGroupName string, targetName string, options *armworkloadorchestration.SolutionsClientListByTargetOptions) *runtime.Pager[armworkloadorchestration.SolutionsClientListByTargetResponse] {
+	var page armworkloadorchestration.SolutionsClientListByTargetResponse
+	for _, name := range f.names {
+		page.Value = append(page.Value, &armworkloadorchestration.Solution{Name: to.Ptr(name)})
+	}
+	return onePage(page)
+}
+
+// fakeSolutionVersions is a SolutionVersionsAPI listing the versions stored per solution name.
+type fakeSolutionVersions struct {
+	versions map[string][]*armworkloadorchestration.SolutionVersion
+}
+
+func (f *fakeSolutionVersions) Get(ctx context.Context, resourceGroupName string, targetName string, solutionName string, solutionVersionName string, options *armworkloadorchestration.SolutionVersionsClientGetOptions) (armworkloadorchestration.SolutionVersionsClientGetResponse, error) {
+	for _, version := range f.versions[solutionName] {
+		if stringValue(version.Name) == solutionVersionName {
+			return armworkloadorchestration.SolutionVersionsClientGetResponse{SolutionVersion: *version}, nil
+		}
+	}
+	return armworkloadorchestration.SolutionVersionsClientGetResponse{}, responseError(http.StatusNotFound, "ResourceNotFound", "solution version not found")
+}
+
+func (f *fakeSolutionVersions) NewListBySolutionPager(resourceGroupName string, targetName string, solutionName string, options *armworkloadorchestration.SolutionVersionsClientListBySolutionOptions) *runtime.Pager[armworkloadorchestration.SolutionVersionsClientListBySolutionResponse] {
+	var page armworkloadorchestration.SolutionVersionsClientListBySolutionResponse
+	page.Value = f.versions[solutionName]
+	return onePage(page)
+}
+
+// solutionVersionInState is version name of solution on target "target" in state.
+func solutionVersionInState(solution, name string, state armworkloadorchestration.State) *armworkloadorchestration.SolutionVersion {
+	return &armworkloadorchestration.SolutionVersion{
+		ID:         to.Ptr(testSolutionVersionID(solution, name)),
+		Name:       to.Ptr(name),
+		Properties: &armworkloadorchestration.SolutionVersionProperties{State: to.Ptr(state)},
+	}
+}
+
+// testSolutionVersionID is the resource ID of version name of solution on target "target".
+func testSolutionVersionID(solution, name string) string {
+	return "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Edge/targets/target/solutions/" + solution + "/versions/" + name
+}
+
+// fakeSchemaVersions is a SchemaVersionsAPI listing the versions named in names, or failing
+// the listing with listErr when set.
+type fakeSchemaVersions struct {
+	names   []string
+	listErr error
+}
+
+func (f *fakeSchemaVersions) BeginCreateOrUpdate(ctx context.Context, resourceGroupName string, schemaName string, schemaVersionName string, resource armworkloadorchestration.SchemaVersion, options *armworkloadorchestration.SchemaVersionsClientBeginCreateOrUpdateOptions) (*runtime.Poller[armworkloadorchestration.SchemaVersionsClientCreateOrUpdateResponse], error) {
+	return nil, errNotFaked
+}
+
+func (f *fakeSchemaVersions) BeginDelete(ctx context.Context, resourceGroupName string, schemaName string, schemaVersionName string, options *armworkloadorchestration.SchemaVersionsClientBeginDeleteOptions) (*runtime.Poller[armworkloadorchestration.SchemaVersionsClientDeleteResponse], error) {
+	return nil, errNotFaked
+}
+
+func (f *fakeSchemaVersions) Get(ctx context.Context, resourceGroupName string, schemaName string, schemaVersionName string, options *armworkloadorchestration.SchemaVersionsClientGetOptions) (armworkloadorchestration.SchemaVersionsClientGetResponse, error) {
+	return armworkloadorchestration.SchemaVersionsClientGetResponse{}, errNotFaked
+}
+
+func (f *fakeSchemaVersions) NewListBySchemaPager(resourceGroupName string, schemaName string, options *armworkloadorchestration.SchemaVersionsClientListBySchemaOptions) *runtime.Pager[armworkloadorchestration.SchemaVersionsClientListBySchemaResponse] {
+	return runtime.NewPager(runtime.PagingHandler[armworkloadorchestration.SchemaVersionsClientListBySchemaResponse]{
+		More: func(armworkloadorchestration.SchemaVersionsClientListBySchemaResponse) bool { return false },
+		Fetcher: func(context.Context, *armworkloadorchestration.SchemaVersionsClientListBySchemaResponse) (armworkloadorchestration.SchemaVersionsClientListBySchemaResponse, error) {
+			var page armworkloadorchestration.SchemaVersionsClientListBySchemaResponse
+			if f.listErr != nil {
+				return page, f.listErr
+			}
+			for _, name := range f.names {
+				page.Value = append(page.Value, &armworkloadorchestration.SchemaVersion{Name: to.Ptr(name)})
+			}
+			return page, nil
+		},
+	})
+}
+
+// fakeSolutionTemplateVersions is a SolutionTemplateVersionsAPI holding the versions stored per
+// solution template name.
+type fakeSolutionTemplateVersions struct {
+	versions map[string][]*armworkloadorchestration.SolutionTemplateVersion
+	gets     int // Calls to Get
+}
+
+func (f *fakeSolutionTemplateVersions) Get(ctx context.Context, resourceGroupName string, solutionTemplateName string, solutionTemplateVersionName string, options *armworkloadorchestration.SolutionTemplateVersionsClientGetOptions) (armworkloadorchestration.SolutionTemplateVersionsClientGetResponse, error) {
+	f.gets++
+	for _, version := range f.versions[solutionTemplateName] {
+		if stringValue(version.Name) == solutionTemplateVersionName {
+			return armworkloadorchestration.SolutionTemplateVersionsClientGetResponse{SolutionTemplateVersion: *version}, nil
+		}
+	}
+	return armworkloadorchestration.SolutionTemplateVersionsClientGetResponse{}, responseError(http.StatusNotFound, "ResourceNotFound", "solution template version not found")
+}
+
+func (f *fakeSolutionTemplateVersions) NewListBySolutionTemplatePager(resourceGroupName string, solutionTemplateName string, options *armworkloadorchestration.SolutionTemplateVersionsClientListBySolutionTemplateOptions) *runtime.Pager[armworkloadorchestration.SolutionTemplateVersionsClientListBySolutionTemplateResponse] {
+	var page armworkloadorchestration.SolutionTemplateVersionsClientListBySolutionTemplateResponse
+	page.Value = f.versions[solutionTemplateName]
+	return onePage(page)
+}
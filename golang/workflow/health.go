@@ -0,0 +1,101 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// Config value keys that switch the post-install health check on and point it at the solution
+	healthCheckEnabledKey  = "HealthCheckEnabled"
+	healthCheckEndpointKey = "HealthCheckEndpoint"
+
+	// maxHealthCheckInterval caps the backoff between health probes
+	maxHealthCheckInterval = 30 * time.Second
+)
+
+// HealthCheckError reports a solution that never answered its health endpoint with 200 OK.
+// It carries the last status seen, or the last transport error when no response came back.
+type HealthCheckError struct {
+	Endpoint   string
+	Timeout    time.Duration
+	StatusCode int    // 0 when the endpoint never responded
+	Body       string // The start of the last response body, for diagnostics
+	Err        error  // The last transport error, if any
+}
+
+func (e *HealthCheckError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s did not become healthy within %s: %v", e.Endpoint, e.Timeout, e.Err)
+	}
+	if e.Body == "" {
+		return fmt.Sprintf("%s did not become healthy within %s: last status %d", e.Endpoint, e.Timeout, e.StatusCode)
+	}
+	return fmt.Sprintf("%s did not become healthy within %s: last status %d: %s", e.Endpoint, e.Timeout, e.StatusCode, e.Body)
+}
+
+func (e *HealthCheckError) Unwrap() error {
+	return e.Err
+}
+
+// WaitForHealthy polls endpoint with GET requests until one returns 200 OK or timeout passes.
+// The wait between probes starts at a second and doubles up to 30 seconds. A nil httpClient
+// uses the shared client. On timeout the error is a *HealthCheckError with the last status seen.
+func WaitForHealthy(ctx context.Context, httpClient *http.Client, endpoint string, timeout time.Duration) error {
+	client := httpClientOrDefault(httpClient)
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	lastErr := &HealthCheckError{Endpoint: endpoint, Timeout: timeout}
+	interval := time.Second
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(waitCtx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return fmt.Errorf("error creating health check request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err == nil {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				loggerFrom(ctx).Info("Solution is healthy", "endpoint", endpoint, "attempts", attempt)
+				return nil
+			}
+			lastErr.StatusCode, lastErr.Body, lastErr.Err = resp.StatusCode, string(body), nil
+		} else if waitCtx.Err() == nil {
+			lastErr.Err = err
+		}
+		loggerFrom(ctx).Debug("Solution not healthy yet", "endpoint", endpoint, "attempt", attempt, "status", lastErr.StatusCode, logKeyError, lastErr.Err)
+
+		select {
+		case <-time.After(interval):
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if lastErr.StatusCode == 0 && lastErr.Err == nil {
+				lastErr.Err = context.DeadlineExceeded
+			}
+			return lastErr
+		}
+		interval = min(interval*2, maxHealthCheckInterval)
+	}
+}
+
+// healthCheckEndpoint returns the endpoint to probe after install, and whether the
+// configuration asks for a health check at all (HealthCheckEnabled set to true).
+func healthCheckEndpoint(configValues map[string]interface{}) (string, bool, error) {
+	enabled, _ := configValues[healthCheckEnabledKey].(bool)
+	if !enabled {
+		return "", false, nil
+	}
+	endpoint, _ := configValues[healthCheckEndpointKey].(string)
+	if endpoint == "" {
+		return "", true, errors.New("HealthCheckEnabled is true but HealthCheckEndpoint is not set")
+	}
+	return endpoint, true, nil
+}
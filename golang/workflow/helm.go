@@ -0,0 +1,144 @@
+package workflow
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// HelmRepoType distinguishes OCI registries from classic HTTP chart repositories.
+type HelmRepoType string
+
+const (
+	HelmRepoOCI  HelmRepoType = "oci"
+	HelmRepoHTTP HelmRepoType = "http"
+)
+
+// HelmChart describes the chart deployed by the solution's helm.v3 component.
+type HelmChart struct {
+	Repo    string            `yaml:"repo"` // OCI reference or http(s) chart repository URL
+	Name    string            `yaml:"name"` // Chart name, required only for HTTP repositories
+	Version string            `yaml:"version"`
+	Wait    bool              `yaml:"wait"`    // Wait for the release's resources to become ready
+	Timeout string            `yaml:"timeout"` // Helm timeout, e.g. "5m"
+	Auth    *HelmRegistryAuth `yaml:"-"`       // Never read from a config file; see HELM_REGISTRY_TOKEN
+}
+
+// Chart used when the caller does not supply one.
+var DefaultHelmChart = HelmChart{
+	Repo:    "ghcr.io/eclipse-symphony/tests/helm/simple-chart",
+	Version: "0.3.0",
+	Wait:    true,
+	Timeout: "5m",
+}
+
+// HelmRegistryAuth holds credentials for pulling charts from a private registry: a username
+// and password, or a token (e.g. a GitHub PAT or an ACR token, optionally with the username
+// the registry expects alongside it). A chart without auth is pulled anonymously. Printing or
+// logging it shows the username only.
+type HelmRegistryAuth struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// String describes the auth without its secret.
+func (a HelmRegistryAuth) String() string {
+	method := "token"
+	if a.Password != "" {
+		method = "password"
+	}
+	if a.Username == "" {
+		return method + " auth"
+	}
+	return fmt.Sprintf("%s auth as %s", method, a.Username)
+}
+
+// GoString keeps %#v from printing the secret.
+func (a HelmRegistryAuth) GoString() string { return a.String() }
+
+// LogValue keeps the secret out of structured logs.
+func (a HelmRegistryAuth) LogValue() slog.Value { return slog.StringValue(a.String()) }
+
+// DetectHelmRepoType classifies a chart repository reference.
+// "oci://host/path" and scheme-less "host/path" references are OCI;
+// "http://" and "https://" URLs are classic Helm repositories.
+func DetectHelmRepoType(repo string) (HelmRepoType, error) {
+	if repo == "" {
+		return "", fmt.Errorf("helm chart repo is empty")
+	}
+
+	if strings.Contains(repo, "://") {
+		u, err := url.Parse(repo)
+		if err != nil || u.Host == "" {
+			return "", fmt.Errorf("invalid helm chart repo %q", repo)
+		}
+		switch u.Scheme {
+		case "oci":
+			return HelmRepoOCI, nil
+		case "http", "https":
+			return HelmRepoHTTP, nil
+		default:
+			return "", fmt.Errorf("unsupported helm chart repo scheme %q in %q", u.Scheme, repo)
+		}
+	}
+
+	// Scheme-less references must name a registry host and a repository path
+	host, path, found := strings.Cut(repo, "/")
+	if !found || path == "" || !(strings.ContainsAny(host, ".:") || host == "localhost") {
+		return "", fmt.Errorf("helm chart repo %q is neither an OCI reference (registry/path) nor an http(s) URL", repo)
+	}
+	return HelmRepoOCI, nil
+}
+
+// buildHelmChartProperties formats the "chart" properties of a helm.v3 component.
+// OCI references carry the chart in the repo path; HTTP repositories need the chart name separately.
+// When auth is set, the registry credentials are passed through as the chart's username and
+// password, a token standing in for the password, so the target can pull private charts.
+func buildHelmChartProperties(helmChart HelmChart) (map[string]interface{}, error) {
+	repoType, err := DetectHelmRepoType(helmChart.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if helmChart.Version == "" {
+		return nil, fmt.Errorf("helm chart version is required for %s", helmChart.Repo)
+	}
+
+	chart := map[string]interface{}{
+		"repo":    helmChart.Repo,
+		"version": helmChart.Version,
+		"wait":    helmChart.Wait,
+	}
+	if helmChart.Timeout != "" {
+		chart["timeout"] = helmChart.Timeout
+	}
+
+	if repoType == HelmRepoHTTP {
+		if helmChart.Name == "" {
+			return nil, fmt.Errorf("helm chart name is required for HTTP repo %s", helmChart.Repo)
+		}
+		chart["name"] = helmChart.Name
+	}
+
+	if auth := helmChart.Auth; auth != nil {
+		switch {
+		case auth.Password != "" && auth.Token != "":
+			return nil, fmt.Errorf("helm registry auth for %s sets both a password and a token; use one", helmChart.Repo)
+		case auth.Password != "":
+			if auth.Username == "" {
+				return nil, fmt.Errorf("helm registry auth for %s has a password but no username", helmChart.Repo)
+			}
+			chart["username"] = auth.Username
+			chart["password"] = auth.Password
+		case auth.Token != "":
+			// Registries take a token in place of the password
+			chart["username"] = auth.Username
+			chart["password"] = auth.Token
+		default:
+			return nil, fmt.Errorf("helm registry auth for %s has neither a password nor a token", helmChart.Repo)
+		}
+	}
+
+	return chart, nil
+}
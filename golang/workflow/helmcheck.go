@@ -0,0 +1,267 @@
+package workflow
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChartUnavailableError reports a chart registry or repository that answered, but not with the
+// chart: it doesn't exist at that version, or the credentials (or their absence) are refused.
+type ChartUnavailableError struct {
+	Chart      string // The chart reference, e.g. "ghcr.io/org/chart:1.2.0"
+	StatusCode int    // HTTP status of the registry's answer; 0 when it answered but lacked the version
+	Message    string
+}
+
+func (e *ChartUnavailableError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("helm chart %s is unavailable: %s", e.Chart, e.Message)
+	}
+	return fmt.Sprintf("helm chart %s is unavailable: status %d %s: %s", e.Chart, e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// ociManifestTypes are the manifest media types a Helm chart in an OCI registry may be stored as.
+var ociManifestTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// CheckHelmChart resolves chart's version from where the target will pull it, so an
+// unreachable repository, a missing version or refused credentials fail before a solution
+// template version points at them rather than at install. OCI charts are checked with a HEAD
+// request for the version's manifest, taking a registry token first when the registry asks
+// for one; HTTP repositories have their index.yaml read. chart.Auth is used when set.
+//
+// A registry that answers without the chart yields a *ChartUnavailableError carrying its HTTP
+// status. Any other error means the registry couldn't be reached from here, which in an
+// air-gapped setup doesn't mean the target can't reach it either.
+func CheckHelmChart(ctx context.Context, httpClient *http.Client, chart HelmChart) error {
+	repoType, err := DetectHelmRepoType(chart.Repo)
+	if err != nil {
+		return err
+	}
+	if chart.Version == "" {
+		return fmt.Errorf("helm chart version is required for %s", chart.Repo)
+	}
+	client := httpClientOrDefault(httpClient)
+	if repoType == HelmRepoHTTP {
+		return checkHTTPChart(ctx, client, chart)
+	}
+	return checkOCIChart(ctx, client, chart)
+}
+
+// checkOCIChart checks that the registry has a manifest for the chart's version.
+func checkOCIChart(ctx context.Context, client *http.Client, chart HelmChart) error {
+	reference := strings.TrimPrefix(chart.Repo, "oci://")
+	host, repository, _ := strings.Cut(reference, "/")
+	chartRef := reference + ":" + chart.Version
+	scheme := "https"
+	if isLocalRegistry(host) {
+		scheme = "http"
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, host, repository, neturl.PathEscape(chart.Version))
+
+	loggerFrom(ctx).Debug("Checking helm chart", "chart", chartRef)
+	head := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Accept", strings.Join(ociManifestTypes, ", "))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error reaching registry %s: %w", host, err)
+		}
+		resp.Body.Close()
+		return resp, nil
+	}
+
+	resp, err := head("")
+	if err != nil {
+		return err
+	}
+	// Registries ask for a token even for anonymous pulls; fetch one and try again
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := registryAuthorization(ctx, client, resp.Header.Get("WWW-Authenticate"), chart.Auth)
+		if err != nil {
+			return &ChartUnavailableError{Chart: chartRef, StatusCode: resp.StatusCode, Message: err.Error()}
+		}
+		if resp, err = head(authorization); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return &ChartUnavailableError{Chart: chartRef, StatusCode: resp.StatusCode, Message: "the registry has no such chart or version"}
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &ChartUnavailableError{Chart: chartRef, StatusCode: resp.StatusCode, Message: "access denied; set the registry credentials (see HelmChart.Auth)"}
+	default:
+		return &ChartUnavailableError{Chart: chartRef, StatusCode: resp.StatusCode, Message: "unexpected registry response"}
+	}
+}
+
+// registryAuthorization answers a registry's WWW-Authenticate challenge: Basic auth with
+// auth's credentials, or a Bearer token from the registry's token service, anonymous when
+// auth is nil.
+func registryAuthorization(ctx context.Context, client *http.Client, challenge string, auth *HelmRegistryAuth) (string, error) {
+	scheme, params := parseAuthChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if auth == nil {
+			return "", fmt.Errorf("the registry requires credentials")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.secret())), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported registry auth challenge %q", challenge)
+	}
+
+	realm, err := neturl.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("registry auth challenge has no valid realm: %q", challenge)
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating token request: %w", err)
+	}
+	if auth != nil {
+		req.SetBasicAuth(auth.Username, auth.secret())
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading registry token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token request failed with status %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("error parsing registry token: %w", err)
+	}
+	return "Bearer " + valueOrDefault(token.Token, token.AccessToken), nil
+}
+
+// checkHTTPChart checks that a classic chart repository's index lists the chart's version.
+func checkHTTPChart(ctx context.Context, client *http.Client, chart HelmChart) error {
+	chartRef := chart.Name + ":" + chart.Version
+	if chart.Name == "" {
+		return fmt.Errorf("helm chart name is required for HTTP repo %s", chart.Repo)
+	}
+	indexURL := strings.TrimSuffix(chart.Repo, "/") + "/index.yaml"
+
+	loggerFrom(ctx).Debug("Checking helm chart", "chart", chartRef, "repo", chart.Repo)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, indexURL, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	if chart.Auth != nil {
+		req.SetBasicAuth(chart.Auth.Username, chart.Auth.secret())
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error reaching chart repository %s: %w", chart.Repo, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &ChartUnavailableError{Chart: chartRef, StatusCode: resp.StatusCode, Message: "error reading the repository's index.yaml"}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading chart repository index: %w", err)
+	}
+
+	var index struct {
+		Entries map[string][]struct {
+			Version string `yaml:"version"`
+		} `yaml:"entries"`
+	}
+	if err := yaml.Unmarshal(body, &index); err != nil {
+		return fmt.Errorf("error parsing chart repository index: %w", err)
+	}
+	for _, entry := range index.Entries[chart.Name] {
+		if entry.Version == chart.Version {
+			return nil
+		}
+	}
+	return &ChartUnavailableError{Chart: chartRef, Message: fmt.Sprintf("the index of %s doesn't list this version", chart.Repo)}
+}
+
+// secret is the password, or the token standing in for one.
+func (a HelmRegistryAuth) secret() string {
+	return valueOrDefault(a.Password, a.Token)
+}
+
+// parseAuthChallenge splits a WWW-Authenticate header such as
+// `Bearer realm="https://ghcr.io/token",service="ghcr.io"` into its scheme and parameters.
+func parseAuthChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		key = strings.TrimSpace(key)
+		if strings.HasPrefix(value, `"`) {
+			// Quoted values may contain commas, e.g. a scope naming several actions
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			params[key], rest, _ = strings.Cut(value, ",")
+		}
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+		rest = strings.TrimSpace(rest)
+	}
+	return scheme, params
+}
+
+// isLocalRegistry reports whether host (with an optional port) is a registry on this machine,
+// which is usually served over plain HTTP.
+func isLocalRegistry(host string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	if hostname == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,133 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// DefaultHierarchyLevel is the level targets are created at unless told otherwise.
+const DefaultHierarchyLevel = "line"
+
+// Hierarchy is one organizational level of a context, e.g. "factory"
+type Hierarchy struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// DefaultHierarchies returns the sample's organizational levels (country -> region -> factory -> line).
+func DefaultHierarchies() []Hierarchy {
+	return []Hierarchy{
+		{Name: "country", Description: "Country level hierarchy"},
+		{Name: "region", Description: "Regional level hierarchy"},
+		{Name: "factory", Description: "Factory level hierarchy"},
+		{Name: "line", Description: "Production line hierarchy"},
+	}
+}
+
+// ParseHierarchies turns a comma-separated list of level names ("country,region,site")
+// into hierarchies, outermost level first. An empty value returns nil.
+func ParseHierarchies(value string) ([]Hierarchy, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var hierarchies []Hierarchy
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("empty hierarchy name in %q", value)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate hierarchy name %q in %q", name, value)
+		}
+		seen[name] = true
+		hierarchies = append(hierarchies, Hierarchy{
+			Name:        name,
+			Description: fmt.Sprintf("%s level hierarchy", name),
+		})
+	}
+	return hierarchies, nil
+}
+
+// MergeHierarchies keeps the context's existing hierarchies, in their order and with their
+// descriptions, and appends any new ones whose names aren't already present. Re-running with
+// the same set therefore leaves the context's hierarchy unchanged.
+func MergeHierarchies(ctx context.Context, existingHierarchies, newHierarchies []Hierarchy) []Hierarchy {
+	seen := make(map[string]bool)
+	var merged []Hierarchy
+
+	for _, hierarchy := range existingHierarchies {
+		if hierarchy.Name == "" || seen[hierarchy.Name] {
+			continue
+		}
+		seen[hierarchy.Name] = true
+		merged = append(merged, hierarchy)
+	}
+
+	for _, hierarchy := range newHierarchies {
+		if hierarchy.Name == "" || seen[hierarchy.Name] {
+			continue
+		}
+		seen[hierarchy.Name] = true
+		merged = append(merged, hierarchy)
+		if len(existingHierarchies) > 0 {
+			loggerFrom(ctx).Info("Adding hierarchy level to the existing context hierarchy", "hierarchy", hierarchy.Name)
+		}
+	}
+
+	return merged
+}
+
+// ValidateHierarchyLevel checks that level is one of the hierarchies defined on the context,
+// so a target isn't created at a level the context doesn't know. The error lists the valid levels.
+func ValidateHierarchyLevel(contextResource *armworkloadorchestration.Context, level string) error {
+	var levels []string
+	if contextResource != nil && contextResource.Properties != nil {
+		for _, hierarchy := range contextResource.Properties.Hierarchies {
+			if hierarchy == nil || hierarchy.Name == nil {
+				continue
+			}
+			if *hierarchy.Name == level {
+				return nil
+			}
+			levels = append(levels, *hierarchy.Name)
+		}
+	}
+	contextName := "context"
+	if contextResource != nil && contextResource.Name != nil {
+		contextName = "context " + *contextResource.Name
+	}
+	if len(levels) == 0 {
+		return fmt.Errorf("hierarchy level %q is not defined on %s, which has no hierarchy levels", level, contextName)
+	}
+	return fmt.Errorf("hierarchy level %q is not defined on %s (valid levels: %s)", level, contextName, strings.Join(levels, ", "))
+}
+
+// hierarchyNames lists the names of the given hierarchies in order.
+func hierarchyNames(hierarchies []Hierarchy) []string {
+	names := make([]string, 0, len(hierarchies))
+	for _, hierarchy := range hierarchies {
+		names = append(names, hierarchy.Name)
+	}
+	return names
+}
+
+// toSDKHierarchies converts hierarchies to the SDK model, skipping unnamed entries.
+func toSDKHierarchies(hierarchies []Hierarchy) []*armworkloadorchestration.Hierarchy {
+	hierarchyObjects := make([]*armworkloadorchestration.Hierarchy, 0, len(hierarchies))
+	for _, hierarchy := range hierarchies {
+		if hierarchy.Name == "" {
+			continue
+		}
+		hierarchyObjects = append(hierarchyObjects, &armworkloadorchestration.Hierarchy{
+			Name:        to.Ptr(hierarchy.Name),
+			Description: to.Ptr(hierarchy.Description),
+		})
+	}
+	return hierarchyObjects
+}
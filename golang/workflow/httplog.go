@@ -0,0 +1,92 @@
+package workflow
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
+)
+
+// redactedHeaders are never written to the HTTP log.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// EnableHTTPLogging writes the SDK's request, response, retry and long-running-operation
+// events to w. The SDK already redacts headers such as Authorization from its log.
+// The SDK's log listener is process-wide, so this affects every SDK client in the process.
+func EnableHTTPLogging(w io.Writer) {
+	var mu sync.Mutex
+	azlog.SetEvents(azlog.EventRequest, azlog.EventResponse, azlog.EventResponseError, azlog.EventRetryPolicy, azlog.EventLRO)
+	azlog.SetListener(func(event azlog.Event, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "[%s] %s\n", event, message)
+	})
+}
+
+// withHTTPLogging returns a copy of client that writes each request and response to w,
+// in the same layout as the SDK's log, with credentials redacted.
+func withHTTPLogging(client *http.Client, w io.Writer) *http.Client {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	logged := *client
+	logged.Transport = &loggingTransport{next: transport, w: w}
+	return &logged
+}
+
+// loggingTransport logs the requests made outside the SDK, i.e. the Configuration API calls.
+type loggingTransport struct {
+	next http.RoundTripper
+	w    io.Writer
+	mu   sync.Mutex
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.log(azlog.EventRequest, fmt.Sprintf("==> OUTGOING REQUEST\n   %s %s\n%s", req.Method, req.URL, formatHeaders(req.Header)))
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	duration := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		t.log(azlog.EventResponseError, fmt.Sprintf("==> REQUEST/RESPONSE (%s) -- REQUEST ERROR\n   %s %s\n   ERROR: %v", duration, req.Method, req.URL, err))
+		return nil, err
+	}
+	t.log(azlog.EventResponse, fmt.Sprintf("==> REQUEST/RESPONSE (%s) -- RESPONSE RECEIVED\n   %s %s\n   RESPONSE Status: %s\n%s", duration, req.Method, req.URL, resp.Status, formatHeaders(resp.Header)))
+	return resp, nil
+}
+
+func (t *loggingTransport) log(event azlog.Event, message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "[%s] %s\n", event, message)
+}
+
+// formatHeaders renders headers one per line, sorted, with credentials replaced by REDACTED.
+func formatHeaders(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			value = "REDACTED"
+		}
+		fmt.Fprintf(&b, "   %s: %s\n", name, value)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
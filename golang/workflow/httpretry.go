@@ -0,0 +1,94 @@
+package workflow
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxConfigurationAttempts bounds how often a throttled or failing Configuration API call is sent
+	maxConfigurationAttempts = 4
+	// maxRetryAfter caps how long a single Retry-After header can make us wait
+	maxRetryAfter = 2 * time.Minute
+)
+
+// sharedHTTPClient is used by the Configuration API calls when the caller doesn't supply one.
+// Sharing it pools connections across calls; the timeout stops a hung connection from blocking forever.
+var sharedHTTPClient = &http.Client{
+	Timeout:   60 * time.Second,
+	Transport: http.DefaultTransport.(*http.Transport).Clone(),
+}
+
+// httpClientOrDefault returns client, or the shared client when client is nil.
+func httpClientOrDefault(client *http.Client) *http.Client {
+	if client == nil {
+		return sharedHTTPClient
+	}
+	return client
+}
+
+// doWithRetry sends the request built by newRequest, resending it when the service answers
+// 429 or 5xx. It waits as long as the Retry-After header asks (capped at maxRetryAfter),
+// or backs off exponentially when there is none. newRequest is called once per attempt so
+// request bodies can be re-read. The final response is returned whatever its status.
+// Waiting between attempts stops early when ctx is done. A disabled RetryConfigurationAPI policy
+// on ctx (see WithRetryPolicy) sends the request once.
+func doWithRetry(ctx context.Context, client *http.Client, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	maxAttempts := maxConfigurationAttempts
+	if retryPolicyFor(ctx, RetryConfigurationAPI, RetryPolicy{}).Disabled {
+		maxAttempts = 1
+	}
+	backoff := 2 * time.Second
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if !isRetryableStatus(resp.StatusCode) || attempt >= maxAttempts {
+			return resp, nil
+		}
+
+		wait := retryAfter(resp.Header.Get("Retry-After"), backoff)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		loggerFrom(ctx).Warn("Configuration API request throttled or failed, retrying",
+			"status", resp.StatusCode, "delay", wait, "attempt", attempt+1, "maxAttempts", maxAttempts)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// isRetryableStatus reports whether a response status is worth retrying: throttling or a server error.
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryAfter parses a Retry-After header given either as seconds or as an HTTP date,
+// falling back to fallback when the header is missing or invalid.
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	wait := fallback
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	} else if when, err := http.ParseTime(header); err == nil {
+		wait = time.Until(when)
+		if wait < 0 {
+			wait = 0
+		}
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait
+}
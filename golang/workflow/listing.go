@@ -0,0 +1,142 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// collectPages drains pager and returns every non-nil item across all pages.
+// Always returns a non-nil slice, so an empty result set is safe to range over.
+func collectPages[P any, T any](ctx context.Context, pager *runtime.Pager[P], items func(P) []*T) ([]*T, error) {
+	all := []*T{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items(page) {
+			if item != nil {
+				all = append(all, item)
+			}
+		}
+	}
+	return all, nil
+}
+
+// Lists every schema in a resource group.
+func ListSchemas(ctx context.Context, client SchemasAPI, resourceGroupName string) ([]*armworkloadorchestration.Schema, error) {
+	return collectPages(ctx, client.NewListByResourceGroupPager(resourceGroupName, nil),
+		func(page armworkloadorchestration.SchemasClientListByResourceGroupResponse) []*armworkloadorchestration.Schema {
+			return page.Value
+		})
+}
+
+// Lists every version of a schema.
+func ListSchemaVersions(ctx context.Context, client SchemaVersionsAPI, resourceGroupName, schemaName string) ([]*armworkloadorchestration.SchemaVersion, error) {
+	return collectPages(ctx, client.NewListBySchemaPager(resourceGroupName, schemaName, nil),
+		func(page armworkloadorchestration.SchemaVersionsClientListBySchemaResponse) []*armworkloadorchestration.SchemaVersion {
+			return page.Value
+		})
+}
+
+// Lists every solution template in a resource group.
+func ListSolutionTemplates(ctx context.Context, client SolutionTemplatesAPI, resourceGroupName string) ([]*armworkloadorchestration.SolutionTemplate, error) {
+	return collectPages(ctx, client.NewListByResourceGroupPager(resourceGroupName, nil),
+		func(page armworkloadorchestration.SolutionTemplatesClientListByResourceGroupResponse) []*armworkloadorchestration.SolutionTemplate {
+			return page.Value
+		})
+}
+
+// Lists every version of a solution template.
+func ListSolutionTemplateVersions(ctx context.Context, client SolutionTemplateVersionsAPI, resourceGroupName, solutionTemplateName string) ([]*armworkloadorchestration.SolutionTemplateVersion, error) {
+	return collectPages(ctx, client.NewListBySolutionTemplatePager(resourceGroupName, solutionTemplateName, nil),
+		func(page armworkloadorchestration.SolutionTemplateVersionsClientListBySolutionTemplateResponse) []*armworkloadorchestration.SolutionTemplateVersion {
+			return page.Value
+		})
+}
+
+// Lists every target in a resource group.
+func ListTargets(ctx context.Context, client TargetsAPI, resourceGroupName string) ([]*armworkloadorchestration.Target, error) {
+	return collectPages(ctx, client.NewListByResourceGroupPager(resourceGroupName, nil),
+		func(page armworkloadorchestration.TargetsClientListByResourceGroupResponse) []*armworkloadorchestration.Target {
+			return page.Value
+		})
+}
+
+// Lists every solution deployed to a target.
+func ListSolutions(ctx context.Context, client SolutionsAPI, resourceGroupName, targetName string) ([]*armworkloadorchestration.Solution, error) {
+	return collectPages(ctx, client.NewListByTargetPager(resourceGroupName, targetName, nil),
+		func(page armworkloadorchestration.SolutionsClientListByTargetResponse) []*armworkloadorchestration.Solution {
+			return page.Value
+		})
+}
+
+// Lists every version of a solution on a target.
+func ListSolutionVersions(ctx context.Context, client SolutionVersionsAPI, resourceGroupName, targetName, solutionName string) ([]*armworkloadorchestration.SolutionVersion, error) {
+	return collectPages(ctx, client.NewListBySolutionPager(resourceGroupName, targetName, solutionName, nil),
+		func(page armworkloadorchestration.SolutionVersionsClientListBySolutionResponse) []*armworkloadorchestration.SolutionVersion {
+			return page.Value
+		})
+}
+
+// Lists every context in a resource group.
+func ListContexts(ctx context.Context, client ContextsAPI, resourceGroupName string) ([]*armworkloadorchestration.Context, error) {
+	return collectPages(ctx, client.NewListByResourceGroupPager(resourceGroupName, nil),
+		func(page armworkloadorchestration.ContextsClientListByResourceGroupResponse) []*armworkloadorchestration.Context {
+			return page.Value
+		})
+}
+
+// InstalledSolution is a solution version deployed, or being deployed, on a target.
+type InstalledSolution struct {
+	SolutionName              string                         `json:"solutionName"`
+	SolutionVersionID         string                         `json:"solutionVersionId"`
+	SolutionTemplateVersionID string                         `json:"solutionTemplateVersionId,omitempty"`
+	Revision                  int32                          `json:"revision"`
+	State                     armworkloadorchestration.State `json:"state"`
+}
+
+// Lists the solution versions installed on a target: those in the Deployed state, and those
+// still Deploying. Returns an empty slice when nothing is installed.
+func ListInstalledSolutions(ctx context.Context, solutionsClient SolutionsAPI, solutionVersionsClient SolutionVersionsAPI, resourceGroupName, targetName string) ([]InstalledSolution, error) {
+	solutions, err := ListSolutions(ctx, solutionsClient, resourceGroupName, targetName)
+	if err != nil {
+		return nil, fmt.Errorf("error listing solutions on target %s: %w", targetName, err)
+	}
+
+	installed := []InstalledSolution{}
+	for _, solution := range solutions {
+		if solution.Name == nil {
+			continue
+		}
+		versions, err := ListSolutionVersions(ctx, solutionVersionsClient, resourceGroupName, targetName, *solution.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error listing versions of solution %s: %w", *solution.Name, err)
+		}
+		for _, version := range versions {
+			if version.ID == nil || version.Properties == nil || version.Properties.State == nil {
+				continue
+			}
+			state := *version.Properties.State
+			if state != armworkloadorchestration.StateDeployed && state != armworkloadorchestration.StateDeploying {
+				continue
+			}
+			entry := InstalledSolution{
+				SolutionName:      *solution.Name,
+				SolutionVersionID: *version.ID,
+				State:             state,
+			}
+			if version.Properties.SolutionTemplateVersionID != nil {
+				entry.SolutionTemplateVersionID = *version.Properties.SolutionTemplateVersionID
+			}
+			if version.Properties.Revision != nil {
+				entry.Revision = *version.Properties.Revision
+			}
+			installed = append(installed, entry)
+		}
+	}
+	return installed, nil
+}
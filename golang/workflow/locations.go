@@ -0,0 +1,148 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+// The resource provider behind workload orchestration, and the resource types a run creates in
+// the chosen region. A region has to support all of them.
+const (
+	workloadOrchestrationProvider = "Microsoft.Edge"
+	providersAPIVersion           = "2021-04-01"
+)
+
+var locationResourceTypes = []string{"schemas", "solutionTemplates", "targets"}
+
+// GetSupportedLocations asks Resource Manager which regions the workload orchestration provider
+// supports for every resource type the workflow creates. Regions come back normalized, e.g.
+// "eastus2euap" for "East US 2 EUAP", and sorted. Retries, httpClient and the endpoint behave
+// as in CreateConfigurationAPICall.
+func GetSupportedLocations(ctx context.Context, credential azcore.TokenCredential, cloudConfig cloud.Configuration, httpClient *http.Client, subscriptionID string) ([]string, error) {
+	endpoint, token, err := configurationAPIAuth(ctx, credential, cloudConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/subscriptions/%s/providers/%s?api-version=%s",
+		endpoint, neturl.PathEscape(subscriptionID), workloadOrchestrationProvider, providersAPIVersion)
+
+	loggerFrom(ctx).Debug("Reading supported locations", "provider", workloadOrchestrationProvider)
+
+	resp, err := doWithRetry(ctx, httpClientOrDefault(httpClient), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provider lookup failed: %w", parseARMError(resp.StatusCode, body))
+	}
+
+	var provider struct {
+		ResourceTypes []struct {
+			ResourceType string   `json:"resourceType"`
+			Locations    []string `json:"locations"`
+		} `json:"resourceTypes"`
+	}
+	if err := json.Unmarshal(body, &provider); err != nil {
+		return nil, fmt.Errorf("error parsing provider response: %w", err)
+	}
+
+	// Count, per region, how many of the needed resource types it supports
+	counts := make(map[string]int)
+	found := 0
+	for _, resourceType := range provider.ResourceTypes {
+		if !slices.ContainsFunc(locationResourceTypes, func(name string) bool { return strings.EqualFold(name, resourceType.ResourceType) }) {
+			continue
+		}
+		found++
+		for _, location := range resourceType.Locations {
+			counts[NormalizeLocation(location)]++
+		}
+	}
+	if found == 0 {
+		return nil, fmt.Errorf("provider %s lists none of the resource types %v", workloadOrchestrationProvider, locationResourceTypes)
+	}
+
+	locations := []string{}
+	for location, count := range counts {
+		if count == found {
+			locations = append(locations, location)
+		}
+	}
+	slices.Sort(locations)
+	return locations, nil
+}
+
+// NormalizeLocation turns a region's display name into its programmatic name, e.g.
+// "East US 2 EUAP" into "eastus2euap". Programmatic names come back unchanged.
+func NormalizeLocation(location string) string {
+	return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+}
+
+// ValidateLocation checks location against the supported regions, failing with the list of
+// valid ones.
+func ValidateLocation(location string, supported []string) error {
+	if slices.Contains(supported, NormalizeLocation(location)) {
+		return nil
+	}
+	return fmt.Errorf("location %q is not supported by %s; valid locations: %s", location, workloadOrchestrationProvider, strings.Join(supported, ", "))
+}
+
+// locationCache keeps the supported regions looked up by a run, so they are fetched once
+// however many regions it checks.
+type locationCache struct {
+	mu        sync.Mutex
+	locations map[string][]string // Keyed by subscription ID
+}
+
+type locationCacheKey struct{}
+
+// withLocationCache returns a context carrying a location cache, unless ctx already has one.
+func withLocationCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(locationCacheKey{}).(*locationCache); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, locationCacheKey{}, &locationCache{locations: make(map[string][]string)})
+}
+
+// supportedLocations is GetSupportedLocations, answered from the context's location cache
+// when an earlier call already filled it.
+func supportedLocations(ctx context.Context, credential azcore.TokenCredential, cloudConfig cloud.Configuration, httpClient *http.Client, subscriptionID string) ([]string, error) {
+	cache, _ := ctx.Value(locationCacheKey{}).(*locationCache)
+	if cache == nil {
+		return GetSupportedLocations(ctx, credential, cloudConfig, httpClient, subscriptionID)
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if locations, ok := cache.locations[subscriptionID]; ok {
+		return locations, nil
+	}
+	locations, err := GetSupportedLocations(ctx, credential, cloudConfig, httpClient, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	cache.locations[subscriptionID] = locations
+	return locations, nil
+}
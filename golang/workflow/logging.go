@@ -0,0 +1,78 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LogFormat selects how a logger built by NewLogger renders its records.
+type LogFormat string
+
+const (
+	LogFormatText LogFormat = "text" // key=value lines, easy to read in a terminal
+	LogFormatJSON LogFormat = "json" // one JSON object per line, for log pipelines
+)
+
+// Attribute keys shared by the workflow's log records.
+const (
+	logKeyStep     = "step"
+	logKeyResource = "resource"
+	logKeyState    = "state"
+	logKeyError    = "error"
+)
+
+// ParseLogFormat maps a format name (text or json) onto a LogFormat. An empty value is text.
+func ParseLogFormat(value string) (LogFormat, error) {
+	switch format := LogFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case "", LogFormatText:
+		return LogFormatText, nil
+	case LogFormatJSON:
+		return LogFormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q (valid: text, json)", value)
+	}
+}
+
+// ParseLogLevel maps a level name (debug, info, warn or error) onto a slog.Level. An empty value is info.
+func ParseLogLevel(value string) (slog.Level, error) {
+	if strings.TrimSpace(value) == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (valid: debug, info, warn, error)", value)
+	}
+	return level, nil
+}
+
+// NewLogger returns a logger writing records at level and above to w in the given format.
+func NewLogger(w io.Writer, format LogFormat, level slog.Leveler) *slog.Logger {
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	if format == LogFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, handlerOptions))
+	}
+	return slog.New(slog.NewTextHandler(w, handlerOptions))
+}
+
+// defaultLogger is used when neither Options.Logger nor WithLogger supplies one. It writes
+// to stderr so stdout stays free for the host's own output, such as the run summary.
+var defaultLogger = NewLogger(os.Stderr, LogFormatText, slog.LevelInfo)
+
+type loggerKey struct{}
+
+// WithLogger returns a context whose workflow steps log to logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the logger carried by ctx, or the default text logger when none is set.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if logger, _ := ctx.Value(loggerKey{}).(*slog.Logger); logger != nil {
+		return logger
+	}
+	return defaultLogger
+}
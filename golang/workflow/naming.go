@@ -0,0 +1,41 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultNamePrefix starts the name of every resource a run creates.
+const DefaultNamePrefix = "sdkexamples"
+
+// ResourceNames names the resources a run creates. Empty fields are derived from the
+// run's name prefix and run ID (see NewResourceNames).
+type ResourceNames struct {
+	Schema           string
+	SolutionTemplate string
+	Target           string
+}
+
+// NewRunID returns a short random identifier, e.g. "3f9a1c07", that keeps the names of
+// concurrent runs apart.
+func NewRunID() string {
+	return fmt.Sprintf("%08x", randomIntn(1<<32))
+}
+
+// NewResourceNames derives per-run names such as "sdkexamples-3f9a1c07-target".
+func NewResourceNames(prefix, runID string) ResourceNames {
+	base := strings.Trim(prefix+"-"+runID, "-")
+	return ResourceNames{
+		Schema:           base + "-schema",
+		SolutionTemplate: base + "-solution",
+		Target:           base + "-target",
+	}
+}
+
+// withDefaults fills any empty name from defaults.
+func (n ResourceNames) withDefaults(defaults ResourceNames) ResourceNames {
+	n.Schema = valueOrDefault(n.Schema, defaults.Schema)
+	n.SolutionTemplate = valueOrDefault(n.SolutionTemplate, defaults.SolutionTemplate)
+	n.Target = valueOrDefault(n.Target, defaults.Target)
+	return n
+}
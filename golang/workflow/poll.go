@@ -0,0 +1,59 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+type operationTimeoutKey struct{}
+
+// WithOperationTimeout returns a context that bounds each long-running operation started with it
+// to timeout. This is separate from any deadline on ctx itself, which bounds the whole workflow.
+// A zero or negative timeout leaves operations unbounded.
+func WithOperationTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, operationTimeoutKey{}, timeout)
+}
+
+// operationTimeout returns the per-operation timeout carried by ctx, or zero when none is set.
+func operationTimeout(ctx context.Context) time.Duration {
+	timeout, _ := ctx.Value(operationTimeoutKey{}).(time.Duration)
+	return timeout
+}
+
+// stateFunc fetches a resource's current provisioning state for timeout diagnostics.
+type stateFunc func(ctx context.Context) (*armworkloadorchestration.ProvisioningState, error)
+
+// pollUntilDone waits for poller under the per-operation timeout carried by ctx.
+// When that timeout expires, the error names the operation and, if state is non-nil,
+// the resource's provisioning state at that moment. It wraps context.DeadlineExceeded.
+func pollUntilDone[T any](ctx context.Context, poller *runtime.Poller[T], operation string, state stateFunc) (T, error) {
+	timeout := operationTimeout(ctx)
+	if timeout <= 0 {
+		return poller.PollUntilDone(ctx, nil)
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res, err := poller.PollUntilDone(opCtx, nil)
+	if err == nil || !errors.Is(opCtx.Err(), context.DeadlineExceeded) || ctx.Err() != nil {
+		return res, err
+	}
+
+	current := "unknown"
+	if state != nil {
+		// The operation's own context has expired, so ask with the caller's
+		provisioningState, stateErr := state(ctx)
+		if stateErr != nil {
+			current = fmt.Sprintf("unknown (%v)", stateErr)
+		} else if provisioningState != nil {
+			current = string(*provisioningState)
+		}
+	}
+	return res, fmt.Errorf("%s did not finish within %s (current provisioning state: %s): %w", operation, timeout, current, context.DeadlineExceeded)
+}
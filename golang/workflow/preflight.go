@@ -0,0 +1,94 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+// The Resource Manager API version the subscription check reads with.
+const subscriptionsAPIVersion = "2022-12-01"
+
+// AuthError reports that the credential can't use the subscription: it doesn't exist, the
+// principal has no access to it, or it is disabled. TenantID and Principal come from the
+// token, so a credential signed in to the wrong tenant shows up here.
+type AuthError struct {
+	SubscriptionID string
+	TenantID       string
+	Principal      string
+	Err            error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("%s (tenant %s) cannot use subscription %s: %v", e.Principal, e.TenantID, e.SubscriptionID, e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// CheckSubscriptionAccess reads the subscription with credential, so a mistyped subscription
+// ID or a credential from the wrong tenant fails before the workflow creates anything. A
+// subscription that is missing, inaccessible or disabled yields an *AuthError; other failures,
+// such as network errors, are returned as they are. httpClient and the endpoint behave as in
+// CreateConfigurationAPICall.
+func CheckSubscriptionAccess(ctx context.Context, credential azcore.TokenCredential, cloudConfig cloud.Configuration, httpClient *http.Client, subscriptionID string) error {
+	endpoint, token, err := configurationAPIAuth(ctx, credential, cloudConfig)
+	if err != nil {
+		return err
+	}
+	authErr := func(err error) *AuthError {
+		return &AuthError{
+			SubscriptionID: subscriptionID,
+			TenantID:       valueOrDefault(tokenClaim(token.Token, "tid"), "unknown"),
+			Principal:      PrincipalFromToken(token.Token),
+			Err:            err,
+		}
+	}
+
+	url := fmt.Sprintf("%s/subscriptions/%s?api-version=%s", endpoint, neturl.PathEscape(subscriptionID), subscriptionsAPIVersion)
+	loggerFrom(ctx).Debug("Checking subscription access", "subscription", subscriptionID)
+
+	resp, err := doWithRetry(ctx, httpClientOrDefault(httpClient), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return authErr(parseARMError(resp.StatusCode, body))
+	default:
+		return fmt.Errorf("subscription lookup failed: %w", parseARMError(resp.StatusCode, body))
+	}
+
+	var subscription struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(body, &subscription); err != nil {
+		return fmt.Errorf("error parsing subscription response: %w", err)
+	}
+	// Enabled, Warned and PastDue subscriptions still accept writes
+	if strings.EqualFold(subscription.State, "Disabled") || strings.EqualFold(subscription.State, "Deleted") {
+		return authErr(fmt.Errorf("subscription is %s", subscription.State))
+	}
+	return nil
+}
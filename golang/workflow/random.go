@@ -0,0 +1,73 @@
+package workflow
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// RandomSource supplies the randomness behind generated versions and capability names.
+// *rand.Rand from math/rand satisfies it, so rand.New(rand.NewSource(1)) makes them the same
+// on every run, e.g. in tests.
+type RandomSource interface {
+	Intn(n int) int
+}
+
+// randomIntn returns a uniformly distributed int in [0, n) from crypto/rand.
+// Unlike the global math/rand source it needs no seeding and is safe for concurrent use,
+// so simultaneous runs don't draw the same sequence of name suffixes.
+func randomIntn(n int) int {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		// The system's secure random source failing is not something a run can recover from
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return int(v.Int64())
+}
+
+// secureRandom draws from crypto/rand; it is the source whenever none is given.
+type secureRandom struct{}
+
+func (secureRandom) Intn(n int) int {
+	return randomIntn(n)
+}
+
+// orSecureRandom returns source, or crypto/rand when it is nil.
+func orSecureRandom(source RandomSource) RandomSource {
+	if source == nil {
+		return secureRandom{}
+	}
+	return source
+}
+
+// lockedRandom serializes draws from a source that isn't safe for concurrent use, such as *rand.Rand.
+type lockedRandom struct {
+	mu     sync.Mutex
+	source RandomSource
+}
+
+func (l *lockedRandom) Intn(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.source.Intn(n)
+}
+
+type randomSourceKey struct{}
+
+// WithRandomSource returns a context whose workflow steps generate versions and capability
+// names from source instead of crypto/rand. Draws are serialized, so steps running
+// concurrently can share a *rand.Rand. A nil source returns ctx unchanged.
+func WithRandomSource(ctx context.Context, source RandomSource) context.Context {
+	if source == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, randomSourceKey{}, RandomSource(&lockedRandom{source: source}))
+}
+
+// randomFrom returns the source carried by ctx, or crypto/rand when none is set.
+func randomFrom(ctx context.Context) RandomSource {
+	source, _ := ctx.Value(randomSourceKey{}).(RandomSource)
+	return orSecureRandom(source)
+}
@@ -0,0 +1,50 @@
+package workflow
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestWithRandomSourceGeneratesTheSameNames(t *testing.T) {
+	draw := func(seed int64) []string {
+		ctx := WithRandomSource(testContext(), rand.New(rand.NewSource(seed)))
+		var names []string
+		for i := 0; i < 10; i++ {
+			names = append(names, GenerateSingleRandomCapability(randomFrom(ctx)).Name)
+			names = append(names, GenerateRandomSemanticVersion(randomFrom(ctx), false, false))
+		}
+		return names
+	}
+
+	first, second := draw(1), draw(1)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("draw %d: %q != %q with the same seed", i, first[i], second[i])
+		}
+	}
+}
+
+func TestWithRandomSourceNilFallsBackToCrypto(t *testing.T) {
+	ctx := WithRandomSource(testContext(), nil)
+	if _, ok := randomFrom(ctx).(secureRandom); !ok {
+		t.Fatalf("randomFrom = %T, want secureRandom", randomFrom(ctx))
+	}
+	// Drawing must not panic
+	GenerateSingleRandomCapability(randomFrom(ctx))
+}
+
+func TestWithRandomSourceIsSafeForConcurrentSteps(t *testing.T) {
+	ctx := WithRandomSource(testContext(), rand.New(rand.NewSource(1)))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				GenerateSingleRandomCapability(randomFrom(ctx))
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,207 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RegionResult is the outcome of the workflow in one region of a RunRegions call. Result is
+// nil when the run failed before it started creating anything.
+type RegionResult struct {
+	Location string          `json:"location"`
+	Result   *WorkflowResult `json:"result,omitempty"`
+	Err      error           `json:"-"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// ParseLocations parses a comma-separated list of regions such as "eastus2euap,westus".
+// Empty input yields nil; empty or repeated entries are errors.
+func ParseLocations(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var locations []string
+	seen := make(map[string]bool)
+	for _, location := range strings.Split(value, ",") {
+		location = strings.TrimSpace(location)
+		if location == "" {
+			return nil, fmt.Errorf("empty location in %q", value)
+		}
+		if seen[strings.ToLower(location)] {
+			return nil, fmt.Errorf("duplicate location %q in %q", location, value)
+		}
+		seen[strings.ToLower(location)] = true
+		locations = append(locations, location)
+	}
+	return locations, nil
+}
+
+// Runs the workflow once per location, one region after another, and returns a result for
+// every region that ran.
+//
+// All regions share one run ID, so CleanupByRunID finds every region's resources, and each
+// region's resources are named with the region in them, e.g. "sdkexamples-westus-3f9a1c07-target".
+// The first region adds the capability to the context as Run does; the later regions reuse it
+// (see Options.Capability) instead of adding one each. CheckpointPath and ResumeTokenPath get
+// the region inserted before their extension so regions don't overwrite each other's state.
+//
+// A failed region stops the remaining ones unless continueOnError is set. Options.Timeout
+// bounds all regions together. With
+// Options.Teardown, nothing is deleted until every region has run; then each region's
+// resources go, followed by the shared capability. The returned error joins the failures of
+// all regions.
+func RunRegions(ctx context.Context, opts Options, locations []string, continueOnError bool) ([]RegionResult, error) {
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("at least one location is required")
+	}
+	seen := make(map[string]bool)
+	for _, location := range locations {
+		if location == "" || seen[strings.ToLower(location)] {
+			return nil, fmt.Errorf("locations must be non-empty and distinct: %v", locations)
+		}
+		seen[strings.ToLower(location)] = true
+	}
+	if opts.Update != nil || opts.CleanupRunID != "" {
+		return nil, fmt.Errorf("update and cleanup modes act on existing resources and can't run per region")
+	}
+	if opts.Logger != nil {
+		ctx = WithLogger(ctx, opts.Logger)
+	}
+	// Timeout bounds all regions together; the teardown gets its grace period past it
+	callerCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	// Check every region before any of them creates something; the lookup is cached, so the
+	// runs below check again for free. A failed lookup is left for the first run to report.
+	ctx = withLocationCache(ctx)
+	if opts.Credential != nil {
+		if supported, err := supportedLocations(ctx, opts.Credential, opts.Cloud, opts.HTTPClient, opts.SubscriptionID); err == nil {
+			for _, location := range locations {
+				if err := ValidateLocation(location, supported); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	// A resumed run takes each region's run ID from its checkpoint instead
+	if opts.RunID == "" && !opts.ResumeFromCheckpoint {
+		opts.RunID = NewRunID()
+	}
+	prefix := valueOrDefault(opts.NamePrefix, DefaultNamePrefix)
+
+	results := make([]RegionResult, 0, len(locations))
+	var errs []error
+	// The capability the regions share, and where the region that added it ran
+	capability, capabilityLocation := opts.Capability, ""
+	for _, location := range locations {
+		regionOpts := opts
+		regionOpts.Location = location
+		regionOpts.NamePrefix = prefix + "-" + location
+		regionOpts.Names = opts.Names.inRegion(location)
+		regionOpts.CheckpointPath = regionPath(opts.CheckpointPath, location)
+		regionOpts.ResumeTokenPath = regionPath(opts.ResumeTokenPath, location)
+		regionOpts.Capability = capability
+		regionOpts.Teardown = false
+		regionOpts.Timeout = 0
+		regionOpts.Logger = loggerFrom(ctx).With("region", location)
+
+		regionOpts.Logger.Info("Starting region")
+		result, err := Run(ctx, regionOpts)
+		regionResult := RegionResult{Location: location, Result: result, Err: err}
+		if err != nil {
+			regionResult.Error = err.Error()
+			errs = append(errs, fmt.Errorf("region %s: %w", location, err))
+		}
+		results = append(results, regionResult)
+		if capability == "" && result != nil && result.Capability != "" {
+			capability, capabilityLocation = result.Capability, location
+		}
+		if err != nil && (!continueOnError || ctx.Err() != nil) {
+			regionOpts.Logger.Warn("Region failed; skipping the remaining regions", logKeyError, err)
+			break
+		}
+	}
+
+	if opts.Teardown && opts.DryRun {
+		loggerFrom(ctx).Info("Dry run: skipping teardown; nothing was created")
+	} else if opts.Teardown {
+		teardownCtx, cancel := teardownContext(ctx, callerCtx)
+		defer cancel()
+		if err := teardownRegions(teardownCtx, opts, results, capability, capabilityLocation); err != nil {
+			errs = append(errs, fmt.Errorf("teardown failed: %w", err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// teardownRegions deletes what every region created and then the capability they shared,
+// when one of them added it (capabilityLocation is set).
+func teardownRegions(ctx context.Context, opts Options, results []RegionResult, capability, capabilityLocation string) error {
+	clientFactory, err := newClientFactory(opts.SubscriptionID, newCachingCredential(opts.Credential), opts.clientOptions()...)
+	if err != nil {
+		return err
+	}
+	resourceGroupName := valueOrDefault(opts.ResourceGroup, RESOURCE_GROUP)
+
+	var errs []error
+	for i := range results {
+		result := results[i].Result
+		if result == nil {
+			continue
+		}
+		start := time.Now()
+		err := TeardownWorkflow(WithLogger(ctx, loggerFrom(ctx).With("region", results[i].Location)), clientFactory, resourceGroupName, WorkflowResourceNames{
+			TargetName:           result.TargetName,
+			SolutionTemplateName: result.SolutionTemplateName,
+			SchemaName:           result.SchemaName,
+		})
+		result.recordStep("Teardown", resourceGroupName, start, err)
+		result.FinishedAt = time.Now().UTC()
+		if err != nil {
+			result.addError("Teardown", err)
+			errs = append(errs, fmt.Errorf("region %s: %w", results[i].Location, err))
+		}
+	}
+
+	if capabilityLocation != "" {
+		contextsClient := clientFactory.NewContextsClient()
+		err := RemoveCapabilitiesFromContext(ctx, contextsClient, valueOrDefault(opts.ContextResourceGroup, CONTEXT_RESOURCE_GROUP), valueOrDefault(opts.ContextName, CONTEXT_NAME), capabilityLocation, []string{capability})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// inRegion suffixes each explicitly set name with location; empty names are derived later.
+func (n ResourceNames) inRegion(location string) ResourceNames {
+	suffix := func(name string) string {
+		if name == "" {
+			return ""
+		}
+		return name + "-" + location
+	}
+	return ResourceNames{
+		Schema:           suffix(n.Schema),
+		SolutionTemplate: suffix(n.SolutionTemplate),
+		Target:           suffix(n.Target),
+	}
+}
+
+// regionPath inserts location before the extension of path, e.g. "run.json" becomes
+// "run.westus.json". An empty path stays empty.
+func regionPath(path, location string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + location + ext
+}
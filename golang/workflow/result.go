@@ -0,0 +1,132 @@
+package workflow
+
+import "time"
+
+// StepStatus reports how far a workflow step got.
+type StepStatus string
+
+const (
+	// StepNotRun means the workflow stopped before reaching the step.
+	StepNotRun StepStatus = "notRun"
+	// StepSucceeded means the step completed (or, in a dry run, would have been submitted).
+	StepSucceeded StepStatus = "succeeded"
+	// StepFailed means the step returned an error; see WorkflowResult.Errors.
+	StepFailed StepStatus = "failed"
+)
+
+// StepError records a failure in one workflow step.
+type StepError struct {
+	Step    string `json:"step"`
+	Message string `json:"message"`
+	// Transient marks a failure that rerunning may fix, such as throttling (see IsTransient)
+	Transient bool `json:"transient,omitempty"`
+}
+
+// StepOutcome records when a step ran and how it ended.
+type StepOutcome struct {
+	Name       string     `json:"name"`
+	Resource   string     `json:"resource,omitempty"`
+	Status     StepStatus `json:"status"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt time.Time  `json:"finishedAt"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// WorkflowResult reports what a workflow run created and how each step went.
+// On failure it holds everything created before the failing step.
+type WorkflowResult struct {
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	DryRun     bool      `json:"dryRun"`
+	RunID      string    `json:"runId,omitempty"`
+
+	Capability                string `json:"capability,omitempty"`
+	SchemaName                string `json:"schemaName,omitempty"`
+	SchemaVersion             string `json:"schemaVersion,omitempty"`
+	SolutionTemplateName      string `json:"solutionTemplateName,omitempty"`
+	SolutionTemplateVersionID string `json:"solutionTemplateVersionId,omitempty"`
+	TargetName                string `json:"targetName,omitempty"`
+	SolutionVersionID         string `json:"solutionVersionId,omitempty"`
+
+	// ResourceIDs lists the full ARM ID of every resource the run created or updated, in creation order
+	ResourceIDs []string `json:"resourceIds"`
+
+	ConfigurationStatus StepStatus `json:"configurationStatus"`
+	ReviewStatus        StepStatus `json:"reviewStatus"`
+	PublishStatus       StepStatus `json:"publishStatus"`
+	InstallStatus       StepStatus `json:"installStatus"`
+
+	// ReviewDiagnostics lists the findings of the review, errors and warnings alike
+	ReviewDiagnostics []ReviewDiagnostic `json:"reviewDiagnostics,omitempty"`
+	// ReviewPreview holds the findings of a review-only run (see Options.ReviewOnly); nil otherwise
+	ReviewPreview *ReviewPreview `json:"reviewPreview,omitempty"`
+
+	// InterruptedStep is the step that was in flight when the run was cancelled or its
+	// Timeout expired
+	InterruptedStep string `json:"interruptedStep,omitempty"`
+
+	// Steps lists every step that ran, in order, with its timing and outcome
+	Steps []StepOutcome `json:"steps"`
+
+	// Errors lists every step failure in order, including non-fatal ones the run continued past
+	Errors []StepError `json:"errors"`
+
+	// Retries lists the attempts of every retried operation (RetryTargetCreation, ...) in the
+	// order they finished
+	Retries []RetryResult `json:"retries"`
+}
+
+func newWorkflowResult() *WorkflowResult {
+	return &WorkflowResult{
+		StartedAt:           time.Now().UTC(),
+		ResourceIDs:         []string{},
+		ConfigurationStatus: StepNotRun,
+		ReviewStatus:        StepNotRun,
+		PublishStatus:       StepNotRun,
+		InstallStatus:       StepNotRun,
+		Steps:               []StepOutcome{},
+		Errors:              []StepError{},
+		Retries:             []RetryResult{},
+	}
+}
+
+// Succeeded reports whether every step that ran completed without error.
+func (r *WorkflowResult) Succeeded() bool {
+	return len(r.Errors) == 0
+}
+
+func (r *WorkflowResult) addError(step string, err error) {
+	r.Errors = append(r.Errors, StepError{Step: step, Message: err.Error(), Transient: IsTransient(err)})
+}
+
+// addResourceID records the ID of a created resource, skipping missing IDs.
+func (r *WorkflowResult) addResourceID(id *string) {
+	if id != nil && *id != "" {
+		r.ResourceIDs = append(r.ResourceIDs, *id)
+	}
+}
+
+// recordStep appends the outcome of a step that started at startedAt and has just finished.
+func (r *WorkflowResult) recordStep(step, resource string, startedAt time.Time, err error) {
+	outcome := StepOutcome{
+		Name:       step,
+		Resource:   resource,
+		Status:     StepSucceeded,
+		StartedAt:  startedAt.UTC(),
+		FinishedAt: time.Now().UTC(),
+	}
+	if err != nil {
+		outcome.Status = StepFailed
+		outcome.Error = err.Error()
+	}
+	r.Steps = append(r.Steps, outcome)
+}
+
+// stepStatus records err against step, if any, and returns the matching status.
+func (r *WorkflowResult) stepStatus(step string, err error) StepStatus {
+	if err != nil {
+		r.addError(step, err)
+		return StepFailed
+	}
+	return StepSucceeded
+}
@@ -0,0 +1,147 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// ResumeStore persists the resume tokens of in-flight long-running operations to a JSON file,
+// so a run that crashed or was interrupted can pick up waiting where the previous one stopped
+// instead of starting the operation again.
+type ResumeStore struct {
+	path   string
+	mu     sync.Mutex
+	tokens map[string]string // operation key -> poller resume token
+}
+
+// OpenResumeStore loads the tokens saved at path. A missing file is treated as empty.
+func OpenResumeStore(path string) (*ResumeStore, error) {
+	store := &ResumeStore{path: path, tokens: map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading resume token file %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &store.tokens); err != nil {
+		return nil, fmt.Errorf("error parsing resume token file %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Pending returns the number of operations with a saved token.
+func (s *ResumeStore) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tokens)
+}
+
+func (s *ResumeStore) token(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[key]
+}
+
+func (s *ResumeStore) set(key, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = token
+	return s.save()
+}
+
+func (s *ResumeStore) remove(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tokens[key]; !ok {
+		return nil
+	}
+	delete(s.tokens, key)
+	return s.save()
+}
+
+// save rewrites the file atomically so a crash never leaves a truncated file behind.
+func (s *ResumeStore) save() error {
+	data, err := json.MarshalIndent(s.tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding resume tokens: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("error writing resume token file: %w", err)
+	}
+	return nil
+}
+
+type resumeStoreKey struct{}
+
+// WithResumeStore returns a context whose resumable operations save their poller's resume
+// token in store while they run, and resume from a saved token when one exists.
+func WithResumeStore(ctx context.Context, store *ResumeStore) context.Context {
+	return context.WithValue(ctx, resumeStoreKey{}, store)
+}
+
+// resumeStore returns the store carried by ctx, or nil when none is set.
+func resumeStore(ctx context.Context) *ResumeStore {
+	store, _ := ctx.Value(resumeStoreKey{}).(*ResumeStore)
+	return store
+}
+
+// pollResumable starts an operation with begin and waits for it like pollUntilDone. When ctx
+// carries a ResumeStore, a token saved under key is passed to begin to rebuild the poller (the
+// SDK's Begin methods do so through runtime.NewPollerFromResumeToken), and the new poller's token
+// is saved until the operation finishes. The token is kept when the wait is cut short by
+// cancellation or a timeout, so the next run can continue it.
+func pollResumable[T any](ctx context.Context, key, operation string, state stateFunc, begin func(resumeToken string) (*runtime.Poller[T], error)) (T, error) {
+	var zero T
+	store := resumeStore(ctx)
+	if store == nil {
+		poller, err := begin("")
+		if err != nil {
+			return zero, err
+		}
+		return pollUntilDone(ctx, poller, operation, state)
+	}
+
+	var poller *runtime.Poller[T]
+	var err error
+	if token := store.token(key); token != "" {
+		loggerFrom(ctx).Info("Resuming operation from saved resume token", "operation", operation)
+		poller, err = begin(token)
+		if err != nil {
+			loggerFrom(ctx).Warn("Could not resume operation, starting it again", "operation", operation, logKeyError, err)
+			poller = nil
+		}
+	}
+	if poller == nil {
+		poller, err = begin("")
+		if err != nil {
+			return zero, err
+		}
+	}
+
+	if !poller.Done() {
+		token, err := poller.ResumeToken()
+		if err == nil {
+			err = store.set(key, token)
+		}
+		if err != nil {
+			loggerFrom(ctx).Warn("Could not save resume token", "operation", operation, logKeyError, err)
+		}
+	}
+
+	res, err := pollUntilDone(ctx, poller, operation, state)
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return res, err
+	}
+	if removeErr := store.remove(key); removeErr != nil {
+		loggerFrom(ctx).Warn("Could not clear resume token", "operation", operation, logKeyError, removeErr)
+	}
+	return res, err
+}
@@ -0,0 +1,269 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// permanentError marks an operation failure that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// unwrapPermanent strips the permanent marker from err, if it has one.
+func unwrapPermanent(err error) error {
+	var permanentErr *permanentError
+	if errors.As(err, &permanentErr) {
+		return permanentErr.err
+	}
+	return err
+}
+
+// permanent wraps err so retryOperation returns it without further attempts.
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// RetryPolicy controls how often, and how far apart, a failed operation is attempted again.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt too; values below 1 mean a single attempt
+	MaxAttempts int
+	// BaseDelay is the wait after the first failure; it doubles after each further failure
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between attempts; zero leaves it uncapped
+	MaxDelay time.Duration
+	// Jitter randomizes each wait by up to this fraction in either direction, e.g. 0.2 for ±20%
+	Jitter float64
+	// IsRetryable decides whether an error is worth another attempt; nil retries every error.
+	// Errors marked permanent and 412 Precondition Failed responses are never retried.
+	IsRetryable func(error) bool
+	// Disabled runs the operation exactly once and returns its error as is, without computing or
+	// waiting out any delay; the fields above are ignored
+	Disabled bool
+}
+
+// NoRetry runs each operation exactly once, e.g. for test environments that should fail fast.
+var NoRetry = RetryPolicy{Disabled: true}
+
+// Operations whose retry policy can be overridden with WithRetryPolicy or Options.RetryPolicies.
+const (
+	RetryContextUpdate            = "contextUpdate"
+	RetrySolutionTemplateCreation = "solutionTemplateCreation"
+	RetryTargetCreation           = "targetCreation"
+	RetryReview                   = "review"
+	RetryPublish                  = "publish"
+	RetryInstall                  = "install"
+	// RetryConfigurationAPI covers the Configuration API calls' resends of 429 and 5xx
+	// responses; only its Disabled field is honoured
+	RetryConfigurationAPI = "configurationAPI"
+)
+
+// retryOperationNames lists every operation Options.DisableRetries turns retries off for.
+var retryOperationNames = []string{RetryContextUpdate, RetrySolutionTemplateCreation, RetryTargetCreation, RetryReview, RetryPublish, RetryInstall, RetryConfigurationAPI}
+
+// DefaultRetryPolicy is used by every operation unless it or the caller says otherwise.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    5 * time.Minute,
+	Jitter:      0.2,
+}
+
+// RetryOption adjusts a RetryPolicy.
+type RetryOption func(*RetryPolicy)
+
+// WithMaxAttempts sets the total number of attempts.
+func WithMaxAttempts(attempts int) RetryOption {
+	return func(p *RetryPolicy) { p.MaxAttempts = attempts }
+}
+
+// WithBaseDelay sets the wait after the first failure.
+func WithBaseDelay(delay time.Duration) RetryOption {
+	return func(p *RetryPolicy) { p.BaseDelay = delay }
+}
+
+// WithMaxDelay caps the wait between attempts.
+func WithMaxDelay(delay time.Duration) RetryOption {
+	return func(p *RetryPolicy) { p.MaxDelay = delay }
+}
+
+// WithJitter sets the fraction by which each wait is randomized.
+func WithJitter(jitter float64) RetryOption {
+	return func(p *RetryPolicy) { p.Jitter = jitter }
+}
+
+// WithRetryable sets the function deciding which errors are retried.
+func WithRetryable(isRetryable func(error) bool) RetryOption {
+	return func(p *RetryPolicy) { p.IsRetryable = isRetryable }
+}
+
+// NewRetryPolicy returns DefaultRetryPolicy with opts applied.
+func NewRetryPolicy(opts ...RetryOption) RetryPolicy {
+	policy := DefaultRetryPolicy
+	for _, opt := range opts {
+		opt(&policy)
+	}
+	return policy
+}
+
+// delay returns the wait before attempt+1, given that attempt (1-based) just failed.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2 // Exponential backoff
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// retryable reports whether err is worth another attempt under the policy.
+func (p RetryPolicy) retryable(err error) bool {
+	var permanentErr *permanentError
+	if errors.As(err, &permanentErr) {
+		return false
+	}
+	if isPreconditionFailed(err) {
+		return false // A stale ETag fails the same way every time; the caller has to re-read
+	}
+	return p.IsRetryable == nil || p.IsRetryable(err)
+}
+
+type retryPoliciesKey struct{}
+
+// WithRetryPolicy returns a context in which the named operation (RetryTargetCreation, ...)
+// uses policy instead of its default. The policy replaces the default entirely; build it with
+// NewRetryPolicy to start from DefaultRetryPolicy.
+func WithRetryPolicy(ctx context.Context, operation string, policy RetryPolicy) context.Context {
+	policies := map[string]RetryPolicy{}
+	for name, existing := range retryPolicies(ctx) {
+		policies[name] = existing
+	}
+	policies[operation] = policy
+	return context.WithValue(ctx, retryPoliciesKey{}, policies)
+}
+
+func retryPolicies(ctx context.Context) map[string]RetryPolicy {
+	policies, _ := ctx.Value(retryPoliciesKey{}).(map[string]RetryPolicy)
+	return policies
+}
+
+// retryPolicyFor returns the policy set for operation on ctx, or def when there is none.
+func retryPolicyFor(ctx context.Context, operation string, def RetryPolicy) RetryPolicy {
+	if policy, ok := retryPolicies(ctx)[operation]; ok {
+		return policy
+	}
+	return def
+}
+
+// RetryResult records how a retried operation went, e.g. that target creation took 4 attempts
+// over 5 minutes.
+type RetryResult struct {
+	Operation string // RetryTargetCreation, ...
+	Attempts  int
+	// Elapsed runs from the first attempt's start to the last one's end, waits included
+	Elapsed time.Duration
+	// Durations holds how long each attempt took, without the waits between them
+	Durations []time.Duration
+	// Errors holds the error of each failed attempt in order; a successful last attempt has none
+	Errors []error
+}
+
+// MarshalJSON writes durations as strings such as "5m0s" and errors as their messages.
+func (r RetryResult) MarshalJSON() ([]byte, error) {
+	type attempt struct {
+		Duration string `json:"duration"`
+		Error    string `json:"error,omitempty"`
+	}
+	attempts := make([]attempt, len(r.Durations))
+	for i, duration := range r.Durations {
+		attempts[i].Duration = duration.String()
+		if i < len(r.Errors) && r.Errors[i] != nil {
+			attempts[i].Error = r.Errors[i].Error()
+		}
+	}
+	return json.Marshal(struct {
+		Operation    string    `json:"operation"`
+		Attempts     int       `json:"attempts"`
+		Elapsed      string    `json:"elapsed"`
+		AttemptTimes []attempt `json:"attemptTimes"`
+	}{r.Operation, r.Attempts, r.Elapsed.String(), attempts})
+}
+
+type retryRecorderKey struct{}
+
+// withRetryRecorder returns a context in which retryOperation passes the RetryResult of every
+// operation to record. record may be called concurrently.
+func withRetryRecorder(ctx context.Context, record func(RetryResult)) context.Context {
+	return context.WithValue(ctx, retryRecorderKey{}, record)
+}
+
+// Utility function to retry operations that might fail due to transient errors.
+// Uses exponential backoff to avoid overwhelming the service.
+// Used for resource creation operations that may temporarily fail.
+// The named operation's policy comes from ctx when the caller overrode it, and def otherwise.
+// The RetryResult goes to the context's recorder, if any; see retryOperationWithResult.
+func retryOperation(ctx context.Context, operationName string, def RetryPolicy, operation func() error) error {
+	retry, err := retryOperationWithResult(ctx, operationName, def, operation)
+	if record, ok := ctx.Value(retryRecorderKey{}).(func(RetryResult)); ok {
+		record(retry)
+	}
+	return err
+}
+
+// retryOperationWithResult is retryOperation, also returning the attempts it made.
+func retryOperationWithResult(ctx context.Context, operationName string, def RetryPolicy, operation func() error) (RetryResult, error) {
+	retry := RetryResult{Operation: operationName}
+	start := time.Now()
+	// attempt runs the operation once and records it
+	attempt := func() error {
+		attemptStart := time.Now()
+		err := operation()
+		retry.Attempts++
+		retry.Durations = append(retry.Durations, time.Since(attemptStart))
+		retry.Elapsed = time.Since(start)
+		if err != nil {
+			retry.Errors = append(retry.Errors, unwrapPermanent(err))
+		}
+		return err
+	}
+
+	policy := retryPolicyFor(ctx, operationName, def)
+	if policy.Disabled {
+		return retry, unwrapPermanent(attempt())
+	}
+	maxAttempts := max(policy.MaxAttempts, 1)
+
+	for {
+		err := attempt()
+		if err == nil {
+			return retry, nil
+		}
+
+		// Last attempt, or an error another attempt can't fix: return the error
+		if retry.Attempts >= maxAttempts || !policy.retryable(err) {
+			return retry, unwrapPermanent(err)
+		}
+
+		delay := policy.delay(retry.Attempts)
+		loggerFrom(ctx).Warn("Attempt failed, retrying", "operation", operationName, "attempt", retry.Attempts,
+			"maxAttempts", maxAttempts, "delay", delay.Round(time.Second), logKeyError, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			retry.Elapsed = time.Since(start)
+			return retry, fmt.Errorf("retry of %s cancelled after attempt %d: %w", operationName, retry.Attempts, errors.Join(err, ctx.Err()))
+		}
+	}
+}
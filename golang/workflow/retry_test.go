@@ -0,0 +1,50 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryOperationDisabledRunsOnce(t *testing.T) {
+	errFailed := errors.New("failed")
+	policies := map[string]RetryPolicy{
+		"NoRetry": NoRetry,
+		// Disabled ignores the other fields, so this must not wait an hour
+		"disabled with delays": {Disabled: true, MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour},
+	}
+	for name, policy := range policies {
+		t.Run(name, func(t *testing.T) {
+			ctx := WithRetryPolicy(testContext(), RetryInstall, policy)
+			for _, opErr := range []error{errFailed, permanent(errFailed)} {
+				var attempts int
+				start := time.Now()
+				retry, err := retryOperationWithResult(ctx, RetryInstall, DefaultRetryPolicy, func() error {
+					attempts++
+					return opErr
+				})
+				if elapsed := time.Since(start); elapsed > time.Second {
+					t.Errorf("took %v, want no wait", elapsed)
+				}
+				if attempts != 1 || retry.Attempts != 1 {
+					t.Errorf("attempts = %d (recorded %d), want 1", attempts, retry.Attempts)
+				}
+				if err != errFailed {
+					t.Errorf("error = %#v, want the operation's error as is", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryOperationDisabledSucceeds(t *testing.T) {
+	ctx := WithRetryPolicy(testContext(), RetryPublish, NoRetry)
+	var attempts int
+	err := retryOperation(ctx, RetryPublish, DefaultRetryPolicy, func() error {
+		attempts++
+		return nil
+	})
+	if err != nil || attempts != 1 {
+		t.Errorf("retryOperation = %v after %d attempts, want nil after 1", err, attempts)
+	}
+}
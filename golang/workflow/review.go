@@ -0,0 +1,314 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// DiagnosticSeverity grades a review diagnostic.
+type DiagnosticSeverity string
+
+const (
+	// DiagnosticError blocks the solution from being deployed on the target.
+	DiagnosticError DiagnosticSeverity = "error"
+	// DiagnosticWarning is worth a look but doesn't block deployment.
+	DiagnosticWarning DiagnosticSeverity = "warning"
+)
+
+// ReviewDiagnostic is one finding of a review, e.g. a configuration value the schema rejects.
+type ReviewDiagnostic struct {
+	Severity DiagnosticSeverity `json:"severity"`
+	Field    string             `json:"field,omitempty"` // What the finding is about; empty when the service doesn't say
+	Code     string             `json:"code,omitempty"`
+	Message  string             `json:"message"`
+}
+
+func (d ReviewDiagnostic) String() string {
+	message := d.Message
+	if d.Field != "" {
+		message = d.Field + ": " + message
+	}
+	if d.Code != "" {
+		message += " (" + d.Code + ")"
+	}
+	return fmt.Sprintf("%s: %s", d.Severity, message)
+}
+
+// ReviewPreview is the outcome of PreviewReview.
+type ReviewPreview struct {
+	// Configuration is the configuration the solution would get on the target, as the service
+	// resolved it; empty when the preview found errors
+	Configuration string             `json:"configuration,omitempty"`
+	Diagnostics   []ReviewDiagnostic `json:"diagnostics"`
+}
+
+// Clean reports whether the preview found no error-severity diagnostics; warnings don't count.
+func (p *ReviewPreview) Clean() bool {
+	return !hasErrorDiagnostics(p.Diagnostics)
+}
+
+// ReviewError reports a review the target rejected, with the diagnostics that explain why; at
+// least one of them is an error. Err is the service's error, when the review operation itself failed.
+type ReviewError struct {
+	TargetName  string
+	Diagnostics []ReviewDiagnostic
+	Err         error
+}
+
+func (e *ReviewError) Error() string {
+	message := fmt.Sprintf("target %s rejected the solution", e.TargetName)
+	var errs []string
+	for _, diagnostic := range e.Diagnostics {
+		if diagnostic.Severity == DiagnosticError {
+			errs = append(errs, diagnostic.String())
+		}
+	}
+	if len(errs) > 0 {
+		message += ": " + strings.Join(errs, "; ")
+	}
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *ReviewError) Unwrap() error {
+	return e.Err
+}
+
+// hasErrorDiagnostics reports whether any of diagnostics is an error.
+func hasErrorDiagnostics(diagnostics []ReviewDiagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == DiagnosticError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validates a solution template version against a target without reviewing it, so nothing the
+// target could publish is created. The review API has no validate-only mode, so this asks the
+// target to resolve the version's configuration instead, which runs the same checks of the
+// configuration values against the schema and of the components against the target.
+// A version the target rejects is not an error: the preview carries the rejection as
+// diagnostics, so callers can gate publishing on ReviewPreview.Clean. Errors are returned when
+// the service couldn't be asked, e.g. on network failures, or failed other than by rejecting
+// the version (see isReviewRejection), e.g. with 403 AuthorizationFailed.
+// With dryRun set, nothing is submitted and a clean preview is returned.
+func PreviewReview(ctx context.Context, client TargetsAPI, resourceGroupName, targetName, solutionTemplateVersionID string, dryRun bool) (*ReviewPreview, error) {
+	if err := validateSolutionTemplateVersionID(solutionTemplateVersionID); err != nil {
+		return nil, err
+	}
+	if dryRun {
+		logDryRun(ctx, "resolveConfiguration", "Microsoft.Edge/targets", targetName, map[string]interface{}{
+			"solutionTemplateVersionId": solutionTemplateVersionID,
+		})
+		return &ReviewPreview{Diagnostics: []ReviewDiagnostic{}}, nil
+	}
+
+	preview := &ReviewPreview{Diagnostics: []ReviewDiagnostic{}}
+	previewOperation := func() error {
+		loggerFrom(ctx).Info("Validating solution template version", logKeyResource, targetName, "solutionTemplateVersionId", solutionTemplateVersionID)
+
+		poller, err := client.BeginResolveConfiguration(ctx, resourceGroupName, targetName, armworkloadorchestration.SolutionTemplateParameter{
+			SolutionTemplateVersionID: to.Ptr(solutionTemplateVersionID),
+		}, nil)
+		var res armworkloadorchestration.TargetsClientResolveConfigurationResponse
+		if err == nil {
+			res, err = pollUntilDone(ctx, poller, "review preview", targetState(client, resourceGroupName, targetName))
+		}
+		if err != nil {
+			// A rejection comes back as a validation failure listing what is wrong; any other
+			// failure, e.g. a 403 or a 409, is returned like any other
+			if diagnostics := reviewDiagnostics(err); isReviewRejection(err, diagnostics) {
+				preview.Diagnostics = diagnostics
+				return nil
+			}
+			return err
+		}
+		preview.Configuration = stringValue(res.Configuration)
+		return nil
+	}
+
+	if err := retryOperation(ctx, RetryReview, DefaultRetryPolicy, previewOperation); err != nil {
+		return nil, fmt.Errorf("error validating solution on target: %w", err)
+	}
+	logDiagnostics(ctx, targetName, preview.Diagnostics)
+	loggerFrom(ctx).Info("Validation completed", logKeyResource, targetName, "diagnostics", len(preview.Diagnostics), "clean", preview.Clean())
+	return preview, nil
+}
+
+// logDiagnostics logs each of a review's diagnostics with its severity, field and code.
+func logDiagnostics(ctx context.Context, targetName string, diagnostics []ReviewDiagnostic) {
+	for _, diagnostic := range diagnostics {
+		loggerFrom(ctx).Warn("Review diagnostic", logKeyResource, targetName, "severity", diagnostic.Severity, "field", diagnostic.Field, "code", diagnostic.Code, "message", diagnostic.Message)
+	}
+}
+
+// armErrorDetail is the ARM error body with the fields diagnostics are built from.
+type armErrorDetail struct {
+	Code           string                   `json:"code"`
+	Message        string                   `json:"message"`
+	Target         string                   `json:"target"`
+	Details        []armErrorDetail         `json:"details"`
+	AdditionalInfo []armErrorAdditionalInfo `json:"additionalInfo"`
+}
+
+type armErrorAdditionalInfo struct {
+	Type string          `json:"type"`
+	Info json.RawMessage `json:"info"`
+}
+
+// validationErrorCodes are the ARM error codes the service uses for a solution that fails
+// validation, whatever the HTTP status they come with.
+var validationErrorCodes = map[string]bool{
+	"ValidationFailed":       true,
+	"SchemaValidationFailed": true,
+	"InvalidConfiguration":   true,
+	"ReviewFailed":           true,
+}
+
+// isReviewRejection reports whether err is the target rejecting the solution, so that reviewing
+// it again can't succeed: a validation failure (HTTP 400 or 422, a validation error code, or a
+// ValidationResult in the additional info) whose diagnostics include an error. Anything else,
+// e.g. a 403 AuthorizationFailed, a 404 or a 409 Conflict, or a rejection naming only warnings,
+// is left to the retry policy.
+func isReviewRejection(err error, diagnostics []ReviewDiagnostic) bool {
+	if !hasErrorDiagnostics(diagnostics) || IsTransient(err) {
+		return false
+	}
+	respErr, detail := armError(err)
+	if respErr == nil {
+		return false
+	}
+	if respErr.StatusCode == http.StatusBadRequest || respErr.StatusCode == http.StatusUnprocessableEntity {
+		return true
+	}
+	return detail != nil && isValidationFailure(*detail)
+}
+
+// isValidationFailure reports whether detail, or any detail nested in it, has a validation
+// error code or a ValidationResult additional info.
+func isValidationFailure(detail armErrorDetail) bool {
+	if validationErrorCodes[detail.Code] {
+		return true
+	}
+	for _, additional := range detail.AdditionalInfo {
+		if additional.Type == "ValidationResult" {
+			return true
+		}
+	}
+	for _, inner := range detail.Details {
+		if isValidationFailure(inner) {
+			return true
+		}
+	}
+	return false
+}
+
+// armError returns the response error behind err and the ARM error body it carries; the body
+// is nil when the response has none, and both are nil when err isn't a response error.
+func armError(err error) (*azcore.ResponseError, *armErrorDetail) {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return nil, nil
+	}
+	if respErr.RawResponse == nil {
+		return respErr, nil
+	}
+	body, readErr := runtime.Payload(respErr.RawResponse)
+	if readErr != nil {
+		return respErr, nil
+	}
+	var envelope struct {
+		Error *armErrorDetail `json:"error"`
+	}
+	if json.Unmarshal(body, &envelope) != nil {
+		return respErr, nil
+	}
+	return respErr, envelope.Error
+}
+
+// reviewDiagnostics lists the findings in the ARM error body behind err: one diagnostic per
+// innermost detail, or the error itself when it has no details. Nil when err carries no body.
+func reviewDiagnostics(err error) []ReviewDiagnostic {
+	_, detail := armError(err)
+	if detail == nil {
+		return nil
+	}
+	return appendDiagnostics(nil, *detail)
+}
+
+// solutionVersionDiagnostics lists the findings a review recorded on the solution version.
+func solutionVersionDiagnostics(version armworkloadorchestration.SolutionVersion) []ReviewDiagnostic {
+	if version.Properties == nil || version.Properties.ErrorDetails == nil {
+		return nil
+	}
+	return appendDiagnostics(nil, fromSDKErrorDetail(version.Properties.ErrorDetails))
+}
+
+// fromSDKErrorDetail converts the SDK's error detail into the ARM error body it was read from.
+func fromSDKErrorDetail(detail *armworkloadorchestration.ErrorDetail) armErrorDetail {
+	converted := armErrorDetail{
+		Code:    stringValue(detail.Code),
+		Message: stringValue(detail.Message),
+		Target:  stringValue(detail.Target),
+	}
+	for _, inner := range detail.Details {
+		if inner != nil {
+			converted.Details = append(converted.Details, fromSDKErrorDetail(inner))
+		}
+	}
+	for _, additional := range detail.AdditionalInfo {
+		if additional == nil {
+			continue
+		}
+		info, err := json.Marshal(additional.Info)
+		if err != nil {
+			continue
+		}
+		converted.AdditionalInfo = append(converted.AdditionalInfo, armErrorAdditionalInfo{Type: stringValue(additional.Type), Info: info})
+	}
+	return converted
+}
+
+func appendDiagnostics(diagnostics []ReviewDiagnostic, detail armErrorDetail) []ReviewDiagnostic {
+	if len(detail.Details) > 0 {
+		for _, inner := range detail.Details {
+			diagnostics = appendDiagnostics(diagnostics, inner)
+		}
+		return diagnostics
+	}
+	if detail.Message == "" && detail.Code == "" {
+		return diagnostics
+	}
+	return append(diagnostics, ReviewDiagnostic{
+		Severity: diagnosticSeverity(detail),
+		Field:    detail.Target,
+		Code:     detail.Code,
+		Message:  detail.Message,
+	})
+}
+
+// diagnosticSeverity reads the severity some services put in an error's additional info,
+// e.g. {"type": "ValidationResult", "info": {"severity": "Warning"}}. Anything else is an error.
+func diagnosticSeverity(detail armErrorDetail) DiagnosticSeverity {
+	for _, additional := range detail.AdditionalInfo {
+		var info struct {
+			Severity string `json:"severity"`
+		}
+		if json.Unmarshal(additional.Info, &info) == nil && strings.EqualFold(info.Severity, string(DiagnosticWarning)) {
+			return DiagnosticWarning
+		}
+	}
+	return DiagnosticError
+}
@@ -0,0 +1,145 @@
+package workflow
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// reviewRejection is the error response of a review that failed with the given details.
+func reviewRejection(details ...map[string]any) error {
+	return runtime.NewResponseError(fakeResponse(http.MethodPost, http.StatusBadRequest, map[string]any{
+		"error": map[string]any{"code": "ReviewFailed", "message": "review failed", "details": details},
+	}))
+}
+
+var (
+	schemaMismatch = map[string]any{"code": "SchemaMismatch", "target": "ErrorThreshold", "message": "value must be a number"}
+	unusedValue    = map[string]any{
+		"code": "UnusedValue", "target": "Extra", "message": "value is not used",
+		"additionalInfo": []any{map[string]any{"type": "ValidationResult", "info": map[string]any{"severity": "Warning"}}},
+	}
+)
+
+func TestReviewTargetFailures(t *testing.T) {
+	templateVersionID := "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Edge/solutionTemplates/app/versions/1.0.0"
+	tests := []struct {
+		name            string
+		err             error
+		wantReviewError bool
+		wantAttempts    int
+		wantDiagnostics int
+	}{
+		{name: "error diagnostics are a rejection", err: reviewRejection(schemaMismatch, unusedValue), wantReviewError: true, wantAttempts: 1, wantDiagnostics: 2},
+		{name: "warnings alone are retried", err: reviewRejection(unusedValue), wantAttempts: 2, wantDiagnostics: 1},
+		{name: "transient failures are retried", err: responseError(http.StatusServiceUnavailable, "ServiceUnavailable", "try again"), wantAttempts: 2, wantDiagnostics: 1},
+		{name: "authorization failures are retried", err: responseError(http.StatusForbidden, "AuthorizationFailed", "no permission to review"), wantAttempts: 2, wantDiagnostics: 1},
+		{name: "conflicts are retried", err: responseError(http.StatusConflict, "Conflict", "another operation is in progress"), wantAttempts: 2, wantDiagnostics: 1},
+		{name: "validation codes are a rejection whatever the status", err: responseError(http.StatusConflict, "ValidationFailed", "value must be a number"), wantReviewError: true, wantAttempts: 1, wantDiagnostics: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithRetryPolicy(testContext(), RetryReview, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+			var attempts int
+			client := &fakeTargets{
+				review: func(armworkloadorchestration.SolutionTemplateParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientReviewSolutionVersionResponse], error) {
+					attempts++
+					return nil, tt.err
+				},
+			}
+
+			_, diagnostics, err := ReviewTarget(ctx, client, &fakeSolutions{}, &fakeSolutionVersions{}, "rg", "target", templateVersionID, false)
+			if err == nil {
+				t.Fatal("ReviewTarget succeeded")
+			}
+			var reviewErr *ReviewError
+			if got := errors.As(err, &reviewErr); got != tt.wantReviewError {
+				t.Errorf("error is a *ReviewError = %t, want %t: %v", got, tt.wantReviewError, err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if len(diagnostics) != tt.wantDiagnostics {
+				t.Errorf("diagnostics = %v, want %d", diagnostics, tt.wantDiagnostics)
+			}
+		})
+	}
+}
+
+func TestPreviewReviewFailures(t *testing.T) {
+	templateVersionID := "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Edge/solutionTemplates/app/versions/1.0.0"
+	tests := []struct {
+		name         string
+		err          error
+		wantErr      bool
+		wantAttempts int
+	}{
+		{name: "a rejection is a preview with diagnostics", err: reviewRejection(schemaMismatch), wantAttempts: 1},
+		{name: "authorization failures are errors", err: responseError(http.StatusForbidden, "AuthorizationFailed", "no permission to review"), wantErr: true, wantAttempts: 2},
+		{name: "conflicts are errors", err: responseError(http.StatusConflict, "Conflict", "another operation is in progress"), wantErr: true, wantAttempts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithRetryPolicy(testContext(), RetryReview, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+			var attempts int
+			client := &fakeTargets{
+				resolve: func(armworkloadorchestration.SolutionTemplateParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientResolveConfigurationResponse], error) {
+					attempts++
+					return nil, tt.err
+				},
+			}
+
+			preview, err := PreviewReview(ctx, client, "rg", "target", templateVersionID, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PreviewReview error = %v, want an error: %t", err, tt.wantErr)
+			}
+			if err == nil && preview.Clean() {
+				t.Error("a rejected version previewed as clean")
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestReviewErrorMessage(t *testing.T) {
+	mismatch := ReviewDiagnostic{Severity: DiagnosticError, Field: "ErrorThreshold", Code: "SchemaMismatch", Message: "value must be a number"}
+	unused := ReviewDiagnostic{Severity: DiagnosticWarning, Field: "Extra", Message: "value is not used"}
+	tests := []struct {
+		name string
+		err  *ReviewError
+		want string
+	}{
+		{
+			name: "error diagnostics only",
+			err:  &ReviewError{TargetName: "target", Diagnostics: []ReviewDiagnostic{unused, mismatch}},
+			want: "target target rejected the solution: error: ErrorThreshold: value must be a number (SchemaMismatch)",
+		},
+		{
+			name: "with the service error",
+			err:  &ReviewError{TargetName: "target", Diagnostics: []ReviewDiagnostic{mismatch}, Err: errors.New("review failed")},
+			want: "target target rejected the solution: error: ErrorThreshold: value must be a number (SchemaMismatch): review failed",
+		},
+		{
+			name: "no error diagnostics",
+			err:  &ReviewError{TargetName: "target", Diagnostics: []ReviewDiagnostic{unused}, Err: errors.New("review failed")},
+			want: "target target rejected the solution: review failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+			if strings.HasSuffix(tt.err.Error(), ": ") {
+				t.Errorf("Error() = %q ends in an empty message", tt.err.Error())
+			}
+		})
+	}
+}
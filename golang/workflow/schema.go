@@ -0,0 +1,275 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// Creates a new schema resource in Azure Workload Orchestration.
+// This is the foundation step - defines the container for configuration rules.
+// Must be created before creating schema versions. Think of it as creating a "database"
+// before adding "tables" (schema versions).
+// An empty schemaName picks an unused "sdkexamples-schema-v<version>" name.
+// tags are applied to the schema (see RunTags).
+// With dryRun set, only the name lookup runs and a synthetic schema is returned.
+func CreateSchema(ctx context.Context, client SchemasAPI, resourceGroupName, schemaName, location string, tags map[string]string, dryRun bool) (*armworkloadorchestration.Schema, error) {
+	if schemaName == "" {
+		var err error
+		schemaName, err = pickSchemaName(ctx, client, resourceGroupName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	loggerFrom(ctx).Info("Creating schema", logKeyResource, schemaName, "resourceGroup", resourceGroupName)
+
+	if dryRun {
+		logDryRun(ctx, "create", "Microsoft.Edge/schemas", schemaName, map[string]interface{}{
+			"resourceGroup": resourceGroupName,
+			"location":      location,
+			"tags":          tags,
+		})
+		return &armworkloadorchestration.Schema{
+			ID:         to.Ptr(dryRunResourceID(resourceGroupName, "schemas", schemaName)),
+			Name:       to.Ptr(schemaName),
+			Location:   to.Ptr(location),
+			Tags:       toSDKTags(tags),
+			Properties: &armworkloadorchestration.SchemaProperties{},
+		}, nil
+	}
+
+	poller, err := client.BeginCreateOrUpdate(ctx, resourceGroupName, schemaName, armworkloadorchestration.Schema{
+		Location:   to.Ptr(location),
+		Tags:       toSDKTags(tags),
+		Properties: &armworkloadorchestration.SchemaProperties{},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating schema: %w", err)
+	}
+
+	res, err := pollUntilDone(ctx, poller, "schema creation", func(ctx context.Context) (*armworkloadorchestration.ProvisioningState, error) {
+		current, err := client.Get(ctx, resourceGroupName, schemaName, nil)
+		if err != nil || current.Properties == nil {
+			return nil, err
+		}
+		return current.Properties.ProvisioningState, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error polling schema creation: %w", err)
+	}
+
+	loggerFrom(ctx).Info("Schema created", logKeyResource, *res.Name, logKeyState, provisioningState(&res.Schema))
+	return &res.Schema, nil
+}
+
+// GetOrCreateSchema returns the schema named schemaName when it already exists, and creates it
+// with CreateSchema otherwise, so re-running with the same names reuses what an earlier run made.
+// An empty schemaName always creates a schema under a fresh name.
+func GetOrCreateSchema(ctx context.Context, client SchemasAPI, resourceGroupName, schemaName, location string, tags map[string]string, dryRun bool) (*armworkloadorchestration.Schema, error) {
+	if schemaName != "" {
+		existing, err := client.Get(ctx, resourceGroupName, schemaName, nil)
+		if err == nil {
+			loggerFrom(ctx).Info("Schema already exists, reusing it", logKeyResource, schemaName)
+			return &existing.Schema, nil
+		}
+		if !isNotFound(err) {
+			return nil, fmt.Errorf("error checking for existing schema %s: %w", schemaName, err)
+		}
+	}
+	return CreateSchema(ctx, client, resourceGroupName, schemaName, location, tags, dryRun)
+}
+
+// pickSchemaName finds an unused "sdkexamples-schema-v<version>" name.
+// Schema names embed the version, so a taken version means the schema already exists.
+func pickSchemaName(ctx context.Context, client SchemasAPI, resourceGroupName string) (string, error) {
+	version, err := pickUniqueVersion(ctx, func() string {
+		return GenerateRandomSemanticVersion(randomFrom(ctx), false, false)
+	}, func(candidate string) (bool, error) {
+		_, err := client.Get(ctx, resourceGroupName, fmt.Sprintf("sdkexamples-schema-v%s", candidate), nil)
+		if err == nil {
+			return true, nil
+		}
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error checking existing schema: %w", err)
+	}, maxVersionAttempts)
+	if err == nil {
+		err = ValidateSemanticVersion(version)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error choosing schema name: %w", err)
+	}
+	return fmt.Sprintf("sdkexamples-schema-v%s", version), nil
+}
+
+// Creates a version for an existing schema with specific YAML configuration rules.
+// PREREQUISITE: Schema must already exist (created by CreateSchema).
+// This defines the actual validation rules for configuration values that will be used
+// by solution templates. Contains data types, required fields, and editing permissions.
+// Uses the built-in soap/hotmelt rules when rules is nil.
+// The version is random unless versionBump ("major", "minor" or "patch") is set, which bumps the
+// schema's highest existing version instead (see NextSemanticVersion).
+// With dryRun set, the rendered rules are printed and a synthetic version is returned.
+func CreateSchemaVersion(ctx context.Context, client SchemaVersionsAPI, resourceGroupName, schemaName string, rules []SchemaRule, versionBump string, dryRun bool) (*armworkloadorchestration.SchemaVersion, error) {
+	loggerFrom(ctx).Info("Creating schema version", logKeyResource, schemaName)
+
+	if rules == nil {
+		rules = DefaultSchemaRules
+	}
+	schemaValue, err := BuildSchemaValue(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// A schema that doesn't exist yet, e.g. one a dry run only pretended to create, has no versions
+	versions, err := ListSchemaVersions(ctx, client, resourceGroupName, schemaName)
+	if err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("error listing existing schema versions: %w", err)
+	}
+	existingVersions := make(map[string]bool)
+	var versionNames []string
+	for _, v := range versions {
+		if v.Name != nil {
+			existingVersions[*v.Name] = true
+			versionNames = append(versionNames, *v.Name)
+		}
+	}
+
+	var schemaVersionName string
+	if versionBump != "" {
+		schemaVersionName, err = NextSemanticVersion(versionNames, versionBump)
+	} else {
+		schemaVersionName, err = pickUniqueVersion(ctx, func() string {
+			return GenerateRandomSemanticVersion(randomFrom(ctx), false, false)
+		}, func(candidate string) (bool, error) {
+			return existingVersions[candidate], nil
+		}, maxVersionAttempts)
+	}
+	if err == nil {
+		err = ValidateSemanticVersion(schemaVersionName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error choosing schema version for %s: %w", schemaName, err)
+	}
+
+	if dryRun {
+		logDryRun(ctx, "create", "Microsoft.Edge/schemas/versions", schemaName+"/"+schemaVersionName, map[string]interface{}{
+			"rules": len(rules),
+			"value": "\n" + schemaValue,
+		})
+		return &armworkloadorchestration.SchemaVersion{
+			ID:   to.Ptr(dryRunResourceID(resourceGroupName, "schemas", schemaName, "versions", schemaVersionName)),
+			Name: to.Ptr(schemaVersionName),
+			Properties: &armworkloadorchestration.SchemaVersionProperties{
+				Value: to.Ptr(schemaValue),
+			},
+		}, nil
+	}
+
+	poller, err := client.BeginCreateOrUpdate(ctx, resourceGroupName, schemaName, schemaVersionName, armworkloadorchestration.SchemaVersion{
+		Properties: &armworkloadorchestration.SchemaVersionProperties{
+			Value: to.Ptr(schemaValue),
+		},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating schema version: %w", err)
+	}
+
+	res, err := pollUntilDone(ctx, poller, "schema version creation", func(ctx context.Context) (*armworkloadorchestration.ProvisioningState, error) {
+		current, err := client.Get(ctx, resourceGroupName, schemaName, schemaVersionName, nil)
+		if err != nil || current.Properties == nil {
+			return nil, err
+		}
+		return current.Properties.ProvisioningState, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error polling schema version creation: %w", err)
+	}
+
+	loggerFrom(ctx).Info("Schema version created", logKeyResource, schemaName, "version", *res.Name)
+	return &res.SchemaVersion, nil
+}
+
+// Evolves an existing schema's rules by creating a new version, a minor bump of the schema's
+// highest version, that holds newRules. Older versions are left intact, so solution template
+// versions that reference them keep working; only new template versions pick up the new rules.
+// Returns the new version's name.
+func UpdateSchemaRules(ctx context.Context, client SchemaVersionsAPI, resourceGroupName, schemaName string, newRules []SchemaRule, dryRun bool) (string, error) {
+	if len(newRules) == 0 {
+		return "", fmt.Errorf("updated schema %s must contain at least one rule", schemaName)
+	}
+	version, err := CreateSchemaVersion(ctx, client, resourceGroupName, schemaName, newRules, string(armworkloadorchestration.UpdateTypeMinor), dryRun)
+	if err != nil {
+		return "", fmt.Errorf("error updating rules of schema %s: %w", schemaName, err)
+	}
+	return stringValue(version.Name), nil
+}
+
+// Deletes a single schema version and waits for the deletion to finish.
+// A version that is already gone counts as deleted.
+func DeleteSchemaVersion(ctx context.Context, client SchemaVersionsAPI, resourceGroupName, schemaName, version string) error {
+	loggerFrom(ctx).Info("Deleting schema version", logKeyResource, schemaName, "version", version)
+
+	poller, err := client.BeginDelete(ctx, resourceGroupName, schemaName, version, nil)
+	if err != nil {
+		if isNotFound(err) {
+			loggerFrom(ctx).Info("Schema version already deleted", logKeyResource, schemaName, "version", version)
+			return nil
+		}
+		return fmt.Errorf("error deleting schema version: %w", err)
+	}
+
+	if _, err := pollUntilDone(ctx, poller, "schema version deletion", nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("error polling schema version deletion: %w", err)
+	}
+
+	loggerFrom(ctx).Info("Schema version deleted", logKeyResource, schemaName, "version", version)
+	return nil
+}
+
+// Deletes a schema and waits for the deletion to finish.
+// PREREQUISITE: All schema versions must be deleted first (DeleteSchemaVersion).
+// A schema that is already gone counts as deleted.
+func DeleteSchema(ctx context.Context, client SchemasAPI, versionsClient SchemaVersionsAPI, resourceGroupName, schemaName string) error {
+	loggerFrom(ctx).Info("Deleting schema", logKeyResource, schemaName)
+
+	versions, err := ListSchemaVersions(ctx, versionsClient, resourceGroupName, schemaName)
+	if err != nil {
+		if isNotFound(err) {
+			loggerFrom(ctx).Info("Schema already deleted", logKeyResource, schemaName)
+			return nil
+		}
+		return fmt.Errorf("error listing schema versions: %w", err)
+	}
+	var remaining []string
+	for _, v := range versions {
+		if v.Name != nil {
+			remaining = append(remaining, *v.Name)
+		}
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("schema %s still has versions %s; delete them first with DeleteSchemaVersion",
+			schemaName, strings.Join(remaining, ", "))
+	}
+
+	poller, err := client.BeginDelete(ctx, resourceGroupName, schemaName, nil)
+	if err != nil {
+		if isNotFound(err) {
+			loggerFrom(ctx).Info("Schema already deleted", logKeyResource, schemaName)
+			return nil
+		}
+		return fmt.Errorf("error deleting schema: %w", err)
+	}
+
+	if _, err := pollUntilDone(ctx, poller, "schema deletion", nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("error polling schema deletion: %w", err)
+	}
+
+	loggerFrom(ctx).Info("Schema deleted", logKeyResource, schemaName)
+	return nil
+}
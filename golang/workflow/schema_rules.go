@@ -0,0 +1,312 @@
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaRule describes one configuration field in a schema version. EditableAt and EditableBy
+// are set per field, e.g. a threshold editable at "factory" by "IT" next to an endpoint
+// editable at "line" by "OT"; see ValidateSchemaRuleAttributes.
+type SchemaRule struct {
+	Name       string   `yaml:"name"`
+	Type       string   `yaml:"type"` // float, string, boolean, ...
+	Required   bool     `yaml:"required"`
+	EditableAt []string `yaml:"editableAt"` // Hierarchy levels where the value may be set
+	EditableBy []string `yaml:"editableBy"` // Roles allowed to set the value; one of SchemaRoles
+}
+
+// SchemaRoles are the roles a schema rule's EditableBy may name: IT for values managed
+// centrally, OT for values set by operations on the shop floor.
+var SchemaRoles = []string{"IT", "OT"}
+
+// schemaRuleBody is the YAML layout of a single rule under rules.configs.
+type schemaRuleBody struct {
+	Type       string   `yaml:"type"`
+	Required   bool     `yaml:"required"`
+	EditableAt []string `yaml:"editableAt"`
+	EditableBy []string `yaml:"editableBy"`
+}
+
+// Default schema rules for the soap/hotmelt solution.
+var DefaultSchemaRules = []SchemaRule{
+	{Name: "ErrorThreshold", Type: "float", Required: true, EditableAt: []string{"line"}, EditableBy: []string{"OT"}},
+	{Name: "HealthCheckEndpoint", Type: "string", Required: false, EditableAt: []string{"line"}, EditableBy: []string{"OT"}},
+	{Name: "EnableLocalLog", Type: "boolean", Required: true, EditableAt: []string{"line"}, EditableBy: []string{"OT"}},
+	{Name: "AgentEndpoint", Type: "string", Required: true, EditableAt: []string{"line"}, EditableBy: []string{"OT"}},
+	{Name: "HealthCheckEnabled", Type: "boolean", Required: false, EditableAt: []string{"line"}, EditableBy: []string{"OT"}},
+	{Name: "ApplicationEndpoint", Type: "string", Required: true, EditableAt: []string{"line"}, EditableBy: []string{"OT"}},
+	{Name: "TemperatureRangeMax", Type: "float", Required: true, EditableAt: []string{"line"}, EditableBy: []string{"OT"}},
+}
+
+// BuildSchemaValue renders rules into the schema version YAML layout:
+//
+//	rules:
+//	  configs:
+//	    <Name>:
+//	      type: ...
+//
+// Fields keep the order they are given in. Names must be non-empty and unique.
+func BuildSchemaValue(rules []SchemaRule) (string, error) {
+	if len(rules) == 0 {
+		return "", fmt.Errorf("schema must contain at least one rule")
+	}
+
+	configs := &yaml.Node{Kind: yaml.MappingNode}
+	seen := make(map[string]bool)
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return "", fmt.Errorf("schema rule at index %d has no name", i)
+		}
+		if seen[rule.Name] {
+			return "", fmt.Errorf("duplicate schema rule %s", rule.Name)
+		}
+		seen[rule.Name] = true
+		if rule.Type == "" {
+			return "", fmt.Errorf("schema rule %s has no type", rule.Name)
+		}
+
+		var body yaml.Node
+		if err := body.Encode(schemaRuleBody{
+			Type:       rule.Type,
+			Required:   rule.Required,
+			EditableAt: rule.EditableAt,
+			EditableBy: rule.EditableBy,
+		}); err != nil {
+			return "", fmt.Errorf("error encoding schema rule %s: %w", rule.Name, err)
+		}
+		configs.Content = append(configs.Content, scalarNode(rule.Name), &body)
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		scalarNode("rules"),
+		{Kind: yaml.MappingNode, Content: []*yaml.Node{scalarNode("configs"), configs}},
+	}}
+
+	value, err := encodeYAML(root)
+	if err != nil {
+		return "", fmt.Errorf("error encoding schema rules: %w", err)
+	}
+	return value, nil
+}
+
+// Checks each rule's EditableAt against levels, the hierarchy level names of the context the
+// schema is used with, and its EditableBy against SchemaRoles. Both must name at least one
+// value. Returns all violations at once.
+func ValidateSchemaRuleAttributes(rules []SchemaRule, levels []string) error {
+	var violations []string
+	for _, rule := range rules {
+		if len(rule.EditableAt) == 0 {
+			violations = append(violations, fmt.Sprintf("%s: editableAt names no hierarchy level", rule.Name))
+		}
+		for _, level := range rule.EditableAt {
+			if !slices.Contains(levels, level) {
+				violations = append(violations, fmt.Sprintf("%s: editableAt level %q is not a hierarchy level (valid: %s)", rule.Name, level, strings.Join(levels, ", ")))
+			}
+		}
+		if len(rule.EditableBy) == 0 {
+			violations = append(violations, fmt.Sprintf("%s: editableBy names no role", rule.Name))
+		}
+		for _, role := range rule.EditableBy {
+			if !slices.Contains(SchemaRoles, role) {
+				violations = append(violations, fmt.Sprintf("%s: editableBy role %q is not a known role (valid: %s)", rule.Name, role, strings.Join(SchemaRoles, ", ")))
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("schema rules have invalid attributes:\n  %s", strings.Join(violations, "\n  "))
+	}
+	return nil
+}
+
+// schemaRuleNames lists the names of rules in order.
+func schemaRuleNames(rules []SchemaRule) []string {
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+// buildConfigurations renders a solution template version's configurations:
+//
+//	schema:
+//	  name: <schemaName>
+//	  version: <schemaVersion>
+//	configs:
+//	  <literal>: <value>
+//	  <ref>: ${{$val(<ref>)}}
+//
+// Literals come first, sorted by key, followed by a $val reference for each of refs in the order
+// given. Pass the schema's rule names as refs (see schemaRuleNames) so every schema field is
+// referenced. Refs must be non-empty and unique, and no literal may share a ref's name.
+func buildConfigurations(schemaName, schemaVersion string, refs []string, literals map[string]string) (string, error) {
+	if schemaName == "" || schemaVersion == "" {
+		return "", fmt.Errorf("configurations need a schema name and version")
+	}
+
+	configs := &yaml.Node{Kind: yaml.MappingNode}
+	keys := make([]string, 0, len(literals))
+	for key := range literals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "" {
+			return "", fmt.Errorf("configuration literal has no name")
+		}
+		configs.Content = append(configs.Content, scalarNode(key), stringNode(literals[key]))
+	}
+
+	seen := make(map[string]bool)
+	for i, ref := range refs {
+		if ref == "" {
+			return "", fmt.Errorf("configuration reference at index %d has no name", i)
+		}
+		if seen[ref] {
+			return "", fmt.Errorf("duplicate configuration reference %s", ref)
+		}
+		seen[ref] = true
+		if _, ok := literals[ref]; ok {
+			return "", fmt.Errorf("configuration %s is both a literal and a schema reference", ref)
+		}
+		configs.Content = append(configs.Content, scalarNode(ref), scalarNode(fmt.Sprintf("${{$val(%s)}}", ref)))
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		scalarNode("schema"),
+		{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			scalarNode("name"), stringNode(schemaName),
+			scalarNode("version"), stringNode(schemaVersion),
+		}},
+		scalarNode("configs"), configs,
+	}}
+
+	value, err := encodeYAML(root)
+	if err != nil {
+		return "", fmt.Errorf("error encoding configurations: %w", err)
+	}
+	return value, nil
+}
+
+// encodeYAML renders node with the two-space indentation the service's documents use.
+func encodeYAML(node *yaml.Node) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Reads schema rules from YAML in the same layout BuildSchemaValue produces.
+// The YAML must parse and contain a top-level "rules" key; field order is preserved.
+func LoadSchemaRules(r io.Reader) ([]SchemaRule, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema rules: %w", err)
+	}
+
+	var doc struct {
+		Rules *struct {
+			Configs yaml.Node `yaml:"configs"`
+		} `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("schema rules are not valid YAML: %w", err)
+	}
+	if doc.Rules == nil {
+		return nil, fmt.Errorf("schema rules must contain a top-level \"rules\" key")
+	}
+	if doc.Rules.Configs.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("schema rules must contain a \"rules.configs\" mapping")
+	}
+
+	content := doc.Rules.Configs.Content
+	rules := make([]SchemaRule, 0, len(content)/2)
+	for i := 0; i+1 < len(content); i += 2 {
+		var body schemaRuleBody
+		if err := content[i+1].Decode(&body); err != nil {
+			return nil, fmt.Errorf("error parsing schema rule %s: %w", content[i].Value, err)
+		}
+		rules = append(rules, SchemaRule{
+			Name:       content[i].Value,
+			Type:       body.Type,
+			Required:   body.Required,
+			EditableAt: body.EditableAt,
+			EditableBy: body.EditableBy,
+		})
+	}
+	return rules, nil
+}
+
+// Checks configValues against a schema version's value (the layout BuildSchemaValue produces):
+// every required field must be set, and each value must match its rule's type. float accepts
+// any number, int or integer a whole number, string a string and boolean or bool a bool; other
+// types aren't checked. Values without a rule are left alone. Returns all violations at once.
+func ValidateConfigAgainstSchema(schemaValue string, configValues map[string]interface{}) error {
+	rules, err := LoadSchemaRules(strings.NewReader(schemaValue))
+	if err != nil {
+		return fmt.Errorf("error reading schema: %w", err)
+	}
+
+	var violations []string
+	for _, rule := range rules {
+		value, ok := configValues[rule.Name]
+		if !ok || value == nil {
+			if rule.Required {
+				violations = append(violations, fmt.Sprintf("%s: required by the schema but not set", rule.Name))
+			}
+			continue
+		}
+		if !matchesSchemaType(rule.Type, value) {
+			violations = append(violations, fmt.Sprintf("%s: schema type is %s, got %v (%T)", rule.Name, rule.Type, value, value))
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("configuration values do not satisfy the schema:\n  %s", strings.Join(violations, "\n  "))
+	}
+	return nil
+}
+
+// matchesSchemaType reports whether value is acceptable for a schema rule of type ruleType.
+// Types it doesn't know are accepted.
+func matchesSchemaType(ruleType string, value interface{}) bool {
+	switch strings.ToLower(ruleType) {
+	case "float":
+		_, ok := normalizeConfigValue(value).(float64)
+		return ok
+	case "int", "integer":
+		number, ok := normalizeConfigValue(value).(float64)
+		return ok && number == math.Trunc(number)
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean", "bool":
+		_, ok := value.(bool)
+		return ok
+	default:
+		return true
+	}
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
+
+// stringNode is a scalar that always reads back as a string, quoted when it would otherwise
+// parse as a number or boolean (e.g. a schema version like 1.0).
+func stringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
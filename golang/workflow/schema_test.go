@@ -0,0 +1,133 @@
+package workflow
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+)
+
+// seededVersions returns the first n versions GenerateRandomSemanticVersion draws from seed.
+func seededVersions(seed int64, n int) []string {
+	source := rand.New(rand.NewSource(seed))
+	versions := make([]string, n)
+	for i := range versions {
+		versions[i] = GenerateRandomSemanticVersion(source, false, false)
+	}
+	return versions
+}
+
+func TestCreateSchemaVersionSkipsTakenVersions(t *testing.T) {
+	const seed = 3
+	draws := seededVersions(seed, 2)
+	if draws[0] == draws[1] {
+		t.Fatalf("seed %d draws %s twice; pick another seed", seed, draws[0])
+	}
+	ctx := WithRandomSource(testContext(), rand.New(rand.NewSource(seed)))
+	client := &fakeSchemaVersions{names: []string{draws[0]}}
+
+	version, err := CreateSchemaVersion(ctx, client, "rg", "schema", nil, "", true)
+	if err != nil {
+		t.Fatalf("CreateSchemaVersion: %v", err)
+	}
+	if got := stringValue(version.Name); got != draws[1] {
+		t.Errorf("version = %s, want the second draw %s since the first (%s) is taken", got, draws[1], draws[0])
+	}
+}
+
+func TestCreateSchemaVersionGivesUpWhenEveryCandidateIsTaken(t *testing.T) {
+	const seed = 3
+	ctx := WithRandomSource(testContext(), rand.New(rand.NewSource(seed)))
+	client := &fakeSchemaVersions{names: seededVersions(seed, maxVersionAttempts)}
+
+	if version, err := CreateSchemaVersion(ctx, client, "rg", "schema", nil, "", true); err == nil {
+		t.Fatalf("CreateSchemaVersion = %s, want an error after %d taken candidates", stringValue(version.Name), maxVersionAttempts)
+	}
+}
+
+func TestCreateSchemaVersionDryRunOnAFreshSchema(t *testing.T) {
+	// The dry run never created the schema, so listing its versions finds nothing at all
+	client := &fakeSchemaVersions{listErr: responseError(http.StatusNotFound, "ResourceNotFound", "schema not found")}
+
+	version, err := CreateSchemaVersion(testContext(), client, "rg", "schema", nil, "", true)
+	if err != nil {
+		t.Fatalf("CreateSchemaVersion: %v", err)
+	}
+	if stringValue(version.Name) == "" {
+		t.Error("dry run returned a version without a name")
+	}
+
+	client.listErr = responseError(http.StatusForbidden, "AuthorizationFailed", "no access")
+	if _, err := CreateSchemaVersion(testContext(), client, "rg", "schema", nil, "", true); err == nil {
+		t.Error("CreateSchemaVersion ignored a failure to list the versions that wasn't a 404")
+	}
+}
+
+func TestBuildSchemaValueGolden(t *testing.T) {
+	tests := []struct {
+		golden string
+		rules  []SchemaRule
+	}{
+		{golden: "schema_default.golden", rules: DefaultSchemaRules},
+		{golden: "schema_custom.golden", rules: []SchemaRule{
+			{Name: "MaxSpeed", Type: "int", Required: true, EditableAt: []string{"factory"}, EditableBy: []string{"IT"}},
+			{Name: "Label", Type: "string", EditableAt: []string{"factory", "line"}, EditableBy: []string{"IT", "OT"}},
+			{Name: "Debug", Type: "boolean"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			value, err := BuildSchemaValue(tt.rules)
+			if err != nil {
+				t.Fatalf("BuildSchemaValue: %v", err)
+			}
+			checkGolden(t, tt.golden, value)
+		})
+	}
+}
+
+func TestBuildConfigurationsGolden(t *testing.T) {
+	tests := []struct {
+		golden   string
+		refs     []string
+		literals map[string]string
+	}{
+		// What CreateSolutionTemplateVersion submits for the built-in rules
+		{golden: "configurations_default.golden", refs: schemaRuleNames(DefaultSchemaRules), literals: map[string]string{"AppName": "Hotmelt"}},
+		// Literals that would read back as another type, or break the YAML, unless quoted
+		{golden: "configurations_quoted.golden", refs: []string{"MaxSpeed"}, literals: map[string]string{
+			"Enabled":  "true",
+			"Release":  "1.10",
+			"Endpoint": "http://agent:8080/path?x=1#frag",
+			"Empty":    "",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			value, err := buildConfigurations("schema", "1.0.0", tt.refs, tt.literals)
+			if err != nil {
+				t.Fatalf("buildConfigurations: %v", err)
+			}
+			checkGolden(t, tt.golden, value)
+		})
+	}
+}
+
+func TestBuildConfigurationsRejectsBadInput(t *testing.T) {
+	tests := []struct {
+		name          string
+		schemaVersion string
+		refs          []string
+		literals      map[string]string
+	}{
+		{name: "no schema version", refs: []string{"A"}},
+		{name: "empty ref", schemaVersion: "1.0.0", refs: []string{"A", ""}},
+		{name: "duplicate ref", schemaVersion: "1.0.0", refs: []string{"A", "A"}},
+		{name: "literal shadows ref", schemaVersion: "1.0.0", refs: []string{"A"}, literals: map[string]string{"A": "x"}},
+		{name: "unnamed literal", schemaVersion: "1.0.0", literals: map[string]string{"": "x"}},
+	}
+	for _, tt := range tests {
+		if _, err := buildConfigurations("schema", tt.schemaVersion, tt.refs, tt.literals); err == nil {
+			t.Errorf("%s: buildConfigurations succeeded, want an error", tt.name)
+		}
+	}
+}
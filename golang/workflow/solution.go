@@ -0,0 +1,253 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// Creates a solution template - a blueprint for deployable solutions.
+// Links to specific capabilities (like "soap" or "shampoo" manufacturing).
+// This is the template container - you need to create versions of it next.
+// Think of it as creating a "product line" before creating specific "product versions".
+// An empty solutionTemplateName falls back to "sdkexamples-solution1".
+// At least one capability is required, and each must be in the target context; check with
+// VerifyCapabilitiesInContext first.
+// tags are applied to the template (see RunTags).
+// With dryRun set, nothing is submitted and a synthetic template is returned.
+func CreateSolutionTemplate(ctx context.Context, client SolutionTemplatesAPI, resourceGroupName, solutionTemplateName, location string, capabilities []string, tags map[string]string, dryRun bool) (*armworkloadorchestration.SolutionTemplate, error) {
+	if len(capabilities) == 0 {
+		return nil, fmt.Errorf("solution template %s needs at least one capability", valueOrDefault(solutionTemplateName, "sdkexamples-solution1"))
+	}
+	solutionTemplateName = valueOrDefault(solutionTemplateName, "sdkexamples-solution1")
+
+	loggerFrom(ctx).Info("Creating solution template", logKeyResource, solutionTemplateName, "resourceGroup", resourceGroupName)
+
+	capabilityPtrs := make([]*string, len(capabilities))
+	for i, cap := range capabilities {
+		capabilityPtrs[i] = to.Ptr(cap)
+	}
+
+	resource := armworkloadorchestration.SolutionTemplate{
+		Location: to.Ptr(location),
+		Tags:     toSDKTags(tags),
+		Properties: &armworkloadorchestration.SolutionTemplateProperties{
+			Capabilities: capabilityPtrs,
+			Description:  to.Ptr("This is Holtmelt Solution with random capabilities"),
+		},
+	}
+
+	if dryRun {
+		logDryRun(ctx, "create", "Microsoft.Edge/solutionTemplates", solutionTemplateName, map[string]interface{}{
+			"resourceGroup": resourceGroupName,
+			"location":      location,
+			"capabilities":  capabilities,
+			"tags":          tags,
+		})
+		resource.ID = to.Ptr(dryRunResourceID(resourceGroupName, "solutionTemplates", solutionTemplateName))
+		resource.Name = to.Ptr(solutionTemplateName)
+		return &resource, nil
+	}
+
+	poller, err := client.BeginCreateOrUpdate(ctx, resourceGroupName, solutionTemplateName, resource, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating solution template: %w", err)
+	}
+
+	res, err := pollUntilDone(ctx, poller, "solution template creation", func(ctx context.Context) (*armworkloadorchestration.ProvisioningState, error) {
+		current, err := client.Get(ctx, resourceGroupName, solutionTemplateName, nil)
+		if err != nil || current.Properties == nil {
+			return nil, err
+		}
+		return current.Properties.ProvisioningState, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error polling solution template creation: %w", err)
+	}
+
+	loggerFrom(ctx).Info("Solution template created", logKeyResource, *res.Name, logKeyState, provisioningState(&res.SolutionTemplate))
+	return &res.SolutionTemplate, nil
+}
+
+// GetOrCreateSolutionTemplate returns the solution template when it already exists, and creates it
+// with CreateSolutionTemplate otherwise, so re-running with the same names reuses what an earlier run made.
+func GetOrCreateSolutionTemplate(ctx context.Context, client SolutionTemplatesAPI, resourceGroupName, solutionTemplateName, location string, capabilities []string, tags map[string]string, dryRun bool) (*armworkloadorchestration.SolutionTemplate, error) {
+	name := valueOrDefault(solutionTemplateName, "sdkexamples-solution1")
+	existing, err := client.Get(ctx, resourceGroupName, name, nil)
+	if err == nil {
+		loggerFrom(ctx).Info("Solution template already exists, reusing it", logKeyResource, name)
+		return &existing.SolutionTemplate, nil
+	}
+	if !isNotFound(err) {
+		return nil, fmt.Errorf("error checking for existing solution template %s: %w", name, err)
+	}
+	return CreateSolutionTemplate(ctx, client, resourceGroupName, solutionTemplateName, location, capabilities, tags, dryRun)
+}
+
+// ParseUpdateType validates an update type name (case-insensitive): major, minor or patch. It
+// says which part of the version number a new solution template version bumps. An empty value
+// returns an empty UpdateType, which leaves the choice to the service's default.
+func ParseUpdateType(value string) (armworkloadorchestration.UpdateType, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	var names []string
+	for _, updateType := range armworkloadorchestration.PossibleUpdateTypeValues() {
+		if strings.EqualFold(value, string(updateType)) {
+			return updateType, nil
+		}
+		names = append(names, strings.ToLower(string(updateType)))
+	}
+	return "", fmt.Errorf("unknown update type %q (expected %s)", value, strings.Join(names, ", "))
+}
+
+// NextSolutionTemplateVersion lists the versions of a solution template and returns the one
+// after the highest, as NextSemanticVersion does with bump. A template that doesn't exist yet
+// has no versions.
+func NextSolutionTemplateVersion(ctx context.Context, client SolutionTemplateVersionsAPI, resourceGroupName, solutionTemplateName, bump string) (string, error) {
+	versions, err := ListSolutionTemplateVersions(ctx, client, resourceGroupName, solutionTemplateName)
+	if err != nil && !isNotFound(err) {
+		return "", fmt.Errorf("error listing versions of solution template %s: %w", solutionTemplateName, err)
+	}
+	var names []string
+	for _, version := range versions {
+		if version.Name != nil {
+			names = append(names, *version.Name)
+		}
+	}
+	return NextSemanticVersion(names, bump)
+}
+
+// Creates a deployable version of a solution template.
+// PREREQUISITES: Solution template and schema version must exist.
+// This links the schema rules to actual deployment configurations and Helm charts.
+// Contains the "recipe" for how to deploy the solution on targets.
+// The configurations reference each of rules, the schema version's rules (the built-in soap/hotmelt
+// rules when nil), so pass the same rules the schema version was created with.
+// Deploys components, or the sample simple-chart Helm component when components is nil.
+// updateType (see ParseUpdateType) is sent with the version when set; empty leaves it to the service.
+// version names the new version, e.g. one from NextSolutionTemplateVersion; a random one is
+// generated when it is empty.
+// With dryRun set, the version body is printed and a synthetic response is returned.
+func CreateSolutionTemplateVersion(ctx context.Context, client SolutionTemplatesAPI, resourceGroupName, solutionTemplateName, schemaName, schemaVersion string, rules []SchemaRule, components []Component, updateType armworkloadorchestration.UpdateType, version string, dryRun bool) (*armworkloadorchestration.SolutionTemplatesClientCreateVersionResponse, error) {
+	if version == "" {
+		version = GenerateRandomSemanticVersion(randomFrom(ctx), false, false)
+	}
+	// The version becomes part of the resource name; reject a malformed one before Azure does
+	if err := ValidateSemanticVersion(version); err != nil {
+		return nil, err
+	}
+	solutionTemplateVersionName := version
+
+	loggerFrom(ctx).Info("Creating solution template version", logKeyResource, solutionTemplateName, "version", solutionTemplateVersionName)
+
+	if rules == nil {
+		rules = DefaultSchemaRules
+	}
+	// Reference every field of the schema version, so the configs can't drift from its rules
+	configurationsStr, err := buildConfigurations(schemaName, schemaVersion, schemaRuleNames(rules), map[string]string{"AppName": "Hotmelt"})
+	if err != nil {
+		return nil, fmt.Errorf("invalid solution template configurations: %w", err)
+	}
+
+	if components == nil {
+		components, err = DefaultComponents()
+		if err != nil {
+			return nil, err
+		}
+	}
+	specification, err := buildSpecification(components)
+	if err != nil {
+		return nil, fmt.Errorf("invalid solution specification: %w", err)
+	}
+
+	body := armworkloadorchestration.SolutionTemplateVersionWithUpdateType{
+		SolutionTemplateVersion: &armworkloadorchestration.SolutionTemplateVersion{
+			Properties: &armworkloadorchestration.SolutionTemplateVersionProperties{
+				Configurations:   to.Ptr(configurationsStr),
+				Specification:    specification,
+				OrchestratorType: to.Ptr(armworkloadorchestration.OrchestratorTypeTO),
+			},
+		},
+		Version: to.Ptr(solutionTemplateVersionName),
+	}
+	if updateType != "" {
+		body.UpdateType = to.Ptr(updateType)
+	}
+
+	if dryRun {
+		componentNames := make([]string, 0, len(components))
+		for _, component := range components {
+			componentNames = append(componentNames, component.Name)
+		}
+		logDryRun(ctx, "create", "Microsoft.Edge/solutionTemplates/versions", solutionTemplateName+"/"+solutionTemplateVersionName, map[string]interface{}{
+			"schema":         schemaName + "@" + schemaVersion,
+			"components":     componentNames,
+			"updateType":     valueOrDefault(string(updateType), "service default"),
+			"configurations": "\n" + configurationsStr,
+		})
+		templateVersion := *body.SolutionTemplateVersion
+		templateVersion.ID = to.Ptr(dryRunResourceID(resourceGroupName, "solutionTemplates", solutionTemplateName, "versions", solutionTemplateVersionName))
+		templateVersion.Name = to.Ptr(solutionTemplateVersionName)
+		return &armworkloadorchestration.SolutionTemplatesClientCreateVersionResponse{SolutionTemplateVersion: templateVersion}, nil
+	}
+
+	poller, err := client.BeginCreateVersion(ctx, resourceGroupName, solutionTemplateName, body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating solution template version: %w", err)
+	}
+
+	res, err := pollUntilDone(ctx, poller, "solution template version creation", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error polling solution template version creation: %w", err)
+	}
+
+	loggerFrom(ctx).Info("Solution template version created", logKeyResource, solutionTemplateName, "version", solutionTemplateVersionName)
+	return &res, nil
+}
+
+// SolutionTemplateVersionResourceID returns the full ARM resource ID of a solution template
+// version, as passed to ReviewTarget.
+func SolutionTemplateVersionResourceID(subscriptionID, resourceGroupName, solutionTemplateName, version string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Edge/solutionTemplates/%s/versions/%s", subscriptionID, resourceGroupName, solutionTemplateName, version)
+}
+
+// resolveSolutionTemplateVersionID returns the full resource ID of a version created by
+// CreateSolutionTemplateVersion. The service's ID is used when the response carries one, and
+// the ID is built from the version name otherwise; either way it is checked before it is returned.
+func resolveSolutionTemplateVersionID(version *armworkloadorchestration.SolutionTemplateVersion, subscriptionID, resourceGroupName, solutionTemplateName string) (string, error) {
+	if version == nil {
+		return "", fmt.Errorf("solution template version of %s is missing", solutionTemplateName)
+	}
+	var id string
+	switch {
+	case version.ID != nil && *version.ID != "":
+		id = *version.ID
+	case version.Name != nil && *version.Name != "":
+		id = SolutionTemplateVersionResourceID(subscriptionID, resourceGroupName, solutionTemplateName, *version.Name)
+	default:
+		return "", fmt.Errorf("solution template version of %s has neither a resource ID nor a name", solutionTemplateName)
+	}
+	if err := validateSolutionTemplateVersionID(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// validateSolutionTemplateVersionID checks id is the full resource ID of a
+// Microsoft.Edge/solutionTemplates/versions resource, not just a version string.
+func validateSolutionTemplateVersionID(id string) error {
+	resourceID, err := arm.ParseResourceID(id)
+	if err != nil {
+		return fmt.Errorf("solution template version ID %q is not a valid resource ID (see SolutionTemplateVersionResourceID): %w", id, err)
+	}
+	if !strings.EqualFold(resourceID.ResourceType.String(), "Microsoft.Edge/solutionTemplates/versions") {
+		return fmt.Errorf("solution template version ID %q is a %s, expected a Microsoft.Edge/solutionTemplates/versions resource ID", id, resourceID.ResourceType)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+)
+
+// Tags stamped on every resource a run creates, so resources left behind by a failed run
+// can be found and removed later (see CleanupByRunID).
+const (
+	TagRunID     = "runId"
+	TagCreatedBy = "createdBy"
+	CreatedBy    = "sdkexample"
+)
+
+// RunTags returns the tags identifying resources created by the run with runID.
+func RunTags(runID string) map[string]string {
+	return map[string]string{
+		TagRunID:     runID,
+		TagCreatedBy: CreatedBy,
+	}
+}
+
+// ParseTags parses comma-separated key=value pairs, e.g. "costCenter=1234,owner=plant-ops".
+// An empty value returns nil.
+func ParseTags(value string) (map[string]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, tagValue, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid tag %q (expected key=value)", pair)
+		}
+		tags[key] = strings.TrimSpace(tagValue)
+	}
+	return tags, nil
+}
+
+// mergeTags combines tag sets; later sets win on conflicting keys.
+func mergeTags(tagSets ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, tags := range tagSets {
+		for key, value := range tags {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// toSDKTags converts tags to the SDK's pointer map, or nil when there are none.
+func toSDKTags(tags map[string]string) map[string]*string {
+	if len(tags) == 0 {
+		return nil
+	}
+	sdkTags := make(map[string]*string, len(tags))
+	for key, value := range tags {
+		sdkTags[key] = to.Ptr(value)
+	}
+	return sdkTags
+}
+
+// fromSDKTags converts the SDK's pointer map to plain tags, dropping nil values.
+func fromSDKTags(sdkTags map[string]*string) map[string]string {
+	tags := make(map[string]string, len(sdkTags))
+	for key, value := range sdkTags {
+		if value != nil {
+			tags[key] = *value
+		}
+	}
+	return tags
+}
+
+// hasRunTag reports whether a resource's tags mark it as created by the run with runID.
+func hasRunTag(sdkTags map[string]*string, runID string) bool {
+	value, ok := sdkTags[TagRunID]
+	return ok && value != nil && *value == runID
+}
@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// testTags are the run's tags plus user tags whose values a careless conversion would mangle.
+func testTags() map[string]string {
+	return mergeTags(RunTags("3f9a1c07"), map[string]string{
+		"costCenter": "1234",
+		"owner":      "plant ops, line=2",
+		"note":       "",
+	})
+}
+
+func TestContextTagsReadBackUnchanged(t *testing.T) {
+	client := &fakeContexts{t: t}
+	tags := testTags()
+
+	if _, _, err := CreateOrUpdateContextWithHierarchies(testContext(), client, "rg", "ctx", "eastus", []Capability{{Name: "soap", Description: "Soap"}}, DefaultHierarchies(), tags, nil, false); err != nil {
+		t.Fatalf("CreateOrUpdateContextWithHierarchies: %v", err)
+	}
+	existing, err := GetExistingContext(testContext(), client, "rg", "ctx")
+	if err != nil {
+		t.Fatalf("GetExistingContext: %v", err)
+	}
+	if !maps.Equal(existing.Tags, tags) {
+		t.Errorf("tags read back as %v, want %v", existing.Tags, tags)
+	}
+}
+
+func TestTargetTagsReadBackUnchanged(t *testing.T) {
+	var stored armworkloadorchestration.Target
+	client := &fakeTargets{
+		createOrUpdate: func(targetName string, resource armworkloadorchestration.Target) (*runtime.Poller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse], error) {
+			resource.Name = to.Ptr(targetName)
+			resource.Properties.ProvisioningState = to.Ptr(armworkloadorchestration.ProvisioningStateSucceeded)
+			stored = resource
+			return donePoller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse](t, stored), nil
+		},
+		get: func(targetName string) (armworkloadorchestration.TargetsClientGetResponse, error) {
+			return armworkloadorchestration.TargetsClientGetResponse{Target: stored}, nil
+		},
+	}
+	tags := testTags()
+
+	created, err := CreateTarget(testContext(), client, "rg", "line-1", "eastus", ContextResourceID("sub", "rg", "ctx"), nil, []string{"soap"}, nil, "", "", tags, false)
+	if err != nil {
+		t.Fatalf("CreateTarget: %v", err)
+	}
+	if got := fromSDKTags(created.Tags); !maps.Equal(got, tags) {
+		t.Errorf("created target's tags = %v, want %v", got, tags)
+	}
+	read, err := client.Get(testContext(), "rg", "line-1", nil)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := fromSDKTags(read.Tags); !maps.Equal(got, tags) {
+		t.Errorf("tags read back as %v, want %v", got, tags)
+	}
+	if !hasRunTag(read.Tags, "3f9a1c07") {
+		t.Error("target read back without its run tag")
+	}
+}
@@ -0,0 +1,499 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// SolutionScope selects the scope on a target's cluster that its solutions are deployed into.
+type SolutionScope string
+
+const (
+	// SolutionScopeNew has the target get a scope of its own, created along with it. Solutions
+	// on different targets are then kept apart even when the targets share a cluster.
+	SolutionScopeNew SolutionScope = "new"
+	// SolutionScopeExisting deploys into a scope that already exists on the cluster, e.g. one
+	// set up for an earlier target, so solutions can be shared with what is already running there.
+	SolutionScopeExisting SolutionScope = "existing"
+)
+
+// ParseSolutionScope validates a scope name (case-insensitive), defaulting to SolutionScopeNew when empty.
+func ParseSolutionScope(value string) (SolutionScope, error) {
+	switch scope := SolutionScope(strings.ToLower(strings.TrimSpace(value))); scope {
+	case "":
+		return SolutionScopeNew, nil
+	case SolutionScopeNew, SolutionScopeExisting:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("unknown solution scope %q (expected %s or %s)", value, SolutionScopeNew, SolutionScopeExisting)
+	}
+}
+
+// Creates a target - represents a physical location/environment where solutions will be deployed.
+// Links to specific capabilities and requires an Azure Context for coordination.
+// Think of this as registering a "factory floor" or "production line" where solutions will run.
+// contextID is the full resource ID of the context the target belongs to (see ContextResourceID).
+// At least one capability is required.
+// Uses an in-cluster Helm topology when topologies is nil, and DefaultExtendedLocation when extendedLocation is nil.
+// solutionScope is validated with ParseSolutionScope; empty means SolutionScopeNew.
+// hierarchyLevel places the target in the context's hierarchy and defaults to DefaultHierarchyLevel;
+// it must be one of the context's levels (see ValidateHierarchyLevel), which the service enforces.
+// An empty targetName falls back to "sdkbox-mk799jyjsdd".
+// tags are applied to the target (see RunTags).
+// With dryRun set, nothing is submitted and a synthetic target is returned.
+func CreateTarget(ctx context.Context, client TargetsAPI, resourceGroupName, targetName, location, contextID string, extendedLocation *ExtendedLocation, capabilities []string, topologies []TargetTopology, solutionScope SolutionScope, hierarchyLevel string, tags map[string]string, dryRun bool) (*armworkloadorchestration.Target, error) {
+	solutionScope, err := ParseSolutionScope(string(solutionScope))
+	if err != nil {
+		return nil, err
+	}
+	if extendedLocation == nil {
+		defaultLocation := DefaultExtendedLocation()
+		extendedLocation = &defaultLocation
+	}
+	if err := extendedLocation.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid extended location: %w", err)
+	}
+	if err := validateContextID(contextID); err != nil {
+		return nil, err
+	}
+	if len(capabilities) == 0 {
+		return nil, fmt.Errorf("target %s needs at least one capability", valueOrDefault(targetName, "sdkbox-mk799jyjsdd"))
+	}
+	if topologies == nil {
+		topologies = DefaultTargetTopologies()
+	}
+	targetSpecification, err := buildTargetSpecification(topologies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target specification: %w", err)
+	}
+
+	targetName = valueOrDefault(targetName, "sdkbox-mk799jyjsdd")
+
+	capabilityPtrs := make([]*string, len(capabilities))
+	for i, cap := range capabilities {
+		capabilityPtrs[i] = to.Ptr(cap)
+	}
+
+	resource := armworkloadorchestration.Target{
+		ExtendedLocation: extendedLocation.toSDK(),
+		Location:         to.Ptr(location),
+		Tags:             toSDKTags(tags),
+		Properties: &armworkloadorchestration.TargetProperties{
+			Capabilities:        capabilityPtrs,
+			ContextID:           to.Ptr(contextID),
+			Description:         to.Ptr("This is MK-71 Site with random capabilities"),
+			DisplayName:         to.Ptr("sdkbox-mk71"),
+			HierarchyLevel:      to.Ptr(valueOrDefault(hierarchyLevel, DefaultHierarchyLevel)),
+			SolutionScope:       to.Ptr(string(solutionScope)),
+			TargetSpecification: targetSpecification,
+		},
+	}
+
+	if dryRun {
+		logDryRun(ctx, "create", "Microsoft.Edge/targets", targetName, map[string]interface{}{
+			"resourceGroup":    resourceGroupName,
+			"location":         location,
+			"extendedLocation": *resource.ExtendedLocation.Name,
+			"contextId":        contextID,
+			"capabilities":     capabilities,
+			"hierarchyLevel":   *resource.Properties.HierarchyLevel,
+			"solutionScope":    *resource.Properties.SolutionScope,
+			"tags":             tags,
+		})
+		resource.ID = to.Ptr(dryRunResourceID(resourceGroupName, "targets", targetName))
+		resource.Name = to.Ptr(targetName)
+		return &resource, nil
+	}
+
+	createOperation := func() error {
+		loggerFrom(ctx).Info("Creating target", logKeyResource, targetName, "resourceGroup", resourceGroupName)
+
+		// Wait for the long-running operation to complete (this blocks); a saved resume token picks up an interrupted creation
+		resumeKey := path.Join("targets", resourceGroupName, targetName, "create")
+		_, err := pollResumable(ctx, resumeKey, "target creation", targetState(client, resourceGroupName, targetName), func(resumeToken string) (*runtime.Poller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse], error) {
+			return client.BeginCreateOrUpdate(ctx, resourceGroupName, targetName, resource, &armworkloadorchestration.TargetsClientBeginCreateOrUpdateOptions{ResumeToken: resumeToken})
+		})
+		if err != nil {
+			// Let the target's provisioning state decide whether this attempt can be retried
+			state, errGet := targetState(client, resourceGroupName, targetName)(ctx)
+			if errGet != nil {
+				loggerFrom(ctx).Warn("Failed to retrieve target provisioning state", logKeyResource, targetName, logKeyError, errGet)
+				return fmt.Errorf("target creation failed: %w", err)
+			}
+			if state == nil {
+				return fmt.Errorf("target creation failed: %w", err)
+			}
+
+			switch *state {
+			case armworkloadorchestration.ProvisioningStateSucceeded:
+				loggerFrom(ctx).Warn("Target provisioned despite the polling error", logKeyResource, targetName, logKeyState, *state, logKeyError, err)
+			case armworkloadorchestration.ProvisioningStateFailed, armworkloadorchestration.ProvisioningStateCanceled:
+				return permanent(fmt.Errorf("target creation ended in provisioning state %s: %w", *state, err))
+			default:
+				return fmt.Errorf("target still in provisioning state %s: %w", *state, err)
+			}
+		}
+		return nil
+	}
+
+	// Target provisioning is slow to settle, so it gets more and longer-spaced attempts
+	err = retryOperation(ctx, RetryTargetCreation, NewRetryPolicy(WithMaxAttempts(5), WithBaseDelay(60*time.Second)), createOperation)
+	if err != nil {
+		return nil, fmt.Errorf("error creating target: %w", err)
+	}
+
+	// Get the created target to return it
+	target, err := client.Get(ctx, resourceGroupName, targetName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting created target: %w", err)
+	}
+
+	loggerFrom(ctx).Info("Target created", logKeyResource, *target.Name, logKeyState, provisioningState(&target.Target))
+	return &target.Target, nil
+}
+
+// GetOrCreateTarget returns the target when it already exists, and creates it with CreateTarget
+// otherwise, so re-running with the same names reuses what an earlier run made. An existing
+// target is returned as it is; its properties are not compared with the ones given.
+func GetOrCreateTarget(ctx context.Context, client TargetsAPI, resourceGroupName, targetName, location, contextID string, extendedLocation *ExtendedLocation, capabilities []string, topologies []TargetTopology, solutionScope SolutionScope, hierarchyLevel string, tags map[string]string, dryRun bool) (*armworkloadorchestration.Target, error) {
+	name := valueOrDefault(targetName, "sdkbox-mk799jyjsdd")
+	existing, err := client.Get(ctx, resourceGroupName, name, nil)
+	if err == nil {
+		loggerFrom(ctx).Info("Target already exists, reusing it", logKeyResource, name, logKeyState, provisioningState(&existing.Target))
+		return &existing.Target, nil
+	}
+	if !isNotFound(err) {
+		return nil, fmt.Errorf("error checking for existing target %s: %w", name, err)
+	}
+	return CreateTarget(ctx, client, resourceGroupName, targetName, location, contextID, extendedLocation, capabilities, topologies, solutionScope, hierarchyLevel, tags, dryRun)
+}
+
+// Reviews a solution template version for deployment on a target.
+// PREREQUISITE: Target and solution template version must exist.
+// This validates the solution can be deployed and creates a "solution version"
+// ready for publishing. Like getting deployment approval before going live.
+// solutionTemplateVersionID must be the version's full resource ID (see SolutionTemplateVersionResourceID).
+// Also returns the review's diagnostics, e.g. configuration values the schema rejects. When any
+// of them is an error, the review fails with a *ReviewError listing them; warnings alone don't.
+// With dryRun set, nothing is submitted and a synthetic solution version ID is returned.
+func ReviewTarget(ctx context.Context, client TargetsAPI, solutionsClient SolutionsAPI, solutionVersionsClient SolutionVersionsAPI, resourceGroupName, targetName, solutionTemplateVersionID string, dryRun bool) (string, []ReviewDiagnostic, error) {
+	if err := validateSolutionTemplateVersionID(solutionTemplateVersionID); err != nil {
+		return "", nil, err
+	}
+	if dryRun {
+		logDryRun(ctx, "review", "Microsoft.Edge/targets", targetName, map[string]interface{}{
+			"solutionTemplateVersionId": solutionTemplateVersionID,
+		})
+		return dryRunResourceID(resourceGroupName, "targets", targetName, "solutions", "dry-run", "versions", path.Base(solutionTemplateVersionID)), nil, nil
+	}
+
+	var solutionVersionID string
+	var diagnostics []ReviewDiagnostic
+	reviewOperation := func() error {
+		loggerFrom(ctx).Info("Reviewing solution template version", logKeyResource, targetName, "solutionTemplateVersionId", solutionTemplateVersionID)
+
+		resumeKey := path.Join("targets", resourceGroupName, targetName, "review", solutionTemplateVersionID)
+		res, err := pollResumable(ctx, resumeKey, "solution review", targetState(client, resourceGroupName, targetName), func(resumeToken string) (*runtime.Poller[armworkloadorchestration.TargetsClientReviewSolutionVersionResponse], error) {
+			return client.BeginReviewSolutionVersion(ctx, resourceGroupName, targetName, armworkloadorchestration.SolutionTemplateParameter{
+				SolutionTemplateVersionID: to.Ptr(solutionTemplateVersionID),
+			}, &armworkloadorchestration.TargetsClientBeginReviewSolutionVersionOptions{ResumeToken: resumeToken})
+		})
+		if err != nil {
+			// A rejection lists what is wrong, and reviewing the same version again won't change it.
+			// Other failures, and rejections naming only warnings, are retried like any other.
+			if diagnostics = reviewDiagnostics(err); isReviewRejection(err, diagnostics) {
+				return permanent(&ReviewError{TargetName: targetName, Diagnostics: diagnostics, Err: err})
+			}
+			return err
+		}
+		diagnostics = solutionVersionDiagnostics(res.SolutionVersion)
+		if hasErrorDiagnostics(diagnostics) {
+			return permanent(&ReviewError{TargetName: targetName, Diagnostics: diagnostics})
+		}
+
+		// The review result sometimes carries only the short version name, not the full resource ID
+		var nameOrID string
+		if res.ID != nil {
+			nameOrID = *res.ID
+		} else if res.Name != nil {
+			nameOrID = *res.Name
+		} else {
+			return fmt.Errorf("review returned no solution version")
+		}
+
+		solutionVersionID, err = ResolveSolutionVersionID(ctx, solutionsClient, solutionVersionsClient, resourceGroupName, targetName, nameOrID)
+		if err != nil {
+			return err
+		}
+
+		loggerFrom(ctx).Info("Review completed", logKeyResource, targetName)
+		return nil
+	}
+
+	err := retryOperation(ctx, RetryReview, DefaultRetryPolicy, reviewOperation)
+	logDiagnostics(ctx, targetName, diagnostics)
+	if err != nil {
+		return "", diagnostics, fmt.Errorf("error reviewing target: %w", err)
+	}
+
+	return solutionVersionID, diagnostics, nil
+}
+
+// Resolves the full solution version resource ID needed by publish/install.
+// Full IDs are returned unchanged; a bare version name is looked up in every
+// solution on the target until a version with that name is found.
+func ResolveSolutionVersionID(ctx context.Context, solutionsClient SolutionsAPI, solutionVersionsClient SolutionVersionsAPI, resourceGroupName, targetName, nameOrID string) (string, error) {
+	if strings.HasPrefix(strings.ToLower(nameOrID), "/subscriptions/") {
+		return nameOrID, nil
+	}
+
+	loggerFrom(ctx).Debug("Review returned a version name, resolving the full solution version ID", "version", nameOrID)
+
+	solutions, err := ListSolutions(ctx, solutionsClient, resourceGroupName, targetName)
+	if err != nil {
+		return "", fmt.Errorf("error listing solutions on target %s: %w", targetName, err)
+	}
+	for _, solution := range solutions {
+		if solution.Name == nil {
+			continue
+		}
+		version, err := solutionVersionsClient.Get(ctx, resourceGroupName, targetName, *solution.Name, nameOrID, nil)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return "", fmt.Errorf("error getting solution version %s: %w", nameOrID, err)
+		}
+		if version.ID != nil {
+			loggerFrom(ctx).Debug("Resolved solution version ID", "solutionVersionId", *version.ID)
+			return *version.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("solution version %s not found on target %s", nameOrID, targetName)
+}
+
+// Publishes a reviewed solution version to make it available for installation.
+// PREREQUISITE: Solution must be reviewed first (ReviewTarget).
+// This moves the solution from "reviewed" state to "published" state.
+// Like releasing software from staging to production-ready.
+// solutionVersionID must be the full resource ID of the solution version the review created.
+func PublishTarget(ctx context.Context, client TargetsAPI, resourceGroupName, targetName, solutionVersionID string, dryRun bool) error {
+	if dryRun {
+		logDryRun(ctx, "publish", "Microsoft.Edge/targets", targetName, map[string]interface{}{
+			"solutionVersionId": solutionVersionID,
+		})
+		return nil
+	}
+
+	if _, err := solutionNameOf(solutionVersionID); err != nil {
+		return err
+	}
+
+	publishOperation := func() error {
+		loggerFrom(ctx).Info("Publishing solution version", logKeyResource, targetName, "solutionVersionId", solutionVersionID)
+
+		poller, err := client.BeginPublishSolutionVersion(ctx, resourceGroupName, targetName, armworkloadorchestration.SolutionVersionParameter{
+			SolutionVersionID: to.Ptr(solutionVersionID),
+		}, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := pollUntilDone(ctx, poller, "solution publish", targetState(client, resourceGroupName, targetName)); err != nil {
+			return err
+		}
+
+		loggerFrom(ctx).Info("Solution version published", logKeyResource, targetName)
+		return nil
+	}
+
+	return retryOperation(ctx, RetryPublish, DefaultRetryPolicy, publishOperation)
+}
+
+// Installs a published solution version on the target environment.
+// PREREQUISITE: Solution must be published first (PublishTarget).
+// This is the final step - actually deploying and running the solution.
+// Like installing and starting the application in production.
+// Once the install completes, the deployed version of the solution that owns solutionVersionID
+// (GetCurrentDeployedVersion) must be solutionVersionID, or the install is reported as failed.
+func InstallTarget(ctx context.Context, client TargetsAPI, solutionsClient SolutionsAPI, solutionVersionsClient SolutionVersionsAPI, resourceGroupName, targetName, solutionVersionID string, dryRun bool) error {
+	if dryRun {
+		logDryRun(ctx, "install", "Microsoft.Edge/targets", targetName, map[string]interface{}{
+			"solutionVersionId": solutionVersionID,
+		})
+		return nil
+	}
+
+	solutionName, err := solutionNameOf(solutionVersionID)
+	if err != nil {
+		return err
+	}
+
+	installOperation := func() error {
+		loggerFrom(ctx).Info("Installing solution version", logKeyResource, targetName, "solutionVersionId", solutionVersionID)
+
+		poller, err := client.BeginInstallSolution(ctx, resourceGroupName, targetName, armworkloadorchestration.InstallSolutionParameter{
+			SolutionVersionID: to.Ptr(solutionVersionID),
+		}, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := pollUntilDone(ctx, poller, "solution install", targetState(client, resourceGroupName, targetName)); err != nil {
+			return err
+		}
+
+		// The install can complete without the new version taking over, so check what is live
+		deployedVersionID, err := GetCurrentDeployedVersion(ctx, solutionsClient, solutionVersionsClient, resourceGroupName, targetName, solutionName)
+		if err != nil {
+			return fmt.Errorf("error verifying the install: %w", err)
+		}
+		if !strings.EqualFold(deployedVersionID, solutionVersionID) {
+			return fmt.Errorf("install completed but the deployed solution version is %q, not %s", deployedVersionID, solutionVersionID)
+		}
+
+		loggerFrom(ctx).Info("Solution version installed", logKeyResource, targetName, "solutionVersionId", solutionVersionID)
+		return nil
+	}
+
+	return retryOperation(ctx, RetryInstall, DefaultRetryPolicy, installOperation)
+}
+
+// GetCurrentDeployedVersion returns the ID of the version of solutionName in the Deployed state
+// on the target, or "" when none of its versions is deployed. Versions of the target's other
+// solutions are ignored. See ListInstalledSolutions for versions still deploying.
+func GetCurrentDeployedVersion(ctx context.Context, solutionsClient SolutionsAPI, solutionVersionsClient SolutionVersionsAPI, resourceGroupName, targetName, solutionName string) (string, error) {
+	installed, err := ListInstalledSolutions(ctx, solutionsClient, solutionVersionsClient, resourceGroupName, targetName)
+	if err != nil {
+		return "", err
+	}
+	for _, solution := range installed {
+		if strings.EqualFold(solution.SolutionName, solutionName) && solution.State == armworkloadorchestration.StateDeployed {
+			return solution.SolutionVersionID, nil
+		}
+	}
+	return "", nil
+}
+
+// solutionNameOf returns the name of the solution that owns a solution version, read from the
+// version's resource ID.
+func solutionNameOf(solutionVersionID string) (string, error) {
+	id, err := arm.ParseResourceID(solutionVersionID)
+	if err != nil || !strings.EqualFold(id.ResourceType.String(), "Microsoft.Edge/targets/solutions/versions") {
+		return "", fmt.Errorf("solution version ID %q is not a Microsoft.Edge/targets/solutions/versions resource ID", solutionVersionID)
+	}
+	return id.Parent.Name, nil
+}
+
+// Installs a solution version like InstallTarget, but first notes which solution version is
+// currently deployed on the target. If the install fails, that previous version is installed
+// again and a *RollbackError recording both versions is returned. Without a previous version
+// (a fresh target, or one already running solutionVersionID) the install error is returned as is.
+func InstallTargetWithRollback(ctx context.Context, targetsClient TargetsAPI, solutionsClient SolutionsAPI, solutionVersionsClient SolutionVersionsAPI, resourceGroupName, targetName, solutionVersionID string, dryRun bool) error {
+	var previousVersionID string
+	solutionName, err := solutionNameOf(solutionVersionID)
+	if err == nil {
+		previousVersionID, err = GetCurrentDeployedVersion(ctx, solutionsClient, solutionVersionsClient, resourceGroupName, targetName, solutionName)
+	}
+	if err != nil {
+		loggerFrom(ctx).Warn("Could not determine the deployed solution version, install will not be rolled back", logKeyResource, targetName, logKeyError, err)
+	}
+
+	installErr := InstallTarget(ctx, targetsClient, solutionsClient, solutionVersionsClient, resourceGroupName, targetName, solutionVersionID, dryRun)
+	if installErr == nil {
+		return nil
+	}
+	if ctx.Err() != nil || previousVersionID == "" || strings.EqualFold(previousVersionID, solutionVersionID) {
+		return installErr
+	}
+
+	loggerFrom(ctx).Warn("Install failed, rolling back to the previous solution version", logKeyResource, targetName,
+		"failedVersion", solutionVersionID, "rollbackVersion", previousVersionID, logKeyError, installErr)
+	rollbackErr := InstallTarget(ctx, targetsClient, solutionsClient, solutionVersionsClient, resourceGroupName, targetName, previousVersionID, dryRun)
+	if rollbackErr != nil {
+		loggerFrom(ctx).Error("Rollback failed", logKeyResource, targetName, "rollbackVersion", previousVersionID, logKeyError, rollbackErr)
+	} else {
+		loggerFrom(ctx).Info("Rolled back to the previous solution version", logKeyResource, targetName,
+			"failedVersion", solutionVersionID, "rollbackVersion", previousVersionID)
+	}
+	return &RollbackError{
+		FailedVersion: solutionVersionID,
+		RolledBackTo:  previousVersionID,
+		Err:           installErr,
+		RollbackErr:   rollbackErr,
+	}
+}
+
+// Deploys a new solution template version onto an already-existing target.
+// This is the day-2 operation: only review, publish and install run; the schema,
+// solution template, target and context are reused as they are.
+// Returns the solution version ID that was installed.
+// With rollbackOnFailure set, a failed install reinstalls the previously deployed version (see
+// InstallTargetWithRollback).
+// With dryRun set, the target and template version are still looked up but nothing is deployed.
+func UpdateDeployment(ctx context.Context, targetsClient TargetsAPI, solutionTemplateVersionsClient SolutionTemplateVersionsAPI, solutionsClient SolutionsAPI, solutionVersionsClient SolutionVersionsAPI, resourceGroupName, targetName, solutionTemplateName, templateVersion string, rollbackOnFailure, dryRun bool) (string, error) {
+	loggerFrom(ctx).Info("Updating deployment", logKeyResource, targetName, "solutionTemplate", solutionTemplateName, "version", templateVersion)
+
+	if _, err := targetsClient.Get(ctx, resourceGroupName, targetName, nil); err != nil {
+		return "", fmt.Errorf("target %s not found: %w", targetName, err)
+	}
+
+	version, err := solutionTemplateVersionsClient.Get(ctx, resourceGroupName, solutionTemplateName, templateVersion, nil)
+	if err != nil {
+		return "", fmt.Errorf("solution template version %s/%s not found: %w", solutionTemplateName, templateVersion, err)
+	}
+	if version.ID == nil {
+		return "", fmt.Errorf("solution template version %s/%s has no resource ID", solutionTemplateName, templateVersion)
+	}
+
+	solutionVersionID, _, err := ReviewTarget(ctx, targetsClient, solutionsClient, solutionVersionsClient, resourceGroupName, targetName, *version.ID, dryRun)
+	if err != nil {
+		return "", err
+	}
+
+	if err := PublishTarget(ctx, targetsClient, resourceGroupName, targetName, solutionVersionID, dryRun); err != nil {
+		return "", fmt.Errorf("error publishing solution version: %w", err)
+	}
+
+	if rollbackOnFailure {
+		err = InstallTargetWithRollback(ctx, targetsClient, solutionsClient, solutionVersionsClient, resourceGroupName, targetName, solutionVersionID, dryRun)
+	} else {
+		err = InstallTarget(ctx, targetsClient, solutionsClient, solutionVersionsClient, resourceGroupName, targetName, solutionVersionID, dryRun)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error installing solution version: %w", err)
+	}
+
+	loggerFrom(ctx).Info("Deployment updated", logKeyResource, targetName, "solutionVersionId", solutionVersionID)
+	return solutionVersionID, nil
+}
+
+// validateContextID checks contextID is the full resource ID of a Microsoft.Edge/contexts resource.
+func validateContextID(contextID string) error {
+	id, err := arm.ParseResourceID(contextID)
+	if err != nil {
+		return fmt.Errorf("context ID %q is not a valid resource ID (see ContextResourceID): %w", contextID, err)
+	}
+	if !strings.EqualFold(id.ResourceType.String(), "Microsoft.Edge/contexts") {
+		return fmt.Errorf("context ID %q is a %s, expected a Microsoft.Edge/contexts resource ID", contextID, id.ResourceType)
+	}
+	return nil
+}
+
+// targetState fetches a target's provisioning state for timeout diagnostics and retry decisions.
+func targetState(client TargetsAPI, resourceGroupName, targetName string) stateFunc {
+	return func(ctx context.Context) (*armworkloadorchestration.ProvisioningState, error) {
+		current, err := client.Get(ctx, resourceGroupName, targetName, nil)
+		if err != nil || current.Properties == nil {
+			return nil, err
+		}
+		return current.Properties.ProvisioningState, nil
+	}
+}
@@ -0,0 +1,248 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+func TestCreateTargetRetriesWhileInProgress(t *testing.T) {
+	ctx := WithRetryPolicy(testContext(), RetryTargetCreation, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	var creates int
+	state := armworkloadorchestration.ProvisioningStateInprogress
+	client := &fakeTargets{
+		createOrUpdate: func(targetName string, resource armworkloadorchestration.Target) (*runtime.Poller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse], error) {
+			creates++
+			if creates == 1 {
+				return nil, responseError(409, "Conflict", "another operation is in progress")
+			}
+			state = armworkloadorchestration.ProvisioningStateSucceeded
+			return donePoller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse](t, targetInState(targetName, state)), nil
+		},
+		get: func(targetName string) (armworkloadorchestration.TargetsClientGetResponse, error) {
+			return armworkloadorchestration.TargetsClientGetResponse{Target: targetInState(targetName, state)}, nil
+		},
+	}
+
+	target, err := CreateTarget(ctx, client, "rg", "line-1", "eastus", ContextResourceID("sub", "rg", "ctx"), nil, []string{"soap"}, nil, "", "", nil, false)
+	if err != nil {
+		t.Fatalf("CreateTarget: %v", err)
+	}
+	if creates != 2 {
+		t.Errorf("create attempts = %d, want 2", creates)
+	}
+	if got := provisioningState(target); got != string(armworkloadorchestration.ProvisioningStateSucceeded) {
+		t.Errorf("provisioning state = %q, want Succeeded", got)
+	}
+}
+
+func TestCreateTargetStopsOnFailedState(t *testing.T) {
+	ctx := WithRetryPolicy(testContext(), RetryTargetCreation, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	var creates int
+	client := &fakeTargets{
+		createOrUpdate: func(string, armworkloadorchestration.Target) (*runtime.Poller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse], error) {
+			creates++
+			return nil, responseError(400, "BadRequest", "invalid capability")
+		},
+		get: func(targetName string) (armworkloadorchestration.TargetsClientGetResponse, error) {
+			return armworkloadorchestration.TargetsClientGetResponse{Target: targetInState(targetName, armworkloadorchestration.ProvisioningStateFailed)}, nil
+		},
+	}
+
+	if _, err := CreateTarget(ctx, client, "rg", "line-1", "eastus", ContextResourceID("sub", "rg", "ctx"), nil, []string{"soap"}, nil, "", "", nil, false); err == nil {
+		t.Fatal("CreateTarget succeeded, want an error")
+	}
+	if creates != 1 {
+		t.Errorf("create attempts = %d, want 1: a Failed target isn't retried", creates)
+	}
+}
+
+func TestGetCurrentDeployedVersionOnlyLooksAtTheSolution(t *testing.T) {
+	solutions := &fakeSolutions{names: []string{"other", "app"}}
+	versions := &fakeSolutionVersions{versions: map[string][]*armworkloadorchestration.SolutionVersion{
+		"other": {solutionVersionInState("other", "1.0.0", armworkloadorchestration.StateDeployed)},
+		"app": {
+			solutionVersionInState("app", "1.0.0", armworkloadorchestration.StateDeploying),
+			solutionVersionInState("app", "2.0.0", armworkloadorchestration.StateDeployed),
+		},
+	}}
+
+	tests := []struct {
+		solution string
+		want     string
+	}{
+		{solution: "app", want: testSolutionVersionID("app", "2.0.0")},
+		{solution: "other", want: testSolutionVersionID("other", "1.0.0")},
+		{solution: "missing", want: ""},
+	}
+	for _, tt := range tests {
+		got, err := GetCurrentDeployedVersion(testContext(), solutions, versions, "rg", "target", tt.solution)
+		if err != nil {
+			t.Fatalf("GetCurrentDeployedVersion(%s): %v", tt.solution, err)
+		}
+		if got != tt.want {
+			t.Errorf("GetCurrentDeployedVersion(%s) = %q, want %q", tt.solution, got, tt.want)
+		}
+	}
+}
+
+func TestInstallTargetVerifiesTheInstalledSolution(t *testing.T) {
+	ctx := WithRetryPolicy(testContext(), RetryInstall, NoRetry)
+	// Another solution's deployed version is listed first and must not fail the check
+	solutions := &fakeSolutions{names: []string{"other", "app"}}
+	versions := &fakeSolutionVersions{versions: map[string][]*armworkloadorchestration.SolutionVersion{
+		"other": {solutionVersionInState("other", "1.0.0", armworkloadorchestration.StateDeployed)},
+		"app":   {solutionVersionInState("app", "2.0.0", armworkloadorchestration.StateDeployed)},
+	}}
+	installing := testSolutionVersionID("app", "2.0.0")
+	client := &fakeTargets{
+		install: func(body armworkloadorchestration.InstallSolutionParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientInstallSolutionResponse], error) {
+			if body.SolutionVersionID == nil || *body.SolutionVersionID != installing {
+				t.Errorf("installed %v, want %s", body.SolutionVersionID, installing)
+			}
+			return donePoller[armworkloadorchestration.TargetsClientInstallSolutionResponse](t, map[string]any{}), nil
+		},
+	}
+
+	if err := InstallTarget(ctx, client, solutions, versions, "rg", "target", installing, false); err != nil {
+		t.Fatalf("InstallTarget: %v", err)
+	}
+
+	versions.versions["app"][0].Properties.State = to.Ptr(armworkloadorchestration.StateFailed)
+	if err := InstallTarget(ctx, client, solutions, versions, "rg", "target", installing, false); err == nil {
+		t.Fatal("InstallTarget succeeded although the version isn't deployed")
+	}
+}
+
+func TestInstallTargetRejectsNonSolutionVersionIDs(t *testing.T) {
+	templateVersionID := "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Edge/solutionTemplates/app/versions/1.0.0"
+	err := InstallTarget(testContext(), &fakeTargets{}, &fakeSolutions{}, &fakeSolutionVersions{}, "rg", "target", templateVersionID, false)
+	if err == nil {
+		t.Fatal("InstallTarget accepted a solution template version ID")
+	}
+}
+
+func TestPublishTargetPublishesTheSolutionVersion(t *testing.T) {
+	ctx := WithRetryPolicy(testContext(), RetryPublish, NoRetry)
+	publishing := testSolutionVersionID("app", "2.0.0")
+	var publishes int
+	client := &fakeTargets{
+		publish: func(body armworkloadorchestration.SolutionVersionParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientPublishSolutionVersionResponse], error) {
+			publishes++
+			if body.SolutionVersionID == nil || *body.SolutionVersionID != publishing {
+				t.Errorf("published %v, want %s", body.SolutionVersionID, publishing)
+			}
+			return donePoller[armworkloadorchestration.TargetsClientPublishSolutionVersionResponse](t, map[string]any{}), nil
+		},
+	}
+
+	if err := PublishTarget(ctx, client, "rg", "target", publishing, false); err != nil {
+		t.Fatalf("PublishTarget: %v", err)
+	}
+	if publishes != 1 {
+		t.Errorf("publishes = %d, want 1", publishes)
+	}
+
+	templateVersionID := "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Edge/solutionTemplates/app/versions/1.0.0"
+	if err := PublishTarget(ctx, client, "rg", "target", templateVersionID, false); err == nil {
+		t.Error("PublishTarget accepted a solution template version ID")
+	}
+	if publishes != 1 {
+		t.Errorf("publishes = %d, want the template version ID not to be sent", publishes)
+	}
+}
+
+func TestResolveSolutionVersionID(t *testing.T) {
+	solutions := &fakeSolutions{names: []string{"other", "app"}}
+	versions := &fakeSolutionVersions{versions: map[string][]*armworkloadorchestration.SolutionVersion{
+		"other": {solutionVersionInState("other", "1.0.0", armworkloadorchestration.StateDeployed)},
+		"app":   {solutionVersionInState("app", "2.0.0", armworkloadorchestration.StateDeployed)},
+	}}
+
+	tests := []struct {
+		name     string
+		nameOrID string
+		want     string
+		wantErr  bool
+	}{
+		{name: "full ID is kept", nameOrID: testSolutionVersionID("app", "9.9.9"), want: testSolutionVersionID("app", "9.9.9")},
+		{name: "name alone is resolved", nameOrID: "2.0.0", want: testSolutionVersionID("app", "2.0.0")},
+		{name: "unknown name", nameOrID: "3.0.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSolutionVersionID(testContext(), solutions, versions, "rg", "target", tt.nameOrID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ResolveSolutionVersionID = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveSolutionVersionID: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveSolutionVersionID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateDeploymentReusesTheExistingTemplateVersion(t *testing.T) {
+	ctx := WithRetryPolicy(testContext(), RetryReview, NoRetry)
+	ctx = WithRetryPolicy(ctx, RetryPublish, NoRetry)
+	ctx = WithRetryPolicy(ctx, RetryInstall, NoRetry)
+
+	templateVersionID := "/subscriptions/sub-id/resourceGroups/rg/providers/Microsoft.Edge/solutionTemplates/app/versions/2.0.0"
+	templateVersions := &fakeSolutionTemplateVersions{versions: map[string][]*armworkloadorchestration.SolutionTemplateVersion{
+		"app": {{ID: to.Ptr(templateVersionID), Name: to.Ptr("2.0.0")}},
+	}}
+	solutions := &fakeSolutions{names: []string{"app"}}
+	versions := &fakeSolutionVersions{versions: map[string][]*armworkloadorchestration.SolutionVersion{
+		"app": {solutionVersionInState("app", "2.0.0", armworkloadorchestration.StateDeployed)},
+	}}
+	solutionVersionID := testSolutionVersionID("app", "2.0.0")
+
+	var published, installed string
+	client := &fakeTargets{
+		// createOrUpdate is left unset: an update must not touch the target itself
+		get: func(targetName string) (armworkloadorchestration.TargetsClientGetResponse, error) {
+			return armworkloadorchestration.TargetsClientGetResponse{Target: targetInState(targetName, armworkloadorchestration.ProvisioningStateSucceeded)}, nil
+		},
+		review: func(body armworkloadorchestration.SolutionTemplateParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientReviewSolutionVersionResponse], error) {
+			if got := stringValue(body.SolutionTemplateVersionID); got != templateVersionID {
+				t.Errorf("reviewed %q, want the existing template version %s", got, templateVersionID)
+			}
+			return donePoller[armworkloadorchestration.TargetsClientReviewSolutionVersionResponse](t, map[string]any{"id": solutionVersionID}), nil
+		},
+		publish: func(body armworkloadorchestration.SolutionVersionParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientPublishSolutionVersionResponse], error) {
+			published = stringValue(body.SolutionVersionID)
+			return donePoller[armworkloadorchestration.TargetsClientPublishSolutionVersionResponse](t, map[string]any{}), nil
+		},
+		install: func(body armworkloadorchestration.InstallSolutionParameter) (*runtime.Poller[armworkloadorchestration.TargetsClientInstallSolutionResponse], error) {
+			installed = stringValue(body.SolutionVersionID)
+			return donePoller[armworkloadorchestration.TargetsClientInstallSolutionResponse](t, map[string]any{}), nil
+		},
+	}
+
+	got, err := UpdateDeployment(ctx, client, templateVersions, solutions, versions, "rg", "target", "app", "2.0.0", false, false)
+	if err != nil {
+		t.Fatalf("UpdateDeployment: %v", err)
+	}
+	if got != solutionVersionID {
+		t.Errorf("UpdateDeployment = %q, want %s", got, solutionVersionID)
+	}
+	if templateVersions.gets != 1 {
+		t.Errorf("template version looked up %d times, want 1", templateVersions.gets)
+	}
+	if published != solutionVersionID || installed != solutionVersionID {
+		t.Errorf("published %q and installed %q, want %s for both", published, installed, solutionVersionID)
+	}
+
+	if _, err := UpdateDeployment(ctx, client, templateVersions, solutions, versions, "rg", "target", "app", "3.0.0", false, false); err == nil {
+		t.Error("UpdateDeployment succeeded with a template version that doesn't exist")
+	}
+}
@@ -0,0 +1,74 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+	"golang.org/x/sync/errgroup"
+)
+
+// TargetSpec describes one target for CreateTargets. The fields and their defaults are
+// those of CreateTarget's parameters.
+type TargetSpec struct {
+	Name             string
+	Location         string
+	ContextID        string
+	ExtendedLocation *ExtendedLocation
+	Capabilities     []string
+	Topologies       []TargetTopology
+	SolutionScope    SolutionScope
+	HierarchyLevel   string
+	Tags             map[string]string
+}
+
+// TargetResult is the outcome of creating one target: Target on success, Err otherwise.
+type TargetResult struct {
+	Spec   TargetSpec
+	Target *armworkloadorchestration.Target
+	Err    error
+}
+
+// CreateTargets creates many targets with at most concurrency of them in flight at once
+// (values below 1 mean one at a time). Every target gets its own CreateTarget call, so the
+// retry policy and per-operation timeout carried by ctx apply to each one separately: a
+// target that keeps failing or hangs only holds up its own slot, and the others carry on.
+// Hierarchy levels aren't checked against the context here; see ValidateHierarchyLevel.
+// The results come back in the order of specs. The returned error joins the failures of
+// every target that could not be created, and is nil when all of them were.
+func CreateTargets(ctx context.Context, client TargetsAPI, resourceGroupName string, specs []TargetSpec, concurrency int, dryRun bool) ([]TargetResult, error) {
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		if spec.Name == "" {
+			return nil, fmt.Errorf("every target needs a name")
+		}
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("target %s is listed more than once", spec.Name)
+		}
+		seen[spec.Name] = true
+	}
+
+	results := make([]TargetResult, len(specs))
+	var group errgroup.Group
+	group.SetLimit(max(concurrency, 1))
+	for i, spec := range specs {
+		group.Go(func() error {
+			target, err := CreateTarget(ctx, client, resourceGroupName, spec.Name, spec.Location, spec.ContextID, spec.ExtendedLocation, spec.Capabilities, spec.Topologies, spec.SolutionScope, spec.HierarchyLevel, spec.Tags, dryRun)
+			results[i] = TargetResult{Spec: spec, Target: target, Err: err}
+			return nil // A failed target must not cancel the others
+		})
+	}
+	group.Wait()
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("target %s: %w", result.Spec.Name, result.Err))
+		}
+	}
+	if len(errs) > 0 {
+		loggerFrom(ctx).Warn("Some targets could not be created", "failed", len(errs), "total", len(specs))
+	}
+	return results, errors.Join(errs...)
+}
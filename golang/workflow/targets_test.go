@@ -0,0 +1,88 @@
+package workflow
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+func TestCreateTargetsRunsConcurrentlyAndKeepsOrder(t *testing.T) {
+	ctx := WithRetryPolicy(testContext(), RetryTargetCreation, NoRetry)
+	const concurrency = 2
+
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	full := make(chan struct{})
+	var fullOnce sync.Once
+	client := &fakeTargets{
+		createOrUpdate: func(targetName string, resource armworkloadorchestration.Target) (*runtime.Poller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse], error) {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			if inFlight == concurrency {
+				fullOnce.Do(func() { close(full) })
+			}
+			mu.Unlock()
+			// Hold the slot until the pool is full, so the test sees targets in flight together
+			select {
+			case <-full:
+			case <-time.After(time.Second):
+			}
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+
+			if targetName == "line-3" {
+				return nil, responseError(400, "BadRequest", "invalid capability")
+			}
+			return donePoller[armworkloadorchestration.TargetsClientCreateOrUpdateResponse](t, targetInState(targetName, armworkloadorchestration.ProvisioningStateSucceeded)), nil
+		},
+		get: func(targetName string) (armworkloadorchestration.TargetsClientGetResponse, error) {
+			state := armworkloadorchestration.ProvisioningStateSucceeded
+			if targetName == "line-3" {
+				state = armworkloadorchestration.ProvisioningStateFailed
+			}
+			return armworkloadorchestration.TargetsClientGetResponse{Target: targetInState(targetName, state)}, nil
+		},
+	}
+
+	contextID := ContextResourceID("sub", "rg", "ctx")
+	names := []string{"line-1", "line-2", "line-3", "line-4", "line-5"}
+	var specs []TargetSpec
+	for _, name := range names {
+		specs = append(specs, TargetSpec{Name: name, Location: "eastus", ContextID: contextID, Capabilities: []string{"soap"}})
+	}
+
+	results, err := CreateTargets(ctx, client, "rg", specs, concurrency, false)
+	if err == nil || !strings.Contains(err.Error(), "target line-3") {
+		t.Errorf("error = %v, want one naming line-3", err)
+	}
+	if maxInFlight != concurrency {
+		t.Errorf("at most %d targets were in flight, want %d", maxInFlight, concurrency)
+	}
+	if len(results) != len(names) {
+		t.Fatalf("got %d results, want %d", len(results), len(names))
+	}
+	for i, result := range results {
+		if result.Spec.Name != names[i] {
+			t.Errorf("result %d is for %s, want %s", i, result.Spec.Name, names[i])
+		}
+		if failed := result.Err != nil; failed != (names[i] == "line-3") {
+			t.Errorf("%s: err = %v", names[i], result.Err)
+		}
+		if result.Err == nil && (result.Target == nil || *result.Target.Name != names[i]) {
+			t.Errorf("%s: target = %+v", names[i], result.Target)
+		}
+	}
+}
+
+func TestCreateTargetsRejectsDuplicateNames(t *testing.T) {
+	specs := []TargetSpec{{Name: "line-1"}, {Name: "line-1"}}
+	if _, err := CreateTargets(testContext(), &fakeTargets{}, "rg", specs, 2, false); err == nil {
+		t.Fatal("CreateTargets accepted a duplicate target name")
+	}
+}
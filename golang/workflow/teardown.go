@@ -0,0 +1,308 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+)
+
+// WorkflowResourceNames identifies the resources created by one workflow run.
+// Empty fields are skipped during teardown.
+type WorkflowResourceNames struct {
+	TargetName           string
+	SolutionTemplateName string
+	SchemaName           string
+
+	// Capabilities added to the context by the run; removed only when RemoveCapabilities is set
+	ContextResourceGroup string
+	ContextName          string
+	ContextLocation      string
+	Capabilities         []string
+	RemoveCapabilities   bool
+}
+
+// Deletes everything a workflow run created, in dependency order:
+// target, solution template versions and template, schema versions and schema,
+// and finally (optionally) the capabilities added to the context.
+// Keeps going past individual failures so one stuck resource doesn't block the rest,
+// and returns all failures joined together.
+func TeardownWorkflow(ctx context.Context, clientFactory *armworkloadorchestration.ClientFactory, resourceGroupName string, names WorkflowResourceNames) error {
+	loggerFrom(ctx).Info("Tearing down workflow resources", "resourceGroup", resourceGroupName)
+
+	var errs []error
+
+	if names.TargetName != "" {
+		if err := DeleteTarget(ctx, clientFactory.NewTargetsClient(), resourceGroupName, names.TargetName); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if names.SolutionTemplateName != "" {
+		if err := DeleteSolutionTemplate(ctx, clientFactory.NewSolutionTemplatesClient(), clientFactory.NewSolutionTemplateVersionsClient(), resourceGroupName, names.SolutionTemplateName); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if names.SchemaName != "" {
+		errs = append(errs, deleteSchemaAndVersions(ctx, clientFactory.NewSchemasClient(), clientFactory.NewSchemaVersionsClient(), resourceGroupName, names.SchemaName)...)
+	}
+
+	if names.RemoveCapabilities && names.ContextName != "" && len(names.Capabilities) > 0 {
+		if err := RemoveCapabilitiesFromContext(ctx, clientFactory.NewContextsClient(), names.ContextResourceGroup, names.ContextName, names.ContextLocation, names.Capabilities); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		loggerFrom(ctx).Warn("Teardown finished with errors", "errors", len(errs))
+		return errors.Join(errs...)
+	}
+
+	loggerFrom(ctx).Info("Teardown completed")
+	return nil
+}
+
+// teardownContext returns a context for deleting resources after ctx may have run into the
+// run's Timeout: it keeps ctx's values but not its deadline, is bounded by
+// InterruptTeardownTimeout instead, and is still cancelled along with parent.
+func teardownContext(ctx, parent context.Context) (context.Context, context.CancelFunc) {
+	teardownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), InterruptTeardownTimeout)
+	stop := context.AfterFunc(parent, cancel)
+	return teardownCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// deleteSchemaAndVersions deletes every version of a schema and then, unless one of those
+// deletions failed, the schema itself. It returns every failure it ran into.
+func deleteSchemaAndVersions(ctx context.Context, schemasClient SchemasAPI, schemaVersionsClient SchemaVersionsAPI, resourceGroupName, schemaName string) []error {
+	var errs []error
+
+	versions, err := ListSchemaVersions(ctx, schemaVersionsClient, resourceGroupName, schemaName)
+	if err != nil && !isNotFound(err) {
+		errs = append(errs, fmt.Errorf("error listing versions of schema %s: %w", schemaName, err))
+	}
+
+	versionErrs := len(errs)
+	for _, version := range versions {
+		if version.Name == nil {
+			continue
+		}
+		if err := DeleteSchemaVersion(ctx, schemaVersionsClient, resourceGroupName, schemaName, *version.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// The schema can only go once all of its versions are gone
+	if len(errs) == versionErrs {
+		if err := DeleteSchema(ctx, schemasClient, schemaVersionsClient, resourceGroupName, schemaName); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Deletes every target, solution template and schema in the resource group tagged with runID
+// (see RunTags), for garbage-collecting runs that failed before their teardown.
+// Contexts are shared between runs and are never deleted.
+// Keeps going past individual failures and returns all of them joined together.
+func CleanupByRunID(ctx context.Context, clientFactory *armworkloadorchestration.ClientFactory, resourceGroupName, runID string) error {
+	if runID == "" {
+		return fmt.Errorf("a run ID is required")
+	}
+	loggerFrom(ctx).Info("Cleaning up resources of an earlier run", "runId", runID, "resourceGroup", resourceGroupName)
+
+	var errs []error
+	deleted := 0
+
+	// Dependency order: targets, then solution templates, then schemas
+	targetsClient := clientFactory.NewTargetsClient()
+	targets, err := ListTargets(ctx, targetsClient, resourceGroupName)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing targets: %w", err))
+	}
+	for _, target := range targets {
+		if target.Name == nil || !hasRunTag(target.Tags, runID) {
+			continue
+		}
+		deleted++
+		if err := DeleteTarget(ctx, targetsClient, resourceGroupName, *target.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	solutionTemplatesClient := clientFactory.NewSolutionTemplatesClient()
+	solutionTemplates, err := ListSolutionTemplates(ctx, solutionTemplatesClient, resourceGroupName)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing solution templates: %w", err))
+	}
+	for _, solutionTemplate := range solutionTemplates {
+		if solutionTemplate.Name == nil || !hasRunTag(solutionTemplate.Tags, runID) {
+			continue
+		}
+		deleted++
+		if err := DeleteSolutionTemplate(ctx, solutionTemplatesClient, clientFactory.NewSolutionTemplateVersionsClient(), resourceGroupName, *solutionTemplate.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	schemasClient := clientFactory.NewSchemasClient()
+	schemas, err := ListSchemas(ctx, schemasClient, resourceGroupName)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error listing schemas: %w", err))
+	}
+	for _, schema := range schemas {
+		if schema.Name == nil || !hasRunTag(schema.Tags, runID) {
+			continue
+		}
+		deleted++
+		errs = append(errs, deleteSchemaAndVersions(ctx, schemasClient, clientFactory.NewSchemaVersionsClient(), resourceGroupName, *schema.Name)...)
+	}
+
+	if len(errs) > 0 {
+		loggerFrom(ctx).Warn("Cleanup finished with errors", "errors", len(errs))
+		return errors.Join(errs...)
+	}
+
+	loggerFrom(ctx).Info("Cleanup completed", "deleted", deleted)
+	return nil
+}
+
+// Deletes a target and waits for the deletion to finish.
+// A target that is already gone counts as deleted.
+func DeleteTarget(ctx context.Context, client TargetsAPI, resourceGroupName, targetName string) error {
+	loggerFrom(ctx).Info("Deleting target", logKeyResource, targetName)
+
+	poller, err := client.BeginDelete(ctx, resourceGroupName, targetName, nil)
+	if err != nil {
+		if isNotFound(err) {
+			loggerFrom(ctx).Info("Target already deleted", logKeyResource, targetName)
+			return nil
+		}
+		return fmt.Errorf("error deleting target %s: %w", targetName, err)
+	}
+
+	if _, err := pollUntilDone(ctx, poller, "target deletion", nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("error polling target deletion for %s: %w", targetName, err)
+	}
+
+	loggerFrom(ctx).Info("Target deleted", logKeyResource, targetName)
+	return nil
+}
+
+// Removes every version of a solution template, then deletes the template itself.
+// A template that is already gone counts as deleted.
+func DeleteSolutionTemplate(ctx context.Context, client SolutionTemplatesAPI, versionsClient SolutionTemplateVersionsAPI, resourceGroupName, solutionTemplateName string) error {
+	loggerFrom(ctx).Info("Deleting solution template", logKeyResource, solutionTemplateName)
+
+	versions, err := ListSolutionTemplateVersions(ctx, versionsClient, resourceGroupName, solutionTemplateName)
+	if err != nil {
+		if isNotFound(err) {
+			loggerFrom(ctx).Info("Solution template already deleted", logKeyResource, solutionTemplateName)
+			return nil
+		}
+		return fmt.Errorf("error listing versions of solution template %s: %w", solutionTemplateName, err)
+	}
+
+	for _, v := range versions {
+		if v.Name == nil {
+			continue
+		}
+		version := *v.Name
+		loggerFrom(ctx).Info("Removing solution template version", logKeyResource, solutionTemplateName, "version", version)
+		poller, err := client.BeginRemoveVersion(ctx, resourceGroupName, solutionTemplateName, armworkloadorchestration.VersionParameter{
+			Version: to.Ptr(version),
+		}, nil)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("error removing solution template version %s: %w", version, err)
+		}
+		if _, err := pollUntilDone(ctx, poller, "solution template version removal", nil); err != nil && !isNotFound(err) {
+			return fmt.Errorf("error polling solution template version removal for %s: %w", version, err)
+		}
+	}
+
+	poller, err := client.BeginDelete(ctx, resourceGroupName, solutionTemplateName, nil)
+	if err != nil {
+		if isNotFound(err) {
+			loggerFrom(ctx).Info("Solution template already deleted", logKeyResource, solutionTemplateName)
+			return nil
+		}
+		return fmt.Errorf("error deleting solution template %s: %w", solutionTemplateName, err)
+	}
+
+	if _, err := pollUntilDone(ctx, poller, "solution template deletion", nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("error polling solution template deletion for %s: %w", solutionTemplateName, err)
+	}
+
+	loggerFrom(ctx).Info("Solution template deleted", logKeyResource, solutionTemplateName)
+	return nil
+}
+
+// Drops the named capabilities from a context and writes the remaining set back.
+func RemoveCapabilitiesFromContext(ctx context.Context, client ContextsAPI, resourceGroupName, contextName, location string, names []string) error {
+	_, err := removeCapabilities(ctx, client, resourceGroupName, contextName, location, names)
+	return err
+}
+
+// RemoveCapability drops one capability from a context, e.g. to prune the ones earlier runs
+// added, and returns how many capabilities the context has left. A capability that isn't in
+// the context is logged as a warning and leaves the context untouched. The context keeps its
+// location, hierarchies and tags.
+func RemoveCapability(ctx context.Context, client ContextsAPI, resourceGroupName, contextName, name string) (int, error) {
+	return removeCapabilities(ctx, client, resourceGroupName, contextName, "", []string{name})
+}
+
+// removeCapabilities writes the context back without the named capabilities and returns how many
+// remain. The write is conditional on the context's ETag; when another run changes the context in
+// between, it is re-read and the removal applied again. An empty location keeps the context's own.
+func removeCapabilities(ctx context.Context, client ContextsAPI, resourceGroupName, contextName, location string, names []string) (int, error) {
+	loggerFrom(ctx).Info("Removing capabilities from context", logKeyResource, contextName, "capabilities", names)
+
+	drop := make(map[string]bool, len(names))
+	for _, name := range names {
+		drop[name] = true
+	}
+
+	for attempt := 1; ; attempt++ {
+		existing, err := GetExistingContext(ctx, client, resourceGroupName, contextName)
+		if err != nil {
+			return 0, fmt.Errorf("error fetching context %s: %w", contextName, err)
+		}
+
+		found := make(map[string]bool, len(names))
+		remaining := make([]Capability, 0, len(existing.Capabilities))
+		for _, cap := range existing.Capabilities {
+			if drop[cap.Name] {
+				found[cap.Name] = true
+				continue
+			}
+			remaining = append(remaining, cap)
+		}
+		for _, name := range names {
+			if !found[name] {
+				loggerFrom(ctx).Warn("Capability not found in context, nothing to remove", logKeyResource, contextName, "capability", name)
+			}
+		}
+		if len(remaining) == len(existing.Capabilities) {
+			return len(remaining), nil
+		}
+
+		_, _, err = CreateOrUpdateContextWithHierarchies(ctx, client, resourceGroupName, contextName, valueOrDefault(location, existing.Location), remaining, existing.Hierarchies, existing.Tags, existing, false)
+		if isPreconditionFailed(err) && attempt < maxContextUpdateAttempts {
+			loggerFrom(ctx).Info("Context changed since it was read, re-reading and removing again",
+				logKeyResource, contextName, "attempt", attempt+1, "maxAttempts", maxContextUpdateAttempts)
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("error removing capabilities from context %s: %w", contextName, err)
+		}
+		loggerFrom(ctx).Info("Capabilities removed from context", logKeyResource, contextName, "remaining", len(remaining))
+		return len(remaining), nil
+	}
+}
@@ -0,0 +1,60 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// tokenRefreshMargin is how long before expiry a cached token is considered stale,
+// so a request never goes out with a token that expires mid-flight.
+const tokenRefreshMargin = 5 * time.Minute
+
+// cachingCredential reuses access tokens from an underlying credential until they near expiry.
+// The REST configuration calls fetch a token per request; over a long workflow this keeps
+// AAD traffic down and refreshes tokens before they lapse.
+type cachingCredential struct {
+	credential azcore.TokenCredential
+	now        func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]azcore.AccessToken
+}
+
+// newCachingCredential wraps credential with a token cache.
+func newCachingCredential(credential azcore.TokenCredential) *cachingCredential {
+	return &cachingCredential{
+		credential: credential,
+		now:        time.Now,
+		tokens:     make(map[string]azcore.AccessToken),
+	}
+}
+
+// GetToken returns a cached token for the requested scopes and tenant while it is still fresh.
+// Requests carrying claims (e.g. a continuous access evaluation challenge) always go to the
+// underlying credential, since they exist to replace the current token.
+func (c *cachingCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	if options.Claims != "" {
+		return c.credential.GetToken(ctx, options)
+	}
+
+	key := options.TenantID + "|" + strings.Join(options.Scopes, " ")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if token, ok := c.tokens[key]; ok && c.now().Add(tokenRefreshMargin).Before(token.ExpiresOn) {
+		return token, nil
+	}
+
+	token, err := c.credential.GetToken(ctx, options)
+	if err != nil {
+		return azcore.AccessToken{}, err
+	}
+	c.tokens[key] = token
+	return token, nil
+}
@@ -0,0 +1,70 @@
+package workflow
+
+import "fmt"
+
+// TargetBinding maps a component role to the provider that deploys it on the target.
+type TargetBinding struct {
+	Role     string                 `yaml:"role"`     // Component type handled, e.g. "helm.v3"
+	Provider string                 `yaml:"provider"` // Symphony provider, e.g. "providers.target.helm"
+	Config   map[string]interface{} `yaml:"config"`   // Provider-specific settings
+}
+
+// TargetTopology is one set of bindings in a target specification.
+type TargetTopology struct {
+	Bindings []TargetBinding `yaml:"bindings"`
+}
+
+// Topology used when the caller does not supply one: Helm deployed into the target's own cluster.
+func DefaultTargetTopologies() []TargetTopology {
+	return []TargetTopology{
+		{
+			Bindings: []TargetBinding{
+				{
+					Role:     "helm.v3",
+					Provider: "providers.target.helm",
+					Config: map[string]interface{}{
+						"inCluster": "true",
+					},
+				},
+			},
+		},
+	}
+}
+
+// buildTargetSpecification renders topologies into the target specification layout.
+func buildTargetSpecification(topologies []TargetTopology) (map[string]interface{}, error) {
+	if len(topologies) == 0 {
+		return nil, fmt.Errorf("target specification needs at least one topology")
+	}
+
+	items := make([]map[string]interface{}, 0, len(topologies))
+	for i, topology := range topologies {
+		if len(topology.Bindings) == 0 {
+			return nil, fmt.Errorf("topology at index %d has no bindings", i)
+		}
+
+		bindings := make([]map[string]interface{}, 0, len(topology.Bindings))
+		for _, binding := range topology.Bindings {
+			if binding.Role == "" || binding.Provider == "" {
+				return nil, fmt.Errorf("topology at index %d has a binding without role or provider", i)
+			}
+			config := binding.Config
+			if config == nil {
+				config = map[string]interface{}{}
+			}
+			bindings = append(bindings, map[string]interface{}{
+				"role":     binding.Role,
+				"provider": binding.Provider,
+				"config":   config,
+			})
+		}
+
+		items = append(items, map[string]interface{}{
+			"bindings": bindings,
+		})
+	}
+
+	return map[string]interface{}{
+		"topologies": items,
+	}, nil
+}
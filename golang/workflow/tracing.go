@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Span attribute keys used by the workflow's tracing.
+const (
+	attrRunID             = attribute.Key("workflow.run_id")
+	attrDryRun            = attribute.Key("workflow.dry_run")
+	attrResource          = attribute.Key("workflow.resource")
+	attrProvisioningState = attribute.Key("azure.provisioning_state")
+)
+
+// tracerOrNoop returns tracer, or a tracer that records nothing when it is nil.
+func tracerOrNoop(tracer trace.Tracer) trace.Tracer {
+	if tracer == nil {
+		return noop.NewTracerProvider().Tracer("")
+	}
+	return tracer
+}
+
+// recordStepSpan adds a span for a finished step, covering start until now, as a child of
+// the span in ctx. A failed step records err on the span and marks it as an error.
+func recordStepSpan(ctx context.Context, tracer trace.Tracer, step, resource string, start time.Time, err error, attrs ...attribute.KeyValue) {
+	_, span := tracer.Start(ctx, step, trace.WithTimestamp(start), trace.WithAttributes(attrResource.String(resource)), trace.WithAttributes(attrs...))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// provisioningStateAttrs returns the provisioning state attribute of a schema, solution template,
+// target or context, or nothing when the resource or its state is missing.
+func provisioningStateAttrs(resource interface{}) []attribute.KeyValue {
+	state := provisioningState(resource)
+	if state == "" {
+		return nil
+	}
+	return []attribute.KeyValue{attrProvisioningState.String(state)}
+}
+
+// provisioningState returns the provisioning state of a schema, solution template, target
+// or context, or "" when the resource is nil or doesn't report one.
+func provisioningState(resource interface{}) string {
+	var state *armworkloadorchestration.ProvisioningState
+	switch r := resource.(type) {
+	case *armworkloadorchestration.Schema:
+		if r != nil && r.Properties != nil {
+			state = r.Properties.ProvisioningState
+		}
+	case *armworkloadorchestration.SolutionTemplate:
+		if r != nil && r.Properties != nil {
+			state = r.Properties.ProvisioningState
+		}
+	case *armworkloadorchestration.Target:
+		if r != nil && r.Properties != nil {
+			state = r.Properties.ProvisioningState
+		}
+	case *armworkloadorchestration.Context:
+		if r != nil && r.Properties != nil {
+			state = r.Properties.ProvisioningState
+		}
+	}
+	if state == nil {
+		return ""
+	}
+	return string(*state)
+}
@@ -0,0 +1,110 @@
+package workflow
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+)
+
+// TransportOptions configures the connection every Azure request goes over: the SDK clients,
+// the token requests and the Configuration API calls. The zero value changes nothing.
+type TransportOptions struct {
+	// Proxy is the URL of an HTTP(S) proxy for every request, e.g. "http://proxy.corp:3128".
+	// It takes precedence over HTTPS_PROXY and HTTP_PROXY, which apply as usual when it is empty.
+	// Loopback and link-local hosts, such as the managed identity endpoint, and the hosts
+	// listed in NO_PROXY are still reached directly.
+	Proxy string
+
+	// CAFile is a PEM bundle of CA certificates to trust on top of the system's, e.g. the root
+	// of a TLS-inspecting proxy
+	CAFile string
+
+	// InsecureSkipVerify turns off server certificate verification altogether. Only for local
+	// testing: anyone on the network path can then read and alter the traffic, tokens included.
+	InsecureSkipVerify bool
+}
+
+// NewHTTPTransport returns a copy of http.DefaultTransport configured by options.
+func NewHTTPTransport(options TransportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options.Proxy != "" {
+		proxyURL, err := neturl.Parse(options.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q: expected e.g. http://proxy.example.com:3128", options.Proxy)
+		}
+		noProxy := os.Getenv("NO_PROXY")
+		if noProxy == "" {
+			noProxy = os.Getenv("no_proxy")
+		}
+		transport.Proxy = func(req *http.Request) (*neturl.URL, error) {
+			if bypassProxy(req.URL.Hostname(), noProxy) {
+				return nil, nil
+			}
+			return proxyURL, nil
+		}
+	}
+
+	if options.CAFile != "" || options.InsecureSkipVerify {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: options.InsecureSkipVerify}
+		if options.CAFile != "" {
+			pool, err := loadCABundle(options.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+// loadCABundle returns the system's trusted CAs plus the PEM certificates in path.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		// Not every platform exposes its store; the bundle alone is then trusted
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA file %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// NewHTTPClient returns a client for the Configuration API calls that sends over transport,
+// with the same timeout as the client used when Options.HTTPClient is nil.
+func NewHTTPClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{Timeout: sharedHTTPClient.Timeout, Transport: transport}
+}
+
+// bypassProxy reports whether host is reached directly: a loopback or link-local address,
+// localhost, or a host matching an entry of noProxy, a comma-separated list of host names,
+// domain suffixes such as ".corp.example.com", or "*".
+func bypassProxy(host, noProxy string) bool {
+	if host == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsLinkLocalUnicast()) {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" || host == strings.TrimPrefix(entry, ".") || strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Generates unique version numbers for schemas and solution templates.
+// Uses semantic versioning format (major.minor.patch) to avoid naming conflicts.
+// Each run creates unique resource names to prevent Azure resource conflicts.
+// The result is MAJOR.MINOR.PATCH with major 0-10, minor 0-20 and patch 0-100, followed by
+// "-alpha.N", "-beta.N" or "-rc.N" (N 1-10) when includePrerelease is set, and by "+B"
+// (B 1-10000) when includeBuild is set, e.g. "3.14.59-rc.2+4821".
+// The numbers are drawn from source, or from crypto/rand when it is nil.
+func GenerateRandomSemanticVersion(source RandomSource, includePrerelease, includeBuild bool) string {
+	source = orSecureRandom(source)
+	major := source.Intn(11)
+	minor := source.Intn(21)
+	patch := source.Intn(101)
+	version := fmt.Sprintf("%d.%d.%d", major, minor, patch)
+
+	if includePrerelease {
+		prereleaseTypes := []string{"alpha", "beta", "rc"}
+		prereleaseType := prereleaseTypes[source.Intn(len(prereleaseTypes))]
+		prereleaseNum := source.Intn(10) + 1
+		version += fmt.Sprintf("-%s.%d", prereleaseType, prereleaseNum)
+	}
+
+	if includeBuild {
+		buildNum := source.Intn(10000) + 1
+		version += fmt.Sprintf("+%d", buildNum)
+	}
+
+	return version
+}
+
+// ValidateSemanticVersion checks that version is a legal Semantic Versioning 2.0.0 string:
+// MAJOR.MINOR.PATCH with no leading zeros, optionally followed by "-" and a prerelease, then by
+// "+" and build metadata. Both are dot-separated, non-empty identifiers of ASCII letters, digits
+// and hyphens. Numeric prerelease identifiers can't have leading zeros; build identifiers can.
+// So "1.0.0-rc.1+build.007" is valid, while "1.0.0-rc.01", "1.0.0+" and "1.0.0-a..b" are not.
+func ValidateSemanticVersion(version string) error {
+	rest, build, hasBuild := strings.Cut(version, "+")
+	core, prerelease, hasPrerelease := strings.Cut(rest, "-")
+
+	fields := strings.Split(core, ".")
+	if len(fields) != 3 {
+		return fmt.Errorf("invalid version %q: expected MAJOR.MINOR.PATCH", version)
+	}
+	for _, field := range fields {
+		if !isNumericIdentifier(field) {
+			return fmt.Errorf("invalid version %q: %q is not a number without leading zeros", version, field)
+		}
+	}
+	if hasPrerelease {
+		for _, identifier := range strings.Split(prerelease, ".") {
+			if !isSemverIdentifier(identifier) {
+				return fmt.Errorf("invalid version %q: bad prerelease identifier %q", version, identifier)
+			}
+			if isDigits(identifier) && !isNumericIdentifier(identifier) {
+				return fmt.Errorf("invalid version %q: numeric prerelease identifier %q has a leading zero", version, identifier)
+			}
+		}
+	}
+	if hasBuild {
+		for _, identifier := range strings.Split(build, ".") {
+			if !isSemverIdentifier(identifier) {
+				return fmt.Errorf("invalid version %q: bad build identifier %q", version, identifier)
+			}
+		}
+	}
+	return nil
+}
+
+// isSemverIdentifier reports whether s is a non-empty run of ASCII letters, digits and hyphens.
+func isSemverIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumericIdentifier reports whether s is a number without leading zeros, e.g. "0" or "42".
+func isNumericIdentifier(s string) bool {
+	return isDigits(s) && (s == "0" || s[0] != '0')
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// NextSemanticVersion returns the version after the highest of existing, with the part named by
+// bump ("major", "minor" or "patch", case-insensitive) incremented and the parts after it reset,
+// e.g. "1.4.2" bumped by minor gives "1.5.0". Prerelease and build suffixes are ignored when
+// comparing and dropped from the result. Entries that aren't MAJOR.MINOR.PATCH versions are
+// skipped, and with none left the bump starts from "0.0.0". The result is higher than every
+// existing version, so it never collides with one.
+func NextSemanticVersion(existing []string, bump string) (string, error) {
+	var highest [3]int
+	for _, version := range existing {
+		parts, ok := parseVersionCore(version)
+		if ok && compareVersionCores(parts, highest) > 0 {
+			highest = parts
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(bump)) {
+	case "major":
+		highest = [3]int{highest[0] + 1, 0, 0}
+	case "minor":
+		highest = [3]int{highest[0], highest[1] + 1, 0}
+	case "patch":
+		highest[2]++
+	default:
+		return "", fmt.Errorf("unknown version bump %q (expected major, minor or patch)", bump)
+	}
+	return fmt.Sprintf("%d.%d.%d", highest[0], highest[1], highest[2]), nil
+}
+
+// parseVersionCore reads the MAJOR.MINOR.PATCH part of version, ignoring any "-prerelease" or
+// "+build" suffix.
+func parseVersionCore(version string) ([3]int, bool) {
+	var parts [3]int
+	core, _, _ := strings.Cut(version, "+")
+	core, _, _ = strings.Cut(core, "-")
+	fields := strings.Split(core, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+func compareVersionCores(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+// Maximum number of candidate versions tried before giving up on finding an unused one.
+const maxVersionAttempts = 10
+
+// Picks a version from generate that is not already taken.
+// Regenerates on collision and fails once maxAttempts candidates have all been taken.
+func pickUniqueVersion(ctx context.Context, generate func() string, taken func(string) (bool, error), maxAttempts int) (string, error) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		candidate := generate()
+		exists, err := taken(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		loggerFrom(ctx).Debug("Version already exists, generating another", "version", candidate)
+	}
+	return "", fmt.Errorf("no free version found after %d attempts", maxAttempts)
+}
+
+// GetNextVersion increments the counter stored in the file at path and returns the new value,
+// starting from 1 when the file doesn't exist yet. The read-increment-write is serialized
+// across processes with an advisory lock on path+".lock", so parallel runs never reuse a
+// version, and the new value is written atomically. A file that doesn't hold a number is an
+// error rather than a reset, which could hand out versions again.
+func GetNextVersion(path string) (int, error) {
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return 0, fmt.Errorf("error locking version file: %w", err)
+	}
+	defer unlock()
+
+	var version int
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return 0, fmt.Errorf("error reading version file: %w", err)
+		}
+	} else {
+		version, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return 0, fmt.Errorf("error parsing version file %s: %w", path, err)
+		}
+	}
+
+	version++
+	if err := writeFileAtomic(path, []byte(strconv.Itoa(version)), 0644); err != nil {
+		return 0, fmt.Errorf("error writing version file: %w", err)
+	}
+
+	return version, nil
+}
+
+// writeFileAtomic writes data to a temp file in the same directory and renames it
+// over filename, so readers never observe a partially written file.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // No-op once the rename has succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, filename)
+}
@@ -0,0 +1,168 @@
+package workflow
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+var generatedVersionPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(?:-(?:alpha|beta|rc)\.(\d+))?(?:\+(\d+))?$`)
+
+func TestGenerateRandomSemanticVersionFormat(t *testing.T) {
+	inRange := func(t *testing.T, version, part string, low, high int) {
+		t.Helper()
+		n, err := strconv.Atoi(part)
+		if err != nil || n < low || n > high {
+			t.Errorf("%s: %q is outside %d-%d", version, part, low, high)
+		}
+	}
+
+	for _, prerelease := range []bool{false, true} {
+		for _, build := range []bool{false, true} {
+			t.Run(fmt.Sprintf("prerelease=%t,build=%t", prerelease, build), func(t *testing.T) {
+				source := rand.New(rand.NewSource(42))
+				for i := 0; i < 500; i++ {
+					version := GenerateRandomSemanticVersion(source, prerelease, build)
+					match := generatedVersionPattern.FindStringSubmatch(version)
+					if match == nil {
+						t.Fatalf("version %q doesn't match %s", version, generatedVersionPattern)
+					}
+					inRange(t, version, match[1], 0, 10)
+					inRange(t, version, match[2], 0, 20)
+					inRange(t, version, match[3], 0, 100)
+					if got := match[4] != ""; got != prerelease {
+						t.Errorf("version %q: prerelease present = %t, want %t", version, got, prerelease)
+					} else if prerelease {
+						inRange(t, version, match[4], 1, 10)
+					}
+					if got := match[5] != ""; got != build {
+						t.Errorf("version %q: build present = %t, want %t", version, got, build)
+					} else if build {
+						inRange(t, version, match[5], 1, 10000)
+					}
+					if err := ValidateSemanticVersion(version); err != nil {
+						t.Errorf("generated version is not valid semver: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestGenerateRandomSemanticVersionIsDeterministic(t *testing.T) {
+	first := rand.New(rand.NewSource(7))
+	second := rand.New(rand.NewSource(7))
+	for i := 0; i < 20; i++ {
+		a := GenerateRandomSemanticVersion(first, true, true)
+		b := GenerateRandomSemanticVersion(second, true, true)
+		if a != b {
+			t.Fatalf("draw %d: %q != %q with the same seed", i, a, b)
+		}
+	}
+}
+
+func TestValidateSemanticVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		valid   bool
+	}{
+		{"0.0.0", true},
+		{"1.2.3", true},
+		{"10.20.100", true},
+		{"1.0.0-alpha", true},
+		{"1.0.0-alpha.1", true},
+		{"1.0.0-0.3.7", true},
+		{"1.0.0-x.7.z.92", true},
+		{"1.0.0-x-y-z.--", true},
+		{"1.0.0-rc.1+build.007", true},
+		{"1.0.0+20130313144700", true},
+		{"1.0.0-beta+exp.sha.5114f85", true},
+		{"1.0.0+21AF26D3----117B344092BD", true},
+
+		{"", false},
+		{"1", false},
+		{"1.2", false},
+		{"1.2.3.4", false},
+		{"01.2.3", false},
+		{"1.02.3", false},
+		{"1.2.03", false},
+		{"-1.2.3", false},
+		{"1.2.x", false},
+		{"v1.2.3", false},
+		{"1.0.0-", false},
+		{"1.0.0+", false},
+		{"1.0.0-rc.01", false},
+		{"1.0.0-a..b", false},
+		{"1.0.0-rc.1+", false},
+		{"1.0.0-rc.1+build..1", false},
+		{"1.0.0-rc_1", false},
+		{"1.0.0+build+1", false},
+		{"1.0.0-é", false},
+	}
+	for _, tt := range tests {
+		err := ValidateSemanticVersion(tt.version)
+		if tt.valid && err != nil {
+			t.Errorf("ValidateSemanticVersion(%q) = %v, want valid", tt.version, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("ValidateSemanticVersion(%q) accepted an invalid version", tt.version)
+		}
+	}
+}
+
+func TestGetNextVersionConcurrentCallers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "version.txt")
+	const callers, calls = 8, 10
+
+	var mu sync.Mutex
+	seen := map[int]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < calls; j++ {
+				version, err := GetNextVersion(path)
+				if err != nil {
+					t.Errorf("GetNextVersion: %v", err)
+					return
+				}
+				mu.Lock()
+				if seen[version] {
+					t.Errorf("version %d handed out twice", version)
+				}
+				seen[version] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for version := 1; version <= callers*calls; version++ {
+		if !seen[version] {
+			t.Errorf("version %d was skipped", version)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading version file: %v", err)
+	}
+	if got := string(data); got != strconv.Itoa(callers*calls) {
+		t.Errorf("version file holds %q, want %d", got, callers*calls)
+	}
+}
+
+func TestGetNextVersionRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "version.txt")
+	if err := os.WriteFile(path, []byte("not a number"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetNextVersion(path); err == nil {
+		t.Fatal("GetNextVersion reset a corrupt counter instead of failing")
+	}
+}
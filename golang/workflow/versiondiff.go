@@ -0,0 +1,264 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of ValueChange.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// ValueChange is one value that differs between two solution template versions. Path locates
+// it in the YAML configurations or the specification, e.g. "components[0].properties.chart.version".
+type ValueChange struct {
+	Path string      `json:"path"`
+	Kind string      `json:"kind"` // ChangeAdded, ChangeRemoved or ChangeChanged
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// SolutionTemplateVersionDiff lists how the configurations and specification of solution
+// template version To differ from those of version From. A version that doesn't exist is
+// compared as empty and flagged with FromMissing or ToMissing.
+// It marshals to JSON as is; String renders it as text.
+type SolutionTemplateVersionDiff struct {
+	SolutionTemplate string        `json:"solutionTemplate"`
+	From             string        `json:"from"`
+	To               string        `json:"to"`
+	FromMissing      bool          `json:"fromMissing,omitempty"`
+	ToMissing        bool          `json:"toMissing,omitempty"`
+	Configurations   []ValueChange `json:"configurations"`
+	Specification    []ValueChange `json:"specification"`
+}
+
+// Fetches two versions of a solution template and compares their configurations and
+// specification value by value. Fails only when neither version exists.
+func DiffSolutionTemplateVersions(ctx context.Context, client SolutionTemplateVersionsAPI, resourceGroupName, solutionTemplateName, versionA, versionB string) (*SolutionTemplateVersionDiff, error) {
+	diff := &SolutionTemplateVersionDiff{SolutionTemplate: solutionTemplateName, From: versionA, To: versionB}
+
+	fetch := func(version string) (configurations interface{}, specification map[string]any, missing bool, err error) {
+		res, err := client.Get(ctx, resourceGroupName, solutionTemplateName, version, nil)
+		if isNotFound(err) {
+			return nil, nil, true, nil
+		}
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("error getting solution template version %s/%s: %w", solutionTemplateName, version, err)
+		}
+		if res.Properties == nil {
+			return nil, nil, false, nil
+		}
+		if res.Properties.Configurations != nil {
+			configurations = parseConfigurations(*res.Properties.Configurations)
+		}
+		return configurations, res.Properties.Specification, false, nil
+	}
+
+	configA, specA, missingA, err := fetch(versionA)
+	if err != nil {
+		return nil, err
+	}
+	configB, specB, missingB, err := fetch(versionB)
+	if err != nil {
+		return nil, err
+	}
+	if missingA && missingB {
+		return nil, fmt.Errorf("neither version %s nor %s of solution template %s exists", versionA, versionB, solutionTemplateName)
+	}
+	diff.FromMissing, diff.ToMissing = missingA, missingB
+
+	// Round-trip the specification through JSON so both sides hold the same value types
+	diff.Configurations = diffValues(configA, configB)
+	// Registry credentials stay out of the diff; it only shows that they changed
+	diff.Specification = redactSecretChanges(diffValues(normalizeJSON(specA), normalizeJSON(specB)))
+	return diff, nil
+}
+
+// redactSecretChanges replaces every "password" value in changes, whether the change is to the
+// password itself or to a chart that holds one, with "REDACTED".
+func redactSecretChanges(changes []ValueChange) []ValueChange {
+	for i, change := range changes {
+		if strings.HasSuffix(change.Path, ".password") {
+			changes[i].From, changes[i].To = redactedValue(change.From), redactedValue(change.To)
+			continue
+		}
+		changes[i].From, changes[i].To = redactSecrets(change.From), redactSecrets(change.To)
+	}
+	return changes
+}
+
+// redactSecrets returns a copy of value with the value of every "password" key inside it redacted.
+func redactSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if key == "password" {
+				redacted[key] = redactedValue(item)
+			} else {
+				redacted[key] = redactSecrets(item)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactSecrets(item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+// redactedValue stands in for a secret; a missing one stays missing.
+func redactedValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return "REDACTED"
+}
+
+// String renders the diff as text: "+" for added values, "-" for removed ones and "~" for changed ones.
+func (d *SolutionTemplateVersionDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Solution template %s: %s -> %s\n", d.SolutionTemplate, d.From, d.To)
+	if d.FromMissing {
+		fmt.Fprintf(&b, "Version %s does not exist; compared as empty\n", d.From)
+	}
+	if d.ToMissing {
+		fmt.Fprintf(&b, "Version %s does not exist; compared as empty\n", d.To)
+	}
+	for _, section := range []struct {
+		name    string
+		changes []ValueChange
+	}{{"Configurations", d.Configurations}, {"Specification", d.Specification}} {
+		fmt.Fprintf(&b, "%s:\n", section.name)
+		if len(section.changes) == 0 {
+			b.WriteString("  (no changes)\n")
+			continue
+		}
+		for _, change := range section.changes {
+			switch change.Kind {
+			case ChangeAdded:
+				fmt.Fprintf(&b, "  + %s: %s\n", change.Path, formatDiffValue(change.To))
+			case ChangeRemoved:
+				fmt.Fprintf(&b, "  - %s: %s\n", change.Path, formatDiffValue(change.From))
+			default:
+				fmt.Fprintf(&b, "  ~ %s: %s -> %s\n", change.Path, formatDiffValue(change.From), formatDiffValue(change.To))
+			}
+		}
+	}
+	return b.String()
+}
+
+// parseConfigurations decodes a version's YAML configurations, keeping text that isn't
+// valid YAML as a single string so it is still compared.
+func parseConfigurations(configurations string) interface{} {
+	var parsed interface{}
+	if err := yaml.Unmarshal([]byte(configurations), &parsed); err != nil {
+		return configurations
+	}
+	return parsed
+}
+
+// normalizeJSON converts value to the types encoding/json decodes into, e.g. float64 for every number.
+func normalizeJSON(value map[string]any) interface{} {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// diffValues compares two decoded documents and returns their differences ordered by path.
+func diffValues(a, b interface{}) []ValueChange {
+	changes := []ValueChange{}
+	collectChanges("", a, b, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func collectChanges(path string, a, b interface{}, changes *[]ValueChange) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		*changes = append(*changes, ValueChange{Path: pathOrRoot(path), Kind: ChangeAdded, To: b})
+		return
+	case b == nil:
+		*changes = append(*changes, ValueChange{Path: pathOrRoot(path), Kind: ChangeRemoved, From: a})
+		return
+	}
+
+	mapA, aIsMap := a.(map[string]interface{})
+	mapB, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		keys := map[string]bool{}
+		for key := range mapA {
+			keys[key] = true
+		}
+		for key := range mapB {
+			keys[key] = true
+		}
+		for key := range keys {
+			collectChanges(joinDiffPath(path, key), mapA[key], mapB[key], changes)
+		}
+		return
+	}
+
+	listA, aIsList := a.([]interface{})
+	listB, bIsList := b.([]interface{})
+	if aIsList && bIsList {
+		for i := 0; i < max(len(listA), len(listB)); i++ {
+			var itemA, itemB interface{}
+			if i < len(listA) {
+				itemA = listA[i]
+			}
+			if i < len(listB) {
+				itemB = listB[i]
+			}
+			collectChanges(fmt.Sprintf("%s[%d]", path, i), itemA, itemB, changes)
+		}
+		return
+	}
+
+	if formatDiffValue(a) != formatDiffValue(b) {
+		*changes = append(*changes, ValueChange{Path: pathOrRoot(path), Kind: ChangeChanged, From: a, To: b})
+	}
+}
+
+func joinDiffPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathOrRoot(path string) string {
+	return valueOrDefault(path, "(root)")
+}
+
+// formatDiffValue renders a value compactly as JSON, falling back to %v.
+func formatDiffValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
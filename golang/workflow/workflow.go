@@ -0,0 +1,992 @@
+// Package workflow implements the end-to-end Azure Workload Orchestration sample:
+// context capabilities, schema, solution template, target, configuration, and the
+// review/publish/install deployment flow. Each step is exported so it can be reused
+// on its own; Run drives the whole sequence.
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/workloadorchestration/armworkloadorchestration"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+// Default configuration; each value can be overridden through Options
+const (
+	LOCATION               = "eastus2euap"
+	SUBSCRIPTION_ID        = "973d15c6-6c57-447e-b9c6-6d79b5b784ab"
+	RESOURCE_GROUP         = "sdkexamples"
+	CONTEXT_RESOURCE_GROUP = "Mehoopany"
+	CONTEXT_NAME           = "Mehoopany-Context"
+)
+
+// Options configures a workflow run. Zero values fall back to the sample defaults.
+type Options struct {
+	SubscriptionID       string
+	ResourceGroup        string // Defaults to RESOURCE_GROUP
+	Location             string // Defaults to LOCATION
+	ContextResourceGroup string // Defaults to CONTEXT_RESOURCE_GROUP
+	ContextName          string // Defaults to CONTEXT_NAME
+	Credential           azcore.TokenCredential
+	Cloud                cloud.Configuration // Azure public cloud when zero
+	ExtendedLocation     *ExtendedLocation   // Where the target's workloads run; DefaultExtendedLocation() when nil
+	SolutionScope        SolutionScope       // Where on the target solutions are deployed; SolutionScopeNew when empty
+	HierarchyLevel       string              // The context hierarchy level the target sits at; DefaultHierarchyLevel when empty
+	HTTPClient           *http.Client        // For the Configuration API calls; a shared client with a timeout when nil
+	// ClientOptions configure the SDK clients, e.g. with a proxy transport (WithClientTransport)
+	// or different retries (WithClientRetry). They apply after Cloud and so override it.
+	ClientOptions []ClientOption
+
+	// RunID identifies this run in the names of the resources it creates; generated when empty
+	RunID string
+	// NamePrefix starts every generated resource name; defaults to DefaultNamePrefix
+	NamePrefix string
+	// Names overrides individual resource names; empty fields are derived from NamePrefix and RunID
+	Names ResourceNames
+	// Tags are applied to every created resource, e.g. for cost attribution. The runId and
+	// createdBy tags (see RunTags) are added on top and take precedence.
+	Tags map[string]string
+	// UpdateType says which part of the version number the solution template version bumps
+	// (see ParseUpdateType); the service's default applies when empty
+	UpdateType armworkloadorchestration.UpdateType
+	// VersionBump names new schema and solution template versions by bumping that part of the
+	// highest existing version (see NextSemanticVersion) rather than picking them at random;
+	// random when empty. Takes the same values as UpdateType.
+	VersionBump armworkloadorchestration.UpdateType
+
+	SchemaRules    []SchemaRule             // Built-in soap/hotmelt rules when nil
+	Components     []Component              // Sample Helm chart when nil
+	Topologies     []TargetTopology         // Bindings of a new target; TopologiesForComponents(Components) when nil
+	ConfigValues   map[string]interface{}   // DefaultConfigValues() when nil
+	ConflictPolicy CapabilityConflictPolicy // Defaults to CapabilityConflictReject
+	Hierarchies    []Hierarchy              // Merged into the context's existing hierarchies; DefaultHierarchies() when nil
+	AuditSink      AuditSink                // Records are discarded when nil
+	Logger         *slog.Logger             // Receives the run's progress; a text logger on stderr when nil (see NewLogger)
+	Tracer         trace.Tracer             // Receives a span for the run and one per step; no-op when nil
+	RandomSource   RandomSource             // Generated versions and capability names draw from it; crypto/rand when nil
+	// SeedCapabilities are reconciled into the context along with the generated capability, e.g.
+	// a canonical list read with LoadCapabilitiesFromJSON; ConflictPolicy applies to them too
+	SeedCapabilities []Capability
+	// ConfigDefaults fill in optional schema fields missing from ConfigValues; required fields
+	// are never defaulted. DefaultConfigDefaults() when nil, and an empty map disables defaulting.
+	ConfigDefaults map[string]interface{}
+	// Capability names a capability already in the context for the run to use instead of adding
+	// a new one; the context is then left unchanged, and teardown leaves the capability in place.
+	// RunRegions sets it so every region shares the capability the first one added.
+	Capability string
+	// CapabilitiesFile receives the context's merged capabilities on each update; DefaultCapabilitiesFile when empty
+	CapabilitiesFile string
+
+	// HTTPLog receives a trace of every HTTP request and response, from the SDK clients (see
+	// EnableHTTPLogging) and the Configuration API calls alike, with credentials redacted.
+	// Nothing is logged when nil.
+	HTTPLog io.Writer
+
+	// HealthCheckTimeout, when set, makes the run wait after a successful install until the
+	// solution's HealthCheckEndpoint answers 200 OK (see WaitForHealthy). The check only runs
+	// when the configuration values set HealthCheckEnabled to true. Zero skips it.
+	HealthCheckTimeout time.Duration
+
+	// OperationTimeout bounds each long-running operation separately from any deadline on the
+	// context passed to Run; zero leaves operations unbounded
+	OperationTimeout time.Duration
+
+	// Timeout bounds the whole run, every step included. When it expires, the step in flight is
+	// cancelled and recorded as WorkflowResult.InterruptedStep. Teardown still gets up to
+	// InterruptTeardownTimeout past it. Zero leaves the run unbounded.
+	Timeout time.Duration
+
+	// RetryPolicies overrides the retry policy of individual operations, keyed by
+	// RetryContextUpdate, RetryTargetCreation, ...; see WithRetryPolicy
+	RetryPolicies map[string]RetryPolicy
+
+	// DisableRetries runs every operation exactly once (NoRetry), overriding RetryPolicies, and
+	// turns off the SDK clients' own retries too
+	DisableRetries bool
+
+	// ResumeTokenPath is a file in which target creation and review save their poller resume
+	// tokens while running; a later run resumes any operation found there. Disabled when empty.
+	ResumeTokenPath string
+
+	// RollbackOnFailure reinstalls the solution version that was deployed on the target before
+	// the run when installing the new one fails; see InstallTargetWithRollback
+	RollbackOnFailure bool
+
+	// CheckpointPath is a file recording each step the run completes and the resources it
+	// produced; a later run with the same RunID skips the recorded steps (see Checkpoint). The
+	// file is removed once every step has succeeded. Disabled when empty, and in a dry run.
+	CheckpointPath string
+	// ResumeFromCheckpoint continues the run recorded at CheckpointPath, taking its run ID when
+	// RunID is empty, and fails when there is nothing to resume
+	ResumeFromCheckpoint bool
+	// DiscardCheckpoint deletes the file at CheckpointPath so every step runs again
+	DiscardCheckpoint bool
+
+	// ReviewOnly validates the solution template version against the target (see PreviewReview)
+	// instead of reviewing it, and stops there: no solution version is created, published or
+	// installed. The findings are in WorkflowResult.ReviewPreview.
+	ReviewOnly bool
+
+	// Teardown deletes everything the run created once it finishes
+	Teardown bool
+
+	// TeardownOnInterrupt deletes whatever the run had created when ctx is cancelled part-way,
+	// e.g. by SIGINT, or Timeout expires. The deletions run on a fresh context bounded by
+	// InterruptTeardownTimeout.
+	TeardownOnInterrupt bool
+
+	// CancelOnInterrupt checks the target when the run is interrupted during target creation,
+	// review, publish or install, and logs whether its operation is still running server-side
+	// (see CheckOperationCancellable). The service can't cancel these operations, so nothing is
+	// stopped. Runs before TeardownOnInterrupt.
+	CancelOnInterrupt bool
+
+	// CleanupRunID, when set, makes Run only delete the resources tagged with that run ID
+	// (see CleanupByRunID) instead of running the workflow
+	CleanupRunID string
+
+	// DryRun prints each create/update the run would submit and continues with synthetic
+	// results instead. Read-only lookups still run; no long-running operation is started.
+	DryRun bool
+
+	// Events receives a StepEvent as each step starts, succeeds or fails. Sends never block: an
+	// event that finds the channel full is dropped, so give it a buffer. Run doesn't close it.
+	// Nothing is sent when nil.
+	Events chan<- StepEvent
+
+	// Update switches to the day-2 flow: deploy a new solution template version
+	// onto an existing target instead of creating anything
+	Update *DeploymentUpdate
+}
+
+// InterruptTeardownTimeout bounds the cleanup started by Options.TeardownOnInterrupt.
+const InterruptTeardownTimeout = 15 * time.Minute
+
+// DeploymentUpdate names the existing target and the solution template version to roll onto it.
+type DeploymentUpdate struct {
+	TargetName           string
+	SolutionTemplateName string
+	Version              string
+}
+
+// DefaultConfigValues returns the sample configuration values matching the default schema rules.
+func DefaultConfigValues() map[string]interface{} {
+	return map[string]interface{}{
+		"ErrorThreshold":      35.3,
+		"HealthCheckEndpoint": "http://localhost:8080/health",
+		"EnableLocalLog":      true,
+		"AgentEndpoint":       "http://localhost:8080/agent",
+		"HealthCheckEnabled":  true,
+		"ApplicationEndpoint": "http://localhost:8080/app",
+		"TemperatureRangeMax": 100.5,
+	}
+}
+
+// DefaultConfigDefaults returns the values filled in for optional schema fields the
+// configuration values leave out (see ApplyConfigDefaults). The health check stays off unless
+// it is asked for.
+func DefaultConfigDefaults() map[string]interface{} {
+	return map[string]interface{}{
+		"HealthCheckEndpoint": "http://localhost:8080/health",
+		"HealthCheckEnabled":  false,
+	}
+}
+
+// Run executes the complete workflow:
+// 1. Adds a new capability to the context and selects it for all resources
+// 2. Creates a schema and schema version
+// 3. Creates a solution template and solution template version
+// 4. Creates a target
+// 5. Sets configuration values via the Configuration API
+// 6. Reviews, publishes and installs the solution on the target
+func Run(ctx context.Context, opts Options) (*WorkflowResult, error) {
+	if opts.Credential == nil {
+		return nil, fmt.Errorf("a credential is required")
+	}
+	if opts.SubscriptionID == "" {
+		return nil, fmt.Errorf("a subscription ID is required")
+	}
+	subscriptionID := opts.SubscriptionID
+	if opts.Logger != nil {
+		ctx = WithLogger(ctx, opts.Logger)
+	}
+	if opts.RandomSource != nil {
+		ctx = WithRandomSource(ctx, opts.RandomSource)
+	}
+	logger := loggerFrom(ctx)
+	// Catch a malformed custom location before anything is created rather than at the target step
+	if opts.ExtendedLocation != nil {
+		if err := opts.ExtendedLocation.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid extended location: %w", err)
+		}
+	}
+	if _, err := ParseSolutionScope(string(opts.SolutionScope)); err != nil {
+		return nil, err
+	}
+	if _, err := ParseUpdateType(string(opts.UpdateType)); err != nil {
+		return nil, err
+	}
+	if _, err := ParseUpdateType(string(opts.VersionBump)); err != nil {
+		return nil, fmt.Errorf("invalid version bump: %w", err)
+	}
+	if opts.ResumeFromCheckpoint && opts.DiscardCheckpoint {
+		return nil, fmt.Errorf("resuming from a checkpoint and discarding it are mutually exclusive")
+	}
+	if opts.ReviewOnly && opts.Update != nil {
+		return nil, fmt.Errorf("a review-only run cannot update a deployment")
+	}
+	httpClient := opts.HTTPClient
+	if opts.HTTPLog != nil {
+		EnableHTTPLogging(opts.HTTPLog)
+		httpClient = withHTTPLogging(httpClientOrDefault(httpClient), opts.HTTPLog)
+	}
+	// Reuse tokens across the REST configuration calls until they near expiry
+	credential := newCachingCredential(opts.Credential)
+	// Teardown outlives Timeout, but not a cancellation by the caller
+	callerCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	if opts.OperationTimeout > 0 {
+		ctx = WithOperationTimeout(ctx, opts.OperationTimeout)
+	}
+	for operation, policy := range opts.RetryPolicies {
+		ctx = WithRetryPolicy(ctx, operation, policy)
+	}
+	if opts.DisableRetries {
+		for _, operation := range retryOperationNames {
+			ctx = WithRetryPolicy(ctx, operation, NoRetry)
+		}
+	}
+	// Nothing is started in a dry run, so there is nothing to resume
+	if opts.ResumeTokenPath != "" && !opts.DryRun {
+		store, err := OpenResumeStore(opts.ResumeTokenPath)
+		if err != nil {
+			return nil, err
+		}
+		if pending := store.Pending(); pending > 0 {
+			logger.Info("Found interrupted operations; they will be resumed", "pending", pending, "file", opts.ResumeTokenPath)
+		}
+		ctx = WithResumeStore(ctx, store)
+	}
+
+	resourceGroupName := valueOrDefault(opts.ResourceGroup, RESOURCE_GROUP)
+	location := valueOrDefault(opts.Location, LOCATION)
+	contextResourceGroup := valueOrDefault(opts.ContextResourceGroup, CONTEXT_RESOURCE_GROUP)
+	contextName := valueOrDefault(opts.ContextName, CONTEXT_NAME)
+	runID := opts.RunID
+	// A checkpoint only applies to the full workflow; update and cleanup modes act on existing resources
+	var checkpoint *checkpointStore
+	if opts.CheckpointPath != "" && !opts.DryRun && opts.Update == nil && opts.CleanupRunID == "" {
+		var err error
+		checkpoint, err = openCheckpoint(ctx, opts.CheckpointPath, runID, opts.ResumeFromCheckpoint, opts.DiscardCheckpoint)
+		if err != nil {
+			return nil, err
+		}
+		runID = checkpoint.checkpoint.RunID
+	}
+	if runID == "" {
+		runID = NewRunID()
+	}
+	if checkpoint != nil {
+		checkpoint.checkpoint.RunID = runID
+	}
+	names := opts.Names.withDefaults(NewResourceNames(valueOrDefault(opts.NamePrefix, DefaultNamePrefix), runID))
+	logger = logger.With("runId", runID)
+	ctx = WithLogger(ctx, logger)
+	logger.Info("Starting run", "dryRun", opts.DryRun)
+	// Every created resource is tagged with the run ID so CleanupByRunID can find it later
+	tags := mergeTags(opts.Tags, RunTags(runID))
+
+	scope, err := ResourceManagerScope(opts.Cloud)
+	if err != nil {
+		return nil, err
+	}
+
+	// Audit every operation against the authenticated principal
+	token, err := credential.GetToken(ctx, policy.TokenRequestOptions{
+		Scopes: []string{scope},
+	})
+	if err != nil {
+		return nil, &WorkflowError{Step: "Authenticate", Err: fmt.Errorf("error getting token: %w", err)}
+	}
+	auditSink := opts.AuditSink
+	// Nothing is changed in a dry run, so there is nothing to audit
+	if auditSink == nil || opts.DryRun {
+		auditSink = NewJSONAuditSink(io.Discard)
+	}
+	auditor := NewAuditor(PrincipalFromToken(token.Token), auditSink)
+	auditor.Logger = logger
+
+	// Create the management client factory
+	clientFactory, err := newClientFactory(subscriptionID, credential, opts.clientOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newWorkflowResult()
+	result.DryRun = opts.DryRun
+	result.RunID = runID
+	// Steps run concurrently, so their retries are recorded under a lock
+	var retriesMu sync.Mutex
+	ctx = withRetryRecorder(ctx, func(retry RetryResult) {
+		retriesMu.Lock()
+		defer retriesMu.Unlock()
+		result.Retries = append(result.Retries, retry)
+	})
+
+	// One span covers the run; each step adds a child span when it is recorded
+	tracer := tracerOrNoop(opts.Tracer)
+	ctx, runSpan := tracer.Start(ctx, "workflow.Run", trace.WithAttributes(attrRunID.String(runID), attrDryRun.Bool(opts.DryRun)))
+	defer runSpan.End()
+
+	// The capability this run added to the context, for teardown after an interrupt
+	var addedCapability string
+
+	fail := func(step, resource string, err error) (*WorkflowResult, error) {
+		result.addError(step, err)
+		workflowErr := &WorkflowError{Step: step, Resource: resource, Err: err}
+		logger.Error("Step failed", logKeyStep, step, logKeyResource, resource, logKeyError, err)
+		if ctx.Err() != nil {
+			result.InterruptedStep = step
+			if opts.CancelOnInterrupt && targetOperationSteps[step] && !opts.DryRun {
+				checkInterruptedOperation(ctx, clientFactory.NewTargetsClient(), resourceGroupName, resource)
+			}
+		}
+		// Only a full run owns what it names; update and cleanup modes act on existing resources
+		createdResources := opts.Update == nil && opts.CleanupRunID == ""
+		if opts.TeardownOnInterrupt && createdResources && !opts.DryRun && ctx.Err() != nil {
+			// ctx is already cancelled, so delete on one that keeps its values but not its cancellation
+			teardownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), InterruptTeardownTimeout)
+			defer cancel()
+			logger.Warn("Run interrupted, deleting the resources it created")
+			teardownErr := TeardownWorkflow(teardownCtx, clientFactory, resourceGroupName, WorkflowResourceNames{
+				TargetName:           result.TargetName,
+				SolutionTemplateName: result.SolutionTemplateName,
+				SchemaName:           result.SchemaName,
+				ContextResourceGroup: contextResourceGroup,
+				ContextName:          contextName,
+				ContextLocation:      location,
+				Capabilities:         []string{addedCapability},
+				RemoveCapabilities:   addedCapability != "",
+			})
+			if teardownErr != nil {
+				result.addError("Teardown", teardownErr)
+			}
+		}
+		result.FinishedAt = time.Now().UTC()
+		runSpan.RecordError(workflowErr)
+		runSpan.SetStatus(codes.Error, workflowErr.Error())
+		return result, workflowErr
+	}
+	// recordAt audits a step that started at start and has just finished, traces it and adds its
+	// timing and outcome to the result. It may be called from concurrent steps.
+	var recordMu sync.Mutex
+	recordAt := func(step, resource string, start time.Time, err error, attrs ...attribute.KeyValue) {
+		auditor.Record(step, resource, err)
+		recordStepSpan(ctx, tracer, step, resource, start, err, attrs...)
+		event := StepEvent{Kind: StepEventSucceeded, Step: step, Resource: resource, Time: time.Now(), Duration: time.Since(start)}
+		if err != nil {
+			event.Kind, event.Err = StepEventFailed, err
+		}
+		publishEvent(ctx, opts.Events, event)
+		recordMu.Lock()
+		defer recordMu.Unlock()
+		result.recordStep(step, resource, start, err)
+	}
+	// startStep announces a step that is about to begin and returns its start time for recordAt
+	startStep := func(step, resource string) time.Time {
+		now := time.Now()
+		publishEvent(ctx, opts.Events, StepEvent{Kind: StepEventStarted, Step: step, Resource: resource, Time: now})
+		return now
+	}
+	// record is recordAt for the sequential steps, which set stepStart before they begin
+	var stepStart time.Time
+	record := func(step, resource string, err error, attrs ...attribute.KeyValue) {
+		recordAt(step, resource, stepStart, err, attrs...)
+	}
+	// skipped reports, and logs, a step that an earlier attempt completed (see Options.CheckpointPath)
+	skipped := func(step string) bool {
+		if !checkpoint.done(step) {
+			return false
+		}
+		logger.Info("Skipping step completed by an earlier attempt", logKeyStep, step)
+		return true
+	}
+
+	// Cleanup mode: garbage-collect the resources of an earlier run and stop
+	if opts.CleanupRunID != "" {
+		result.RunID = opts.CleanupRunID
+		if opts.DryRun {
+			logger.Info("Dry run: skipping cleanup", "cleanupRunId", opts.CleanupRunID)
+			result.FinishedAt = time.Now().UTC()
+			return result, nil
+		}
+		stepStart = startStep("Cleanup", opts.CleanupRunID)
+		err = CleanupByRunID(ctx, clientFactory, resourceGroupName, opts.CleanupRunID)
+		record("Cleanup", opts.CleanupRunID, err)
+		if err != nil {
+			return fail("Cleanup", opts.CleanupRunID, fmt.Errorf("cleanup failed: %w", err))
+		}
+		result.FinishedAt = time.Now().UTC()
+		return result, nil
+	}
+
+	// Day-2 mode: roll a new solution template version onto an existing target and stop
+	if update := opts.Update; update != nil {
+		result.TargetName = update.TargetName
+		result.SolutionTemplateName = update.SolutionTemplateName
+		stepStart = startStep("UpdateDeployment", update.TargetName)
+		result.SolutionVersionID, err = UpdateDeployment(ctx, clientFactory.NewTargetsClient(), clientFactory.NewSolutionTemplateVersionsClient(), clientFactory.NewSolutionsClient(), clientFactory.NewSolutionVersionsClient(), resourceGroupName, update.TargetName, update.SolutionTemplateName, update.Version, opts.RollbackOnFailure, opts.DryRun)
+		record("UpdateDeployment", update.TargetName, err)
+		if err != nil {
+			return fail("UpdateDeployment", update.TargetName, fmt.Errorf("deployment update failed: %w", err))
+		}
+		result.ReviewStatus = StepSucceeded
+		result.PublishStatus = StepSucceeded
+		result.InstallStatus = StepSucceeded
+		result.FinishedAt = time.Now().UTC()
+		return result, nil
+	}
+
+	// An unsupported region would only fail deep inside the first long-running operation. Being
+	// unable to look the regions up isn't fatal; the service still has the final say.
+	ctx = withLocationCache(ctx)
+	supported, err := supportedLocations(ctx, credential, opts.Cloud, httpClient, subscriptionID)
+	if err != nil {
+		logger.Warn("Could not look up supported locations; skipping the location check", logKeyError, err)
+	} else {
+		stepStart = startStep("ValidateLocation", location)
+		err := ValidateLocation(location, supported)
+		record("ValidateLocation", location, err)
+		if err != nil {
+			return fail("ValidateLocation", location, err)
+		}
+	}
+
+	conflictPolicy := opts.ConflictPolicy
+	if conflictPolicy == "" {
+		conflictPolicy = CapabilityConflictReject
+	}
+
+	hierarchies := opts.Hierarchies
+	if hierarchies == nil {
+		hierarchies = DefaultHierarchies()
+	}
+
+	configValues := opts.ConfigValues
+	if configValues == nil {
+		configValues = DefaultConfigValues()
+	}
+
+	schemaRules := opts.SchemaRules
+	if schemaRules == nil {
+		schemaRules = DefaultSchemaRules
+	}
+	configDefaults := opts.ConfigDefaults
+	if configDefaults == nil {
+		configDefaults = DefaultConfigDefaults()
+	}
+	configValues, defaulted := ApplyConfigDefaults(schemaRules, configValues, configDefaults)
+	if len(defaulted) > 0 {
+		logger.Info("Defaulted optional configuration values", "fields", defaulted)
+	}
+
+	// Configuration values the schema rejects would only fail at review, after everything has
+	// been created, so check them against the schema the run is about to create first. The
+	// rules' editableAt levels must be among the context's hierarchy levels once the run has
+	// merged its own into them.
+	stepStart = startStep("ValidateConfiguration", "")
+	contextsClient := clientFactory.NewContextsClient()
+	levels := hierarchyNames(hierarchies)
+	existingContext, err := GetExistingContext(ctx, contextsClient, contextResourceGroup, contextName)
+	if err != nil {
+		record("ValidateConfiguration", contextName, err)
+		return fail("ValidateConfiguration", contextName, err)
+	}
+	for _, hierarchy := range existingContext.Hierarchies {
+		if !slices.Contains(levels, hierarchy.Name) {
+			levels = append(levels, hierarchy.Name)
+		}
+	}
+	schemaValue, err := BuildSchemaValue(schemaRules)
+	if err == nil {
+		err = ValidateSchemaRuleAttributes(schemaRules, levels)
+	}
+	// A component whose type the target has no binding for would only fail at install
+	components := opts.Components
+	if components == nil && err == nil {
+		components, err = DefaultComponents()
+	}
+	topologies := opts.Topologies
+	if topologies == nil && err == nil {
+		topologies, err = TopologiesForComponents(components)
+	}
+	if err == nil {
+		err = ValidateComponentBindings(components, topologies)
+	}
+	if err == nil {
+		err = ValidateConfigAgainstSchema(schemaValue, configValues)
+	}
+	record("ValidateConfiguration", "", err)
+	if err != nil {
+		return fail("ValidateConfiguration", "", err)
+	}
+
+	// STEP 1 (context capabilities) and STEP 2a (schema and schema version) don't depend on each
+	// other, so they run side by side; the first failure cancels the other. Each branch logs with
+	// its own "branch" attribute so interleaved records can be told apart.
+	var (
+		contextResult  *armworkloadorchestration.Context
+		capabilityName string
+		schema         *armworkloadorchestration.Schema
+		schemaVersion  *armworkloadorchestration.SchemaVersion
+	)
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	// STEP 1: Manage Azure context with random capabilities and verify
+	group.Go(func() error {
+		logger := logger.With("branch", "context")
+		ctx := WithLogger(groupCtx, logger)
+		if skipped("VerifyContext") {
+			capabilityName = checkpoint.get().Capability
+			return nil
+		}
+		if opts.Capability != "" {
+			capabilityName = opts.Capability
+			if opts.DryRun {
+				return nil
+			}
+			logger.Info("Verifying existing capability in context", logKeyStep, "VerifyContext", "capability", capabilityName)
+			start := startStep("VerifyContext", contextName)
+			err := VerifyCapabilitiesInContext(ctx, contextsClient, contextResourceGroup, contextName, []string{capabilityName})
+			recordAt("VerifyContext", contextName, start, err)
+			if err != nil {
+				return &WorkflowError{Step: "VerifyContext", Resource: contextName, Err: err}
+			}
+			return nil
+		}
+		logger.Info("Managing context capabilities", logKeyStep, "UpdateContext", logKeyResource, contextName)
+
+		start := startStep("UpdateContext", contextName)
+		var err error
+		contextResult, capabilityName, err = ManageAzureContext(ctx, contextsClient, contextResourceGroup, contextName, location, hierarchies, tags, opts.SeedCapabilities, opts.CapabilitiesFile, conflictPolicy, opts.DryRun)
+		recordAt("UpdateContext", contextName, start, err, provisioningStateAttrs(contextResult)...)
+		if err != nil {
+			return &WorkflowError{Step: "UpdateContext", Resource: contextName, Err: fmt.Errorf("context management failed: %w", err)}
+		}
+
+		// Verify capability exists in context; a dry run can only check the context it would have written
+		contextCheck := contextResult
+		if !opts.DryRun {
+			// Wait for context propagation
+			logger.Info("Waiting for context propagation", "delay", 30*time.Second)
+			select {
+			case <-time.After(30 * time.Second):
+			case <-ctx.Done():
+				return &WorkflowError{Step: "VerifyContext", Resource: contextName, Err: fmt.Errorf("waiting for context propagation: %w", ctx.Err())}
+			}
+
+			logger.Info("Verifying capability in context", logKeyStep, "VerifyContext", "capability", capabilityName)
+			contextCheck, err = VerifyContextExists(ctx, contextsClient, contextResourceGroup, contextName)
+			if err != nil {
+				return &WorkflowError{Step: "VerifyContext", Resource: contextName, Err: fmt.Errorf("failed to verify context: %w", err)}
+			}
+		}
+
+		// The capability generated for this run is used consistently across the solution template,
+		// target and all other resources, so check it here, before any of them is created
+		if err := ValidateCapabilities(contextCheck, []string{capabilityName}); err != nil {
+			return &WorkflowError{Step: "VerifyContext", Resource: contextName, Err: err}
+		}
+		logger.Info("Capability verified in context", logKeyStep, "VerifyContext", "capability", capabilityName)
+		checkpoint.complete(ctx, "VerifyContext", func(c *Checkpoint) { c.Capability = capabilityName })
+		return nil
+	})
+
+	// STEP 2a: Create the schema and its version
+	schemasClient := clientFactory.NewSchemasClient()
+	schemaVersionsClient := clientFactory.NewSchemaVersionsClient()
+	group.Go(func() error {
+		ctx := WithLogger(groupCtx, logger.With("branch", "schema"))
+
+		if skipped("CreateSchema") {
+			id := checkpoint.get().SchemaID
+			schema = &armworkloadorchestration.Schema{ID: to.Ptr(id), Name: to.Ptr(resourceName(id))}
+		} else {
+			start := startStep("CreateSchema", names.Schema)
+			var err error
+			schema, err = GetOrCreateSchema(ctx, schemasClient, resourceGroupName, names.Schema, location, tags, opts.DryRun)
+			if schema != nil {
+				recordAt("CreateSchema", *schema.Name, start, err, provisioningStateAttrs(schema)...)
+			} else {
+				recordAt("CreateSchema", resourceGroupName, start, err)
+			}
+			if err != nil {
+				return &WorkflowError{Step: "CreateSchema", Resource: resourceGroupName, Err: fmt.Errorf("error creating schema: %w", err)}
+			}
+			checkpoint.complete(ctx, "CreateSchema", func(c *Checkpoint) { c.SchemaID = stringValue(schema.ID) })
+		}
+
+		if skipped("CreateSchemaVersion") {
+			id := checkpoint.get().SchemaVersionID
+			schemaVersion = &armworkloadorchestration.SchemaVersion{ID: to.Ptr(id), Name: to.Ptr(resourceName(id))}
+			return nil
+		}
+		start := startStep("CreateSchemaVersion", *schema.Name)
+		var err error
+		schemaVersion, err = CreateSchemaVersion(ctx, schemaVersionsClient, resourceGroupName, *schema.Name, opts.SchemaRules, string(opts.VersionBump), opts.DryRun)
+		recordAt("CreateSchemaVersion", *schema.Name, start, err)
+		if err != nil {
+			return &WorkflowError{Step: "CreateSchemaVersion", Resource: *schema.Name, Err: fmt.Errorf("error creating schema version: %w", err)}
+		}
+		checkpoint.complete(ctx, "CreateSchemaVersion", func(c *Checkpoint) { c.SchemaVersionID = stringValue(schemaVersion.ID) })
+		return nil
+	})
+
+	groupErr := group.Wait()
+	// Whatever either branch created goes into the result, even when the other one failed
+	if contextResult != nil {
+		result.addResourceID(contextResult.ID)
+	}
+	if opts.Capability == "" {
+		addedCapability = capabilityName
+	}
+	if schema != nil && schema.Name != nil {
+		result.SchemaName = *schema.Name
+		result.addResourceID(schema.ID)
+	}
+	if schemaVersion != nil && schemaVersion.Name != nil {
+		result.SchemaVersion = *schemaVersion.Name
+		result.addResourceID(schemaVersion.ID)
+	}
+	if groupErr != nil {
+		var workflowErr *WorkflowError
+		if errors.As(groupErr, &workflowErr) {
+			return fail(workflowErr.Step, workflowErr.Resource, workflowErr.Err)
+		}
+		return fail("Setup", "", groupErr)
+	}
+	capabilities := []string{capabilityName}
+	result.Capability = capabilities[0]
+
+	// STEP 2b: Create the solution template and target
+
+	// Create solution template
+	solutionTemplatesClient := clientFactory.NewSolutionTemplatesClient()
+	// Retry solution template creation a few times as context may take time to propagate
+	var solutionTemplate *armworkloadorchestration.SolutionTemplate
+	if skipped("CreateSolutionTemplate") {
+		id := checkpoint.get().SolutionTemplateID
+		solutionTemplate = &armworkloadorchestration.SolutionTemplate{ID: to.Ptr(id), Name: to.Ptr(resourceName(id))}
+	} else {
+		stepStart = startStep("CreateSolutionTemplate", names.SolutionTemplate)
+		retryErr := retryOperation(ctx, RetrySolutionTemplateCreation, DefaultRetryPolicy, func() error {
+			var err error
+			solutionTemplate, err = GetOrCreateSolutionTemplate(ctx, solutionTemplatesClient, resourceGroupName, names.SolutionTemplate, location, capabilities, tags, opts.DryRun)
+			return err
+		})
+		record("CreateSolutionTemplate", names.SolutionTemplate, retryErr, provisioningStateAttrs(solutionTemplate)...)
+
+		if retryErr != nil {
+			return fail("CreateSolutionTemplate", names.SolutionTemplate, fmt.Errorf("error creating solution template after retries: %w", retryErr))
+		}
+		checkpoint.complete(ctx, "CreateSolutionTemplate", func(c *Checkpoint) { c.SolutionTemplateID = stringValue(solutionTemplate.ID) })
+	}
+	result.SolutionTemplateName = *solutionTemplate.Name
+	result.addResourceID(solutionTemplate.ID)
+
+	var solutionTemplateVersionID string
+	if skipped("CreateSolutionTemplateVersion") {
+		solutionTemplateVersionID = checkpoint.get().SolutionTemplateVersionID
+	} else {
+		stepStart = startStep("CreateSolutionTemplateVersion", *solutionTemplate.Name)
+
+		// Create solution template version, named after the highest existing one with VersionBump
+		var version string
+		var err error
+		if opts.VersionBump != "" {
+			version, err = NextSolutionTemplateVersion(ctx, clientFactory.NewSolutionTemplateVersionsClient(), resourceGroupName, *solutionTemplate.Name, string(opts.VersionBump))
+		}
+		var solutionTemplateVersionResult *armworkloadorchestration.SolutionTemplatesClientCreateVersionResponse
+		if err == nil {
+			solutionTemplateVersionResult, err = CreateSolutionTemplateVersion(ctx, solutionTemplatesClient, resourceGroupName, *solutionTemplate.Name, *schema.Name, *schemaVersion.Name, opts.SchemaRules, components, opts.UpdateType, version, opts.DryRun)
+		}
+		record("CreateSolutionTemplateVersion", *solutionTemplate.Name, err)
+		if err != nil {
+			return fail("CreateSolutionTemplateVersion", *solutionTemplate.Name, fmt.Errorf("error creating solution template version: %w", err))
+		}
+
+		// Review needs the version's full resource ID, not just its version string
+		solutionTemplateVersionID, err = resolveSolutionTemplateVersionID(&solutionTemplateVersionResult.SolutionTemplateVersion, subscriptionID, resourceGroupName, *solutionTemplate.Name)
+		if err != nil {
+			return fail("CreateSolutionTemplateVersion", *solutionTemplate.Name, err)
+		}
+		checkpoint.complete(ctx, "CreateSolutionTemplateVersion", func(c *Checkpoint) { c.SolutionTemplateVersionID = solutionTemplateVersionID })
+	}
+	result.SolutionTemplateVersionID = solutionTemplateVersionID
+	result.addResourceID(&solutionTemplateVersionID)
+
+	// Create target, referencing the context this run manages; a dry run may not have created it yet
+	targetsClient := clientFactory.NewTargetsClient()
+	var target *armworkloadorchestration.Target
+	if skipped("CreateTarget") {
+		id := checkpoint.get().TargetID
+		target = &armworkloadorchestration.Target{ID: to.Ptr(id), Name: to.Ptr(resourceName(id))}
+	} else {
+		stepStart = startStep("CreateTarget", names.Target)
+		// Check the hierarchy level against the context the target will reference, rather than
+		// leaving the service to reject it; a dry run checks the context it would have written
+		hierarchyLevel := valueOrDefault(opts.HierarchyLevel, DefaultHierarchyLevel)
+		targetContext := contextResult
+		if !opts.DryRun {
+			targetContext, err = VerifyContextExists(ctx, contextsClient, contextResourceGroup, contextName)
+			if err != nil {
+				record("CreateTarget", names.Target, err)
+				return fail("CreateTarget", names.Target, err)
+			}
+		}
+		if err := ValidateHierarchyLevel(targetContext, hierarchyLevel); err != nil {
+			record("CreateTarget", names.Target, err)
+			return fail("CreateTarget", names.Target, err)
+		}
+		contextID := ContextResourceID(subscriptionID, contextResourceGroup, contextName)
+		target, err = GetOrCreateTarget(ctx, targetsClient, resourceGroupName, names.Target, location, contextID, opts.ExtendedLocation, capabilities, topologies, opts.SolutionScope, hierarchyLevel, tags, opts.DryRun)
+		record("CreateTarget", names.Target, err, provisioningStateAttrs(target)...)
+		if err != nil {
+			return fail("CreateTarget", names.Target, fmt.Errorf("error creating target: %w", err))
+		}
+		checkpoint.complete(ctx, "CreateTarget", func(c *Checkpoint) { c.TargetID = stringValue(target.ID) })
+	}
+	result.TargetName = *target.Name
+	result.addResourceID(target.ID)
+
+	// STEP 3: Configuration API Call - Set configuration values before review
+
+	configName := *target.Name + "Config"
+	solutionName := *solutionTemplate.Name
+	// The configuration version the sample has always written to
+	version := "version1"
+
+	if skipped("SetConfiguration") {
+		result.ConfigurationStatus = StepSucceeded
+	} else {
+		logger.Debug("Configuration values", logKeyResource, configName, "values", configValues)
+
+		stepStart = startStep("SetConfiguration", configName)
+		err = CreateConfigurationAPICall(ctx, credential, opts.Cloud, httpClient, subscriptionID, resourceGroupName, configName, solutionName, version, configValues, opts.DryRun)
+		record("SetConfiguration", configName, err)
+		result.ConfigurationStatus = result.stepStatus("SetConfiguration", err)
+		configurationSet := err == nil
+		if err != nil {
+			if ctx.Err() != nil {
+				return fail("SetConfiguration", configName, err)
+			}
+			logger.Warn("Setting configuration failed, continuing with the workflow", logKeyStep, "SetConfiguration", logKeyResource, configName, logKeyError, err)
+			checkpoint.halt()
+		} else {
+			checkpoint.complete(ctx, "SetConfiguration", nil)
+		}
+
+		// STEP 3.1: GET Configuration to verify the values were set correctly
+		if opts.DryRun {
+			logger.Info("Dry run: skipping configuration read-back; nothing was written")
+		} else {
+			storedValues, err := GetConfigurationAPICall(ctx, credential, opts.Cloud, httpClient, subscriptionID, resourceGroupName, configName, solutionName, version)
+			if err != nil {
+				logger.Warn("Reading configuration back failed", logKeyResource, configName, logKeyError, err)
+			} else if configurationSet && storedValues != "" {
+				// Catch values the service stored differently from what was sent, e.g. a coerced type
+				stepStart = startStep("VerifyConfiguration", configName)
+				err = VerifyConfigurationValues(storedValues, configValues)
+				record("VerifyConfiguration", configName, err)
+				if err != nil {
+					result.ConfigurationStatus = result.stepStatus("VerifyConfiguration", err)
+					logger.Warn("Configuration verification failed, continuing with the workflow", logKeyStep, "VerifyConfiguration", logKeyResource, configName, logKeyError, err)
+				} else {
+					logger.Info("Configuration values verified", logKeyStep, "VerifyConfiguration", logKeyResource, configName)
+				}
+			}
+		}
+	}
+
+	// STEP 4: Review target using the extracted solution template version ID
+	solutionsClient := clientFactory.NewSolutionsClient()
+	solutionVersionsClient := clientFactory.NewSolutionVersionsClient()
+	var solutionVersionID string
+	if opts.ReviewOnly {
+		stepStart = startStep("PreviewReview", *target.Name)
+		result.ReviewPreview, err = PreviewReview(ctx, targetsClient, resourceGroupName, *target.Name, solutionTemplateVersionID, opts.DryRun)
+		if err == nil && !result.ReviewPreview.Clean() {
+			err = &ReviewError{TargetName: *target.Name, Diagnostics: result.ReviewPreview.Diagnostics}
+		}
+		record("PreviewReview", *target.Name, err)
+		// Validating is all a review-only run does, so a rejection or a failed call fails the run
+		if err != nil {
+			result.ReviewStatus = StepFailed
+			return fail("PreviewReview", *target.Name, err)
+		}
+		result.ReviewStatus = StepSucceeded
+		logger.Info("Review-only run: skipping publish and install", logKeyResource, *target.Name)
+	} else if skipped("ReviewSolutionVersion") {
+		solutionVersionID = checkpoint.get().SolutionVersionID
+		result.ReviewStatus = StepSucceeded
+	} else {
+		stepStart = startStep("ReviewSolutionVersion", *target.Name)
+		solutionVersionID, result.ReviewDiagnostics, err = ReviewTarget(ctx, targetsClient, solutionsClient, solutionVersionsClient, resourceGroupName, *target.Name, solutionTemplateVersionID, opts.DryRun)
+		record("ReviewSolutionVersion", *target.Name, err)
+		// Publishing a version the target rejected can't succeed, so unlike other review
+		// failures a rejection stops the run
+		var reviewErr *ReviewError
+		if errors.As(err, &reviewErr) {
+			result.ReviewStatus = StepFailed
+			return fail("ReviewSolutionVersion", *target.Name, err)
+		}
+		result.ReviewStatus = result.stepStatus("ReviewSolutionVersion", err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fail("ReviewSolutionVersion", *target.Name, err)
+			}
+			logger.Error("Review failed, skipping publish and install", logKeyStep, "ReviewSolutionVersion", logKeyResource, *target.Name, logKeyError, err)
+			checkpoint.halt()
+		} else {
+			checkpoint.complete(ctx, "ReviewSolutionVersion", func(c *Checkpoint) { c.SolutionVersionID = solutionVersionID })
+		}
+	}
+	result.SolutionVersionID = solutionVersionID
+
+	// STEP 5: Publish and install the reviewed solution version
+	// Publish target
+	if opts.ReviewOnly || solutionVersionID == "" {
+		// Nothing was reviewed, so there is nothing to publish
+	} else if skipped("PublishSolutionVersion") {
+		result.PublishStatus = StepSucceeded
+	} else {
+		stepStart = startStep("PublishSolutionVersion", *target.Name)
+		err = PublishTarget(ctx, targetsClient, resourceGroupName, *target.Name, solutionVersionID, opts.DryRun)
+		record("PublishSolutionVersion", *target.Name, err)
+		result.PublishStatus = result.stepStatus("PublishSolutionVersion", err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fail("PublishSolutionVersion", *target.Name, err)
+			}
+			logger.Error("Publish failed", logKeyStep, "PublishSolutionVersion", logKeyResource, *target.Name, logKeyError, err)
+			checkpoint.halt()
+		} else {
+			checkpoint.complete(ctx, "PublishSolutionVersion", nil)
+		}
+	}
+
+	// Install target
+	if opts.ReviewOnly || solutionVersionID == "" {
+		// Nothing was published, so there is nothing to install
+	} else if skipped("InstallSolution") {
+		err = nil
+		result.InstallStatus = StepSucceeded
+	} else {
+		stepStart = startStep("InstallSolution", *target.Name)
+		if opts.RollbackOnFailure {
+			err = InstallTargetWithRollback(ctx, targetsClient, solutionsClient, solutionVersionsClient, resourceGroupName, *target.Name, solutionVersionID, opts.DryRun)
+		} else {
+			err = InstallTarget(ctx, targetsClient, solutionsClient, solutionVersionsClient, resourceGroupName, *target.Name, solutionVersionID, opts.DryRun)
+		}
+		record("InstallSolution", *target.Name, err)
+		result.InstallStatus = result.stepStatus("InstallSolution", err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fail("InstallSolution", *target.Name, err)
+			}
+			logger.Error("Install failed", logKeyStep, "InstallSolution", logKeyResource, *target.Name, logKeyError, err)
+			checkpoint.halt()
+		} else {
+			checkpoint.complete(ctx, "InstallSolution", nil)
+		}
+	}
+
+	// Optionally wait for the installed solution to start serving
+	if err == nil && opts.HealthCheckTimeout > 0 && !opts.DryRun && !opts.ReviewOnly {
+		endpoint, enabled, err := healthCheckEndpoint(configValues)
+		if enabled {
+			stepStart = startStep("HealthCheck", *target.Name)
+			if err == nil {
+				logger.Info("Waiting for the solution to become healthy", logKeyStep, "HealthCheck", "endpoint", endpoint, "timeout", opts.HealthCheckTimeout)
+				err = WaitForHealthy(ctx, httpClient, endpoint, opts.HealthCheckTimeout)
+			}
+			record("HealthCheck", *target.Name, err)
+			if err != nil {
+				if ctx.Err() != nil {
+					return fail("HealthCheck", *target.Name, err)
+				}
+				result.addError("HealthCheck", err)
+				logger.Error("Health check failed", logKeyStep, "HealthCheck", logKeyResource, *target.Name, logKeyError, err)
+			}
+		}
+	}
+	logger.Info("Workflow completed", "target", *target.Name, "solutionVersionId", solutionVersionID)
+	// Every step succeeded, so there is nothing left for a later attempt to resume. A review-only
+	// run stops short of publishing, which a later run with the checkpoint picks up.
+	if result.Succeeded() && !opts.ReviewOnly {
+		checkpoint.remove(ctx)
+	}
+
+	// Delete everything this run created, including the capability it added, if it added one
+	if opts.Teardown && opts.DryRun {
+		logger.Info("Dry run: skipping teardown; nothing was created")
+	} else if opts.Teardown {
+		stepStart = startStep("Teardown", resourceGroupName)
+		teardownCtx, cancel := teardownContext(ctx, callerCtx)
+		defer cancel()
+		err = TeardownWorkflow(teardownCtx, clientFactory, resourceGroupName, WorkflowResourceNames{
+			TargetName:           *target.Name,
+			SolutionTemplateName: *solutionTemplate.Name,
+			SchemaName:           *schema.Name,
+			ContextResourceGroup: contextResourceGroup,
+			ContextName:          contextName,
+			ContextLocation:      location,
+			Capabilities:         capabilities,
+			RemoveCapabilities:   addedCapability != "",
+		})
+		record("Teardown", resourceGroupName, err)
+		if err != nil {
+			return fail("Teardown", resourceGroupName, fmt.Errorf("teardown failed: %w", err))
+		}
+	}
+
+	result.FinishedAt = time.Now().UTC()
+	return result, nil
+}
+
+// clientOptions returns the options the run's SDK clients are created with.
+func (opts Options) clientOptions() []ClientOption {
+	options := append([]ClientOption{WithClientCloud(opts.Cloud)}, opts.ClientOptions...)
+	if opts.DisableRetries {
+		// A negative MaxRetries turns the SDK's retry policy off
+		options = append(options, WithClientRetry(policy.RetryOptions{MaxRetries: -1}))
+	}
+	return options
+}
+
+// valueOrDefault returns value, or def when value is empty.
+func valueOrDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}